@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/klyr/klyr/internal/capture"
+	"github.com/klyr/klyr/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func newCaptureCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "capture",
+		Short: "Inspect captured requests",
+	}
+
+	cmd.AddCommand(newCaptureShowCmd())
+
+	return cmd
+}
+
+func newCaptureShowCmd() *cobra.Command {
+	var configPath string
+	var dir string
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "show <request-id>",
+		Short: "Print the captured request for a decision log request ID",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dir == "" {
+				if configPath == "" {
+					return errors.New("config path or --dir is required")
+				}
+				cfg, err := config.Load(configPath)
+				if err != nil {
+					return err
+				}
+				if cfg.Capture.Dir == "" {
+					return errors.New("capture.dir is not set in the config")
+				}
+				dir = cfg.ResolvePath(cfg.Capture.Dir)
+			}
+
+			rec, err := capture.Load(dir, args[0])
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(rec)
+			}
+			return capture.Write(cmd.OutOrStdout(), rec)
+		},
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to config file")
+	cmd.Flags().StringVar(&dir, "dir", "", "Capture directory (overrides capture.dir from the config)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the stored record as JSON")
+
+	return cmd
+}
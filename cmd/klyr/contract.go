@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klyr/klyr/internal/config"
+	"github.com/klyr/klyr/internal/contract"
+	"github.com/klyr/klyr/internal/openapi"
+	"github.com/klyr/klyr/internal/report"
+	"github.com/spf13/cobra"
+)
+
+func newContractCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "contract",
+		Short: "Work with API contracts",
+	}
+
+	cmd.AddCommand(newContractImportCmd())
+	cmd.AddCommand(newContractExportCmd())
+	cmd.AddCommand(newContractDiffCmd())
+	cmd.AddCommand(newContractMergeCmd())
+
+	return cmd
+}
+
+func newContractImportCmd() *cobra.Command {
+	var specPath string
+	var configPath string
+	var policyName string
+	var outPath string
+	var basePath string
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Generate enforce-mode contracts from an OpenAPI document",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if specPath == "" {
+				return errors.New("openapi path is required")
+			}
+			if configPath == "" {
+				return errors.New("config path is required")
+			}
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			doc, err := openapi.Load(specPath)
+			if err != nil {
+				return err
+			}
+
+			targets, err := importTargets(cfg, policyName)
+			if err != nil {
+				return err
+			}
+			if outPath != "" && len(targets) > 1 {
+				return errors.New("--out requires --policy when several policies have routes")
+			}
+
+			for _, target := range targets {
+				policyCfg := cfg.Policies[target.policy]
+				c, warnings, err := openapi.Import(doc, openapi.ImportOptions{
+					RouteID:      target.routeID,
+					Policy:       target.policy,
+					PathPrefixes: target.prefixes,
+					BasePath:     basePath,
+					MaxBodyBytes: policyCfg.Limits.MaxBodyBytes,
+				})
+				if err != nil {
+					return fmt.Errorf("import for policy %s: %w", target.policy, err)
+				}
+				for _, warning := range warnings {
+					fmt.Fprintf(os.Stderr, "warning: %s: %s\n", target.policy, warning)
+				}
+				if len(c.Endpoints) == 0 {
+					fmt.Fprintf(os.Stderr, "warning: %s: no spec paths under %v\n", target.policy, target.prefixes)
+				}
+
+				path := outPath
+				if path == "" {
+					if policyCfg.Contract.Path == "" {
+						return fmt.Errorf("policies.%s.contract.path is required", target.policy)
+					}
+					path = cfg.ResolvePath(policyCfg.Contract.Path)
+				}
+				if err := contract.Save(path, c); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "wrote %s (%d endpoints) for policy %s\n", path, len(c.Endpoints), target.policy)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&specPath, "openapi", "", "Path to OpenAPI 3 document (YAML or JSON)")
+	cmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to config file")
+	cmd.Flags().StringVar(&policyName, "policy", "", "Only import for this policy")
+	cmd.Flags().StringVar(&outPath, "out", "", "Override contract output path")
+	cmd.Flags().StringVar(&basePath, "base-path", "", "Prefix for spec paths (default: path of the first server URL)")
+
+	return cmd
+}
+
+type importTarget struct {
+	policy   string
+	routeID  string
+	prefixes []string
+}
+
+// importTargets groups routes by policy, since contracts are stored per
+// policy and shared by every route that uses it.
+func importTargets(cfg *config.Config, policyName string) ([]importTarget, error) {
+	byPolicy := map[string]*importTarget{}
+	for i, route := range cfg.Routes {
+		if policyName != "" && route.Policy != policyName {
+			continue
+		}
+		if _, ok := cfg.Policies[route.Policy]; !ok {
+			continue
+		}
+		target, ok := byPolicy[route.Policy]
+		if !ok {
+			target = &importTarget{policy: route.Policy, routeID: fmt.Sprintf("route-%d", i)}
+			byPolicy[route.Policy] = target
+		}
+		prefix := route.Match.PathPrefix
+		if prefix == "" {
+			prefix = "/"
+		}
+		target.prefixes = append(target.prefixes, prefix)
+	}
+	if len(byPolicy) == 0 {
+		if policyName != "" {
+			return nil, fmt.Errorf("no routes use policy %q", policyName)
+		}
+		return nil, errors.New("config has no routes with a policy")
+	}
+
+	names := make([]string, 0, len(byPolicy))
+	for name := range byPolicy {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	targets := make([]importTarget, 0, len(names))
+	for _, name := range names {
+		targets = append(targets, *byPolicy[name])
+	}
+	return targets, nil
+}
+
+func newContractExportCmd() *cobra.Command {
+	var inputPath string
+	var outPath string
+	var asOpenAPI bool
+	var title string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Render a contract as an OpenAPI skeleton",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !asOpenAPI {
+				return errors.New("--openapi is the only supported export format")
+			}
+			if inputPath == "" {
+				return errors.New("input path is required")
+			}
+			c, err := contract.Load(inputPath)
+			if err != nil {
+				return err
+			}
+			data, err := openapi.Marshal(openapi.Export(c, openapi.ExportOptions{Title: title}))
+			if err != nil {
+				return err
+			}
+			return report.WriteOutput(outPath, data)
+		},
+	}
+
+	cmd.Flags().BoolVar(&asOpenAPI, "openapi", false, "Export as OpenAPI 3 YAML")
+	cmd.Flags().StringVar(&inputPath, "in", "", "Path to contract JSON")
+	cmd.Flags().StringVar(&outPath, "out", "", "Output file path (default stdout)")
+	cmd.Flags().StringVar(&title, "title", "", "OpenAPI info.title (default: route and policy)")
+
+	return cmd
+}
+
+func newContractDiffCmd() *cobra.Command {
+	var format string
+	var outPath string
+
+	cmd := &cobra.Command{
+		Use:   "diff <old.json> <new.json>",
+		Short: "Compare two contracts and fail if the new one widens the old",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			old, err := contract.Load(args[0])
+			if err != nil {
+				return err
+			}
+			updated, err := contract.Load(args[1])
+			if err != nil {
+				return err
+			}
+
+			changes := contract.Diff(old, updated)
+			var data []byte
+			switch format {
+			case "", "text":
+				data = []byte(renderContractDiff(changes))
+			case "json":
+				if changes == nil {
+					changes = []contract.Change{}
+				}
+				data, err = json.MarshalIndent(changes, "", "  ")
+				if err != nil {
+					return err
+				}
+				data = append(data, '\n')
+			default:
+				return fmt.Errorf("unknown format %q", format)
+			}
+			if err := report.WriteOutput(outPath, data); err != nil {
+				return err
+			}
+
+			if contract.Widens(changes) {
+				return errors.New("contract widens: review the changes marked with !")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "text", "Output format: text|json")
+	cmd.Flags().StringVar(&outPath, "out", "", "Output file path (default stdout)")
+
+	return cmd
+}
+
+func renderContractDiff(changes []contract.Change) string {
+	if len(changes) == 0 {
+		return "no changes\n"
+	}
+	var b strings.Builder
+	widening := 0
+	for _, change := range changes {
+		if change.Widening {
+			widening++
+		}
+		b.WriteString(change.String())
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "\n%d changes, %d widening\n", len(changes), widening)
+	return b.String()
+}
+
+func newContractMergeCmd() *cobra.Command {
+	var outPath string
+	var bodyMargin int64
+	var prune contract.PruneOptions
+
+	cmd := &cobra.Command{
+		Use:   "merge <contract.json>...",
+		Short: "Combine contracts learned from several environments",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outPath == "" {
+				return errors.New("output path is required")
+			}
+			if bodyMargin < 0 {
+				return errors.New("body-margin must be >= 0")
+			}
+			if prune.MinClients > contract.MaxTrackedClients {
+				return fmt.Errorf("min-clients must be <= %d", contract.MaxTrackedClients)
+			}
+
+			var merged *contract.Contract
+			for _, path := range args {
+				c, err := contract.Load(path)
+				if err != nil {
+					return fmt.Errorf("load %s: %w", path, err)
+				}
+				if merged == nil {
+					merged = contract.New(c.RouteID, c.Policy)
+				} else if c.Policy != merged.Policy {
+					fmt.Fprintf(os.Stderr, "warning: %s is for policy %s, merging into %s\n", path, c.Policy, merged.Policy)
+				}
+				merged.Merge(c)
+			}
+			merged.GeneratedAt = time.Now().UTC()
+			rejected := merged.Prune(prune)
+			merged.Finalize(bodyMargin)
+
+			if err := contract.Save(outPath, merged); err != nil {
+				return err
+			}
+			printLearnSummaries([]contract.LearnSummary{{RouteID: merged.RouteID, Policy: merged.Policy, Path: outPath, Samples: merged.Samples, Rejected: rejected}})
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&outPath, "out", "", "Path to write the merged contract")
+	cmd.Flags().Int64Var(&bodyMargin, "body-margin", 1024, "Bytes added to the largest observed body for max_body_bytes")
+	cmd.Flags().IntVar(&prune.MinFrequency, "min-frequency", 0, "Drop items seen in fewer requests across all inputs")
+	cmd.Flags().IntVar(&prune.MinClients, "min-clients", 0, "Drop items seen from fewer distinct client IPs across all inputs")
+
+	return cmd
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"errors"
+	"time"
 
 	"github.com/klyr/klyr/internal/config"
 	"github.com/spf13/cobra"
@@ -10,6 +11,7 @@ import (
 func newEnforceCmd() *cobra.Command {
 	var configPath string
 	var contractPath string
+	var watch time.Duration
 
 	cmd := &cobra.Command{
 		Use:   "enforce",
@@ -18,23 +20,18 @@ func newEnforceCmd() *cobra.Command {
 			if configPath == "" {
 				return errors.New("config path is required")
 			}
-			cfg, err := config.Load(configPath)
+			loader := configLoader{path: configPath, mode: config.ModeEnforce, contract: contractPath}
+			cfg, err := loader.load()
 			if err != nil {
 				return err
 			}
-			applyOverrides(cfg, config.ModeEnforce, "")
-			if contractPath != "" {
-				applyOverrides(cfg, "", contractPath)
-			}
-			if err := cfg.Validate(); err != nil {
-				return err
-			}
-			return runGateway(cmd.Context(), cfg, false, 0)
+			return runGateway(cmd.Context(), cfg, loader, runOptions{watch: watch})
 		},
 	}
 
 	cmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to config file")
 	cmd.Flags().StringVar(&contractPath, "contract", "", "Override contract path")
+	addWatchFlag(cmd, &watch)
 
 	return cmd
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/klyr/klyr/internal/config"
+	"github.com/klyr/klyr/internal/explain"
+	"github.com/klyr/klyr/internal/gateway"
+	"github.com/spf13/cobra"
+)
+
+func newEvalCmd() *cobra.Command {
+	var configPath string
+	var method string
+	var rawURL string
+	var headers []string
+	var data string
+	var rawPath string
+	var curl string
+	var remoteAddr string
+
+	cmd := &cobra.Command{
+		Use:   "eval",
+		Short: "Explain the decision for one request without a running upstream",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if configPath == "" {
+				return errors.New("config path is required")
+			}
+			sources := 0
+			for _, s := range []string{rawURL, rawPath, curl} {
+				if s != "" {
+					sources++
+				}
+			}
+			if sources != 1 {
+				return errors.New("exactly one of --url, --raw or --curl is required")
+			}
+
+			var req *http.Request
+			var err error
+			switch {
+			case rawPath != "":
+				var raw []byte
+				if rawPath == "-" {
+					raw, err = io.ReadAll(cmd.InOrStdin())
+				} else {
+					raw, err = os.ReadFile(rawPath)
+				}
+				if err != nil {
+					return fmt.Errorf("read raw request: %w", err)
+				}
+				req, err = explain.ParseRaw(raw)
+			case curl != "":
+				req, err = explain.ParseCurl(curl)
+			default:
+				header := http.Header{}
+				for _, h := range headers {
+					name, value, ok := strings.Cut(h, ":")
+					if !ok {
+						return fmt.Errorf("invalid header %q, expected Name: value", h)
+					}
+					header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+				}
+				req, err = explain.NewRequest(method, rawURL, header, data)
+			}
+			if err != nil {
+				return err
+			}
+			if remoteAddr != "" {
+				if net.ParseIP(remoteAddr) != nil {
+					remoteAddr = net.JoinHostPort(remoteAddr, "0")
+				}
+				req.RemoteAddr = remoteAddr
+			}
+
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+			gw, err := gateway.NewOffline(cfg)
+			if err != nil {
+				return err
+			}
+			defer gw.Close()
+
+			ev, err := gw.Evaluate(req)
+			if err != nil {
+				return err
+			}
+			return explain.Write(cmd.OutOrStdout(), ev)
+		},
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to config file")
+	cmd.Flags().StringVarP(&method, "method", "X", "", "Request method (default GET, or POST with --data)")
+	cmd.Flags().StringVar(&rawURL, "url", "", "Request URL; the query is used as written")
+	cmd.Flags().StringArrayVarP(&headers, "header", "H", nil, "Request header as \"Name: value\" (repeatable)")
+	cmd.Flags().StringVarP(&data, "data", "d", "", "Request body")
+	cmd.Flags().StringVar(&rawPath, "raw", "", "Read the request in HTTP wire format from this file, or - for stdin")
+	cmd.Flags().StringVar(&curl, "curl", "", "Build the request from a curl command line")
+	cmd.Flags().StringVar(&remoteAddr, "remote-addr", "", "Client address of the request (default 192.0.2.1)")
+
+	return cmd
+}
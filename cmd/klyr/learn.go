@@ -12,6 +12,7 @@ func newLearnCmd() *cobra.Command {
 	var configPath string
 	var duration time.Duration
 	var outPath string
+	var watch time.Duration
 
 	cmd := &cobra.Command{
 		Use:   "learn",
@@ -23,24 +24,19 @@ func newLearnCmd() *cobra.Command {
 			if duration <= 0 {
 				return errors.New("duration must be > 0")
 			}
-			cfg, err := config.Load(configPath)
+			loader := configLoader{path: configPath, mode: config.ModeLearn, contract: outPath}
+			cfg, err := loader.load()
 			if err != nil {
 				return err
 			}
-			applyOverrides(cfg, config.ModeLearn, "")
-			if outPath != "" {
-				applyOverrides(cfg, "", outPath)
-			}
-			if err := cfg.Validate(); err != nil {
-				return err
-			}
-			return runGateway(cmd.Context(), cfg, true, duration)
+			return runGateway(cmd.Context(), cfg, loader, runOptions{learnMode: true, duration: duration, watch: watch})
 		},
 	}
 
 	cmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to config file")
 	cmd.Flags().DurationVar(&duration, "duration", 0, "Learn duration (e.g. 2m)")
 	cmd.Flags().StringVar(&outPath, "out", "", "Override contract output path")
+	addWatchFlag(cmd, &watch)
 
 	return cmd
 }
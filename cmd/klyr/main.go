@@ -26,6 +26,11 @@ func main() {
 	root.AddCommand(newLearnCmd())
 	root.AddCommand(newEnforceCmd())
 	root.AddCommand(newReportCmd())
+	root.AddCommand(newContractCmd())
+	root.AddCommand(newRulesCmd())
+	root.AddCommand(newEvalCmd())
+	root.AddCommand(newReplayCmd())
+	root.AddCommand(newCaptureCmd())
 	root.AddCommand(newValidateCmd())
 	root.AddCommand(newVersionCmd())
 
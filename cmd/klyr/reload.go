@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klyr/klyr/internal/config"
+	"github.com/klyr/klyr/internal/gateway"
+	"github.com/klyr/klyr/internal/observability"
+	"github.com/spf13/cobra"
+)
+
+func addWatchFlag(cmd *cobra.Command, watch *time.Duration) {
+	cmd.Flags().DurationVar(watch, "watch", 0, "Poll config, rule and contract files for changes at this interval and reload (0 disables)")
+}
+
+// reloader re-runs config loading and validation and swaps the result into the
+// gateway. Failed reloads leave the previous generation serving.
+type reloader struct {
+	loader  configLoader
+	gw      *gateway.Gateway
+	metrics *observability.Metrics
+
+	mu  sync.Mutex
+	cfg *config.Config
+}
+
+func newReloader(loader configLoader, cfg *config.Config, gw *gateway.Gateway, metrics *observability.Metrics) *reloader {
+	return &reloader{loader: loader, gw: gw, metrics: metrics, cfg: cfg}
+}
+
+func (r *reloader) config() *config.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cfg
+}
+
+func (r *reloader) reload(source string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := r.loader.load()
+	if err == nil {
+		err = r.gw.Reload(cfg)
+	}
+	if err != nil {
+		r.metrics.ObserveReload(false, r.gw.Generation())
+		logReloadError(source, err)
+		return err
+	}
+
+	warnRestartRequired(r.cfg, cfg)
+	r.cfg = cfg
+	r.metrics.ObserveReload(true, r.gw.Generation())
+	log.Printf("config reloaded (%s): generation %d", source, r.gw.Generation())
+	return nil
+}
+
+func (r *reloader) watchSignals(ctx context.Context, hup <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			_ = r.reload("sighup")
+		}
+	}
+}
+
+func (r *reloader) watchFiles(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := fingerprintFiles(watchedFiles(r.loader.path, r.config()))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := fingerprintFiles(watchedFiles(r.loader.path, r.config()))
+		if current == last {
+			continue
+		}
+		last = current
+		if err := r.reload("watch"); err == nil {
+			// The new config may reference different files.
+			last = fingerprintFiles(watchedFiles(r.loader.path, r.config()))
+		}
+	}
+}
+
+// watchedFiles lists the files whose contents feed a gateway snapshot: the
+// config itself, rule pattern files and contracts loaded for enforcement.
+func watchedFiles(configPath string, cfg *config.Config) []string {
+	files := []string{configPath}
+	if cfg == nil {
+		return files
+	}
+	for _, rule := range cfg.Rules {
+		if rule.Match.PatternsFile != "" {
+			files = append(files, cfg.ResolvePath(rule.Match.PatternsFile))
+		}
+	}
+	for _, policyCfg := range cfg.Policies {
+		if policyCfg.Mode == config.ModeEnforce && policyCfg.Contract.Path != "" {
+			files = append(files, cfg.ResolvePath(policyCfg.Contract.Path))
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func fingerprintFiles(files []string) string {
+	var b strings.Builder
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(&b, "%s|missing\n", path)
+			continue
+		}
+		fmt.Fprintf(&b, "%s|%d|%d\n", path, info.ModTime().UnixNano(), info.Size())
+	}
+	return b.String()
+}
+
+func logReloadError(source string, err error) {
+	var verr *config.ValidationError
+	if errors.As(err, &verr) {
+		log.Printf("config reload failed (%s): %v; keeping previous config", source, err)
+		for _, msg := range verr.Problems {
+			log.Printf("  %s", msg)
+		}
+		return
+	}
+	log.Printf("config reload failed (%s): %v; keeping previous config", source, err)
+}
+
+// warnRestartRequired reports settings that are bound when the process starts
+// and therefore do not change on reload.
+func warnRestartRequired(prev, next *config.Config) {
+	if prev == nil || next == nil {
+		return
+	}
+	if prev.Server != next.Server {
+		log.Printf("config reload: server settings changed; restart required to apply")
+	}
+	if prev.Metrics != next.Metrics {
+		log.Printf("config reload: metrics settings changed; restart required to apply")
+	}
+	if prev.Logging.DecisionLog != next.Logging.DecisionLog {
+		log.Printf("config reload: logging.decisionLog changed; restart required to apply")
+	}
+	if !reflect.DeepEqual(prev.Capture, next.Capture) {
+		log.Printf("config reload: capture settings changed; restart required to apply")
+	}
+}
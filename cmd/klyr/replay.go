@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/klyr/klyr/internal/config"
+	"github.com/klyr/klyr/internal/gateway"
+	"github.com/klyr/klyr/internal/replay"
+	"github.com/klyr/klyr/internal/report"
+	"github.com/spf13/cobra"
+)
+
+func newReplayCmd() *cobra.Command {
+	var configPath string
+	var inPath string
+	var since string
+
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Replay recorded decisions against a candidate config and report what changes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if configPath == "" {
+				return errors.New("config path is required")
+			}
+			if inPath == "" {
+				return errors.New("decision log path is required")
+			}
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+
+			reader := report.Reader{}
+			if since != "" {
+				dur, err := time.ParseDuration(since)
+				if err != nil {
+					return fmt.Errorf("invalid since duration: %w", err)
+				}
+				reader.Since = time.Now().Add(-dur)
+			}
+			decisions, err := reader.Read(inPath)
+			if err != nil {
+				return err
+			}
+
+			gw, err := gateway.NewOffline(cfg)
+			if err != nil {
+				return err
+			}
+			defer gw.Close()
+
+			return replay.WriteText(cmd.OutOrStdout(), replay.Run(gw, decisions))
+		},
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to the candidate config file")
+	cmd.Flags().StringVar(&inPath, "in", "", "Path to a decision log recorded with logging.replay enabled")
+	cmd.Flags().StringVar(&since, "since", "", "Only replay entries newer than this duration (e.g. 24h)")
+
+	return cmd
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/klyr/klyr/internal/config"
+	"github.com/klyr/klyr/internal/gateway"
+	"github.com/klyr/klyr/internal/modsec"
+	"github.com/klyr/klyr/internal/rules"
+	"github.com/klyr/klyr/internal/ruletest"
+	"github.com/spf13/cobra"
+)
+
+func newRulesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rules",
+		Short: "Work with rules",
+	}
+
+	cmd.AddCommand(newRulesLintCmd())
+	cmd.AddCommand(newRulesImportCmd())
+	cmd.AddCommand(newRulesTestCmd())
+
+	return cmd
+}
+
+func newRulesLintCmd() *cobra.Command {
+	var configPath string
+
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Flag regex rules that are expensive to evaluate",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if configPath == "" {
+				return errors.New("config path is required")
+			}
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+
+			issues := rules.Lint(cfg)
+			out := cmd.OutOrStdout()
+			for _, issue := range issues {
+				if _, err := fmt.Fprintf(out, "rule %s: %s: %q\n", issue.RuleID, issue.Message, issue.Pattern); err != nil {
+					return err
+				}
+			}
+			if len(issues) > 0 {
+				return fmt.Errorf("%d lint issue(s)", len(issues))
+			}
+			_, err = fmt.Fprintln(out, "rules ok")
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to config file")
+
+	return cmd
+}
+
+func newRulesImportCmd() *cobra.Command {
+	var modsecPath string
+	var outPath string
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Translate ModSecurity SecRule directives into Klyr rules",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if modsecPath == "" {
+				return errors.New("modsec path is required")
+			}
+			directives, err := modsec.Load(modsecPath)
+			if err != nil {
+				return err
+			}
+
+			imported, warnings := modsec.Translate(directives)
+			for _, warning := range warnings {
+				fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+			}
+			data, err := modsec.Marshal(imported)
+			if err != nil {
+				return err
+			}
+			if outPath == "" {
+				_, err = cmd.OutOrStdout().Write(data)
+			} else {
+				err = os.WriteFile(outPath, data, 0o644)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "translated %d rules from %d directives, %d warnings\n", len(imported), len(directives), len(warnings))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&modsecPath, "modsec", "", "Path to a ModSecurity or Coraza rules file")
+	cmd.Flags().StringVar(&outPath, "out", "", "Output path for the rules YAML (default stdout)")
+
+	return cmd
+}
+
+func newRulesTestCmd() *cobra.Command {
+	var configPath string
+	var casePaths []string
+	var junitPath string
+
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "Run YAML request cases through the rules and check the outcome",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if configPath == "" {
+				return errors.New("config path is required")
+			}
+			if len(casePaths) == 0 {
+				return errors.New("at least one --cases file is required")
+			}
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return err
+			}
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+			cases, err := ruletest.Load(casePaths...)
+			if err != nil {
+				return err
+			}
+			gw, err := gateway.NewOffline(cfg)
+			if err != nil {
+				return err
+			}
+			defer gw.Close()
+
+			results := ruletest.Run(gw, cases)
+			if junitPath != "" {
+				f, err := os.Create(junitPath)
+				if err != nil {
+					return fmt.Errorf("create junit report: %w", err)
+				}
+				if err := ruletest.WriteJUnit(f, results); err != nil {
+					f.Close()
+					return err
+				}
+				if err := f.Close(); err != nil {
+					return fmt.Errorf("write junit report: %w", err)
+				}
+			}
+
+			out := cmd.OutOrStdout()
+			failed := 0
+			for _, r := range results {
+				if r.Passed() {
+					continue
+				}
+				failed++
+				if r.Err != nil {
+					fmt.Fprintf(out, "ERROR %s: %v\n", r.Case.Name, r.Err)
+					continue
+				}
+				for _, failure := range r.Failures {
+					fmt.Fprintf(out, "FAIL %s: %s\n", r.Case.Name, failure)
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d rule test(s) failed", failed, len(results))
+			}
+			_, err = fmt.Fprintf(out, "%d rule test(s) passed\n", len(results))
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to config file")
+	cmd.Flags().StringArrayVar(&casePaths, "cases", nil, "Path to a YAML test corpus (repeatable)")
+	cmd.Flags().StringVar(&junitPath, "junit", "", "Write results as JUnit XML to this path")
+
+	return cmd
+}
@@ -10,7 +10,9 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/klyr/klyr/internal/capture"
 	"github.com/klyr/klyr/internal/config"
+	"github.com/klyr/klyr/internal/contract"
 	"github.com/klyr/klyr/internal/gateway"
 	"github.com/klyr/klyr/internal/logging"
 	"github.com/klyr/klyr/internal/observability"
@@ -22,6 +24,7 @@ func newRunCmd() *cobra.Command {
 	var configPath string
 	var modeOverride string
 	var contractOverride string
+	var watch time.Duration
 
 	cmd := &cobra.Command{
 		Use:   "run",
@@ -30,30 +33,55 @@ func newRunCmd() *cobra.Command {
 			if configPath == "" {
 				return errors.New("config path is required")
 			}
-			cfg, err := config.Load(configPath)
+			loader := configLoader{path: configPath, mode: modeOverride, contract: contractOverride}
+			cfg, err := loader.load()
 			if err != nil {
 				return err
 			}
-			applyOverrides(cfg, modeOverride, contractOverride)
-			if err := cfg.Validate(); err != nil {
-				return err
-			}
-			return runGateway(cmd.Context(), cfg, false, 0)
+			return runGateway(cmd.Context(), cfg, loader, runOptions{watch: watch})
 		},
 	}
 
 	cmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to config file")
 	cmd.Flags().StringVar(&modeOverride, "mode", "", "Override policy mode for all policies")
 	cmd.Flags().StringVar(&contractOverride, "contract", "", "Override contract path for all policies")
+	addWatchFlag(cmd, &watch)
 
 	return cmd
 }
 
-func runGateway(ctx context.Context, cfg *config.Config, learnMode bool, duration time.Duration) error {
+type runOptions struct {
+	learnMode bool
+	duration  time.Duration
+	watch     time.Duration
+}
+
+// configLoader re-reads the config file with the command-line overrides
+// applied, so reloads see the same effective config as startup.
+type configLoader struct {
+	path     string
+	mode     string
+	contract string
+}
+
+func (l configLoader) load() (*config.Config, error) {
+	cfg, err := config.Load(l.path)
+	if err != nil {
+		return nil, err
+	}
+	applyOverrides(cfg, l.mode, l.contract)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func runGateway(ctx context.Context, cfg *config.Config, loader configLoader, opts runOptions) error {
 	gw, err := gateway.New(cfg)
 	if err != nil {
 		return err
 	}
+	defer gw.Close()
 
 	if cfg.Logging.DecisionLog != "" {
 		logger, closer, err := logging.OpenDecisionLog(cfg.ResolvePath(cfg.Logging.DecisionLog))
@@ -63,8 +91,16 @@ func runGateway(ctx context.Context, cfg *config.Config, learnMode bool, duratio
 		defer func() { _ = closer() }()
 		gw.SetDecisionLogger(logger)
 	}
+	if cfg.Capture.Enabled {
+		store, err := capture.Open(cfg.ResolvePath(cfg.Capture.Dir), cfg.Capture)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		gw.SetCaptureStore(store)
+	}
 
-	metricsSrv, err := startMetricsServer(cfg, gw)
+	metricsSrv, metrics, err := startMetricsServer(cfg, gw)
 	if err != nil {
 		return err
 	}
@@ -80,6 +116,12 @@ func runGateway(ctx context.Context, cfg *config.Config, learnMode bool, duratio
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 
+	// Register for SIGHUP before serving: until then the signal's default
+	// action would terminate the process instead of reloading.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
 	serverErr := make(chan error, 1)
 	go func() {
 		if cfg.Server.TLS.Enabled {
@@ -92,9 +134,15 @@ func runGateway(ctx context.Context, cfg *config.Config, learnMode bool, duratio
 	signalCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	if learnMode {
+	rl := newReloader(loader, cfg, gw, metrics)
+	go rl.watchSignals(signalCtx, hup)
+	if opts.watch > 0 {
+		go rl.watchFiles(signalCtx, opts.watch)
+	}
+
+	if opts.learnMode {
 		select {
-		case <-time.After(duration):
+		case <-time.After(opts.duration):
 		case <-signalCtx.Done():
 		case err := <-serverErr:
 			if err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -117,10 +165,13 @@ func runGateway(ctx context.Context, cfg *config.Config, learnMode bool, duratio
 		return err
 	}
 
-	if learnMode {
-		if err := gw.SaveContracts(cfg); err != nil {
+	if opts.learnMode {
+		cfg = rl.config()
+		summaries, err := gw.SaveContracts(cfg)
+		if err != nil {
 			return err
 		}
+		printLearnSummaries(summaries)
 		if err := ensureMinSamples(cfg, gw); err != nil {
 			return err
 		}
@@ -129,13 +180,14 @@ func runGateway(ctx context.Context, cfg *config.Config, learnMode bool, duratio
 	return nil
 }
 
-func startMetricsServer(cfg *config.Config, gw *gateway.Gateway) (*http.Server, error) {
+func startMetricsServer(cfg *config.Config, gw *gateway.Gateway) (*http.Server, *observability.Metrics, error) {
 	if !cfg.Metrics.Enabled {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	reg := prometheus.NewRegistry()
 	metrics := observability.NewMetrics(reg)
+	metrics.SetConfigGeneration(gw.Generation())
 	gw.SetMetrics(metrics)
 
 	mux := http.NewServeMux()
@@ -145,7 +197,7 @@ func startMetricsServer(cfg *config.Config, gw *gateway.Gateway) (*http.Server,
 	go func() {
 		_ = srv.ListenAndServe()
 	}()
-	return srv, nil
+	return srv, metrics, nil
 }
 
 func applyOverrides(cfg *config.Config, modeOverride, contractOverride string) {
@@ -160,6 +212,19 @@ func applyOverrides(cfg *config.Config, modeOverride, contractOverride string) {
 	}
 }
 
+func printLearnSummaries(summaries []contract.LearnSummary) {
+	for _, summary := range summaries {
+		fmt.Fprintf(os.Stderr, "wrote %s for policy %s (%d samples), %d items rejected\n", summary.Path, summary.Policy, summary.Samples, len(summary.Rejected))
+		for _, r := range summary.Rejected {
+			scope := ""
+			if r.Endpoint != "" {
+				scope = " [" + r.Endpoint + "]"
+			}
+			fmt.Fprintf(os.Stderr, "  rejected %s %s%s: %s (count %d, clients %d)\n", r.Kind, r.Name, scope, r.Reason, r.Count, r.Clients)
+		}
+	}
+}
+
 func ensureMinSamples(cfg *config.Config, gw *gateway.Gateway) error {
 	for i, route := range cfg.Routes {
 		routeID := fmt.Sprintf("route-%d", i)
@@ -0,0 +1,148 @@
+package capture
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klyr/klyr/internal/config"
+	"github.com/klyr/klyr/internal/logging"
+)
+
+func TestStoreRedactsAndCaps(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir, config.CaptureConfig{
+		MaxBodyBytes: 96,
+		Redact: config.CaptureRedact{
+			Headers:  []string{"x-api-key"},
+			Params:   []string{"password", "token"},
+			Patterns: []string{`\b\d{16}\b`},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	defer store.Close()
+
+	body := `{"user":"admin","password":"hunter2","card":"4111111111111111","note":"` + strings.Repeat("x", 40) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "http://shop.test/login?token=abc&next=/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Api-Key", "k-123")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "s3cr3t"})
+	decision := logging.Decision{
+		RequestID:    "abc123",
+		Timestamp:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		ClientIP:     "192.0.2.1",
+		RouteID:      "route-0",
+		Policy:       "default",
+		Action:       "block",
+		StatusCode:   http.StatusForbidden,
+		MatchedRules: []logging.MatchedRule{{ID: "sqli-fingerprint"}},
+	}
+	if err := store.Save(NewRecord(decision, req, []byte(body))); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	rec, err := Load(dir, "abc123")
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	var out bytes.Buffer
+	if err := Write(&out, rec); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	text := out.String()
+	for _, want := range []string{
+		"# route route-0, policy default, action block (403)",
+		"# matched rules: sqli-fingerprint",
+		"POST /login?token=<redacted>&next=/ HTTP/1.1\r\n",
+		"Host: shop.test\r\n",
+		"Authorization: <redacted>\r\n",
+		"Cookie: session=<redacted>\r\n",
+		"X-Api-Key: <redacted>\r\n",
+		`"password":"<redacted>"`,
+		`"card":"<redacted>"`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in capture:\n%s", want, text)
+		}
+	}
+	for _, secret := range []string{"hunter2", "4111111111111111", "s3cr3t", "k-123", "Bearer"} {
+		if strings.Contains(text, secret) {
+			t.Fatalf("expected %q to be redacted:\n%s", secret, text)
+		}
+	}
+	if !rec.BodyTruncated || len(rec.Body) != 96 || rec.BodySize != int64(len(body)) {
+		t.Fatalf("expected body capped at 96 bytes, got %d of %d", len(rec.Body), rec.BodySize)
+	}
+
+	if _, err := Load(dir, "../etc/passwd"); err == nil {
+		t.Fatal("expected invalid id error")
+	}
+	if _, err := Load(dir, "missing"); err == nil || !strings.Contains(err.Error(), "no capture for request missing") {
+		t.Fatalf("expected missing capture error, got %v", err)
+	}
+}
+
+func TestStorePrunes(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, age := range []time.Duration{3 * time.Hour, 30 * time.Minute, 20 * time.Minute, 10 * time.Minute} {
+		path := filepath.Join(dir, string(rune('a'+i))+".json")
+		if err := os.WriteFile(path, []byte("{}"), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+	}
+
+	store, err := Open(dir, config.CaptureConfig{MaxFiles: 2, Retention: time.Hour})
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	store.Close()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != "c.json,d.json" {
+		t.Fatalf("expected the two newest captures to remain, got %v", names)
+	}
+}
+
+func TestStoreQueuesAndPrunesToWatermark(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir, config.CaptureConfig{MaxFiles: 10})
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	for i := 0; i < 11; i++ {
+		if !store.Enqueue(Record{RequestID: fmt.Sprintf("req-%02d", i)}) {
+			t.Fatalf("expected capture %d to be queued", i)
+		}
+	}
+	store.Close()
+	if store.Enqueue(Record{RequestID: "late"}) {
+		t.Fatalf("expected a closed store to drop captures")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	if len(entries) != 9 {
+		t.Fatalf("expected pruning down to 9 captures, got %d", len(entries))
+	}
+}
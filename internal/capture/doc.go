@@ -0,0 +1,2 @@
+// Package capture provides functionality for Klyr.
+package capture
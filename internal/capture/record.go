@@ -0,0 +1,97 @@
+package capture
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klyr/klyr/internal/logging"
+)
+
+// Record is one captured request and the decision taken on it.
+type Record struct {
+	RequestID     string    `json:"request_id"`
+	Timestamp     time.Time `json:"ts"`
+	ClientIP      string    `json:"client_ip"`
+	RouteID       string    `json:"route_id"`
+	Policy        string    `json:"policy"`
+	Action        string    `json:"action"`
+	StatusCode    int       `json:"status_code"`
+	MatchedRules  []string  `json:"matched_rules,omitempty"`
+	RequestLine   string    `json:"request_line"`
+	Headers       []Header  `json:"headers"`
+	Body          []byte    `json:"body,omitempty"`
+	BodySize      int64     `json:"body_size"`
+	BodyTruncated bool      `json:"body_truncated,omitempty"`
+}
+
+type Header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// NewRecord captures r as received, with Host first and the other headers
+// sorted by name. body is the part of the body the gateway read, if any.
+func NewRecord(d logging.Decision, r *http.Request, body []byte) Record {
+	rec := Record{
+		RequestID:   d.RequestID,
+		Timestamp:   d.Timestamp,
+		ClientIP:    d.ClientIP,
+		RouteID:     d.RouteID,
+		Policy:      d.Policy,
+		Action:      d.Action,
+		StatusCode:  d.StatusCode,
+		RequestLine: fmt.Sprintf("%s %s %s", r.Method, r.URL.RequestURI(), r.Proto),
+		Body:        body,
+		BodySize:    int64(len(body)),
+	}
+	for _, m := range d.MatchedRules {
+		rec.MatchedRules = append(rec.MatchedRules, m.ID)
+	}
+	if r.Host != "" {
+		rec.Headers = append(rec.Headers, Header{Name: "Host", Value: r.Host})
+	}
+	names := make([]string, 0, len(r.Header))
+	for name := range r.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range r.Header[name] {
+			rec.Headers = append(rec.Headers, Header{Name: name, Value: value})
+		}
+	}
+	return rec
+}
+
+// Write renders rec as comment lines describing the decision followed by
+// the request in HTTP/1.x wire format.
+func Write(w io.Writer, rec Record) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# request %s at %s from %s\n", rec.RequestID, rec.Timestamp.UTC().Format(time.RFC3339), rec.ClientIP)
+	fmt.Fprintf(bw, "# route %s, policy %s, action %s", rec.RouteID, rec.Policy, rec.Action)
+	if rec.StatusCode != 0 {
+		fmt.Fprintf(bw, " (%d)", rec.StatusCode)
+	}
+	fmt.Fprintln(bw)
+	if len(rec.MatchedRules) > 0 {
+		fmt.Fprintf(bw, "# matched rules: %s\n", strings.Join(rec.MatchedRules, ", "))
+	}
+	if rec.BodyTruncated {
+		fmt.Fprintf(bw, "# body truncated to %d of %d bytes\n", len(rec.Body), rec.BodySize)
+	}
+	fmt.Fprintf(bw, "%s\r\n", rec.RequestLine)
+	for _, h := range rec.Headers {
+		fmt.Fprintf(bw, "%s: %s\r\n", h.Name, h.Value)
+	}
+	fmt.Fprint(bw, "\r\n")
+	bw.Write(rec.Body)
+	if len(rec.Body) > 0 && rec.Body[len(rec.Body)-1] != '\n' {
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
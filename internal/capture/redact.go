@@ -0,0 +1,181 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/klyr/klyr/internal/config"
+)
+
+const redacted = "<redacted>"
+
+// defaultParams are always redacted, like the Authorization header.
+var defaultParams = []string{"password", "passwd", "secret", "token", "api_key", "apikey"}
+
+// Redactor removes credentials and configured secrets from a record. The
+// gateway also applies it to request bodies recorded for replay.
+type Redactor struct {
+	headers  map[string]bool
+	params   map[string]bool
+	patterns []*regexp.Regexp
+}
+
+func NewRedactor(cfg config.CaptureRedact) (*Redactor, error) {
+	r := &Redactor{
+		headers: map[string]bool{"Authorization": true, "Proxy-Authorization": true},
+		params:  map[string]bool{},
+	}
+	for _, name := range cfg.Headers {
+		r.headers[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+	}
+	for _, name := range append(defaultParams, cfg.Params...) {
+		r.params[strings.ToLower(name)] = true
+	}
+	for _, pattern := range cfg.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("compile capture redact pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// Redact replaces header values, cookie values and named parameters in the
+// request line and body, then every pattern match. A redacted JSON body is
+// re-encoded.
+func (r *Redactor) Redact(rec Record) Record {
+	method, target, ok := strings.Cut(rec.RequestLine, " ")
+	if ok {
+		uri, proto, _ := strings.Cut(target, " ")
+		if path, query, ok := strings.Cut(uri, "?"); ok {
+			uri = path + "?" + r.redactPairs(query)
+		}
+		rec.RequestLine = strings.TrimSpace(method + " " + uri + " " + proto)
+	}
+	rec.RequestLine = r.redactPatterns(rec.RequestLine)
+
+	headers := make([]Header, len(rec.Headers))
+	contentType := ""
+	for i, h := range rec.Headers {
+		if http.CanonicalHeaderKey(h.Name) == "Content-Type" {
+			contentType = h.Value
+		}
+		h.Value = r.Header(h.Name, h.Value)
+		headers[i] = h
+	}
+	rec.Headers = headers
+	rec.Body = r.Body(contentType, rec.Body)
+	return rec
+}
+
+// Header returns value with credentials, cookie values and pattern matches
+// redacted.
+func (r *Redactor) Header(name, value string) string {
+	switch canon := http.CanonicalHeaderKey(name); {
+	case r.headers[canon]:
+		return redacted
+	case canon == "Cookie":
+		value = redactCookies(value)
+	}
+	return r.redactPatterns(value)
+}
+
+// Body redacts named parameters in a form or JSON body, re-encoding JSON,
+// and then every pattern match.
+func (r *Redactor) Body(contentType string, body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+	contentType = strings.ToLower(contentType)
+	switch {
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		body = []byte(r.redactPairs(string(body)))
+	case strings.Contains(contentType, "json"):
+		body = r.redactJSON(body)
+	}
+	if len(r.patterns) > 0 {
+		body = []byte(r.redactPatterns(string(body)))
+	}
+	return body
+}
+
+func (r *Redactor) redactPairs(raw string) string {
+	if len(r.params) == 0 || raw == "" {
+		return raw
+	}
+	pairs := strings.Split(raw, "&")
+	for i, pair := range pairs {
+		key, _, hasValue := strings.Cut(pair, "=")
+		name := key
+		if decoded, err := url.QueryUnescape(key); err == nil {
+			name = decoded
+		}
+		if hasValue && r.params[strings.ToLower(name)] {
+			pairs[i] = key + "=" + redacted
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func (r *Redactor) redactJSON(body []byte) []byte {
+	var value any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return body
+	}
+	if !r.redactValue(value) {
+		return body
+	}
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return body
+	}
+	return bytes.TrimRight(out.Bytes(), "\n")
+}
+
+// redactValue replaces, in place, the values of object keys named in
+// r.params at any depth, and reports whether it changed anything.
+func (r *Redactor) redactValue(value any) bool {
+	changed := false
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if r.params[strings.ToLower(key)] {
+				v[key] = redacted
+				changed = true
+				continue
+			}
+			changed = r.redactValue(item) || changed
+		}
+	case []any:
+		for _, item := range v {
+			changed = r.redactValue(item) || changed
+		}
+	}
+	return changed
+}
+
+func (r *Redactor) redactPatterns(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, redacted)
+	}
+	return s
+}
+
+func redactCookies(header string) string {
+	parts := strings.Split(header, ";")
+	for i, part := range parts {
+		name, _, _ := strings.Cut(strings.TrimSpace(part), "=")
+		parts[i] = name + "=" + redacted
+	}
+	return strings.Join(parts, "; ")
+}
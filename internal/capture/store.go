@@ -0,0 +1,254 @@
+package capture
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klyr/klyr/internal/config"
+)
+
+// pruneInterval bounds how often Save scans the directory for expired
+// captures.
+const pruneInterval = time.Minute
+
+// queueSize bounds the captures waiting for the writer; Enqueue drops
+// captures beyond it rather than block a request.
+const queueSize = 256
+
+// errorInterval rate-limits logged write failures.
+const errorInterval = time.Minute
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Store writes captures as <request id>.json files in one directory and
+// prunes them by age and count. Enqueued captures are written by a
+// background writer until Close.
+type Store struct {
+	dir       string
+	maxBody   int64
+	maxFiles  int
+	retention time.Duration
+	redactor  *Redactor
+
+	mu        sync.Mutex
+	files     int
+	lastPrune time.Time
+
+	queueMu sync.RWMutex
+	queue   chan Record
+	closed  bool
+	done    chan struct{}
+
+	errMu      sync.Mutex
+	lastErr    time.Time
+	suppressed int
+}
+
+// Open creates dir if needed and prunes it once.
+func Open(dir string, cfg config.CaptureConfig) (*Store, error) {
+	redactor, err := NewRedactor(cfg.Redact)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create capture dir: %w", err)
+	}
+	s := &Store{
+		dir:       dir,
+		maxBody:   cfg.MaxBodyBytes,
+		maxFiles:  cfg.MaxFiles,
+		retention: cfg.Retention,
+		redactor:  redactor,
+		queue:     make(chan Record, queueSize),
+		done:      make(chan struct{}),
+	}
+	if s.maxBody == 0 {
+		s.maxBody = config.DefaultCaptureBodyBytes
+	}
+	if s.maxFiles == 0 {
+		s.maxFiles = config.DefaultCaptureMaxFiles
+	}
+	if s.retention == 0 {
+		s.retention = config.DefaultCaptureRetention
+	}
+	if err := s.Prune(time.Now()); err != nil {
+		return nil, err
+	}
+	go s.write()
+	return s, nil
+}
+
+// Enqueue hands rec to the background writer without blocking and reports
+// whether it was accepted. It is dropped when the queue is full or the
+// store is closed.
+func (s *Store) Enqueue(rec Record) bool {
+	s.queueMu.RLock()
+	defer s.queueMu.RUnlock()
+	if s.closed {
+		return false
+	}
+	select {
+	case s.queue <- rec:
+		return true
+	default:
+		s.logError(fmt.Errorf("queue full, dropped capture %s", rec.RequestID))
+		return false
+	}
+}
+
+// Close stops accepting captures and waits for the queued ones to be
+// written.
+func (s *Store) Close() {
+	s.queueMu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.queueMu.Unlock()
+	<-s.done
+}
+
+func (s *Store) write() {
+	defer close(s.done)
+	for rec := range s.queue {
+		if err := s.Save(rec); err != nil {
+			s.logError(err)
+		}
+	}
+}
+
+func (s *Store) logError(err error) {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastErr) < errorInterval {
+		s.suppressed++
+		return
+	}
+	if s.suppressed > 0 {
+		log.Printf("capture: %v (%d similar errors suppressed)", err, s.suppressed)
+	} else {
+		log.Printf("capture: %v", err)
+	}
+	s.lastErr = now
+	s.suppressed = 0
+}
+
+// Save redacts rec, caps its body and writes it synchronously.
+func (s *Store) Save(rec Record) error {
+	if !validID.MatchString(rec.RequestID) {
+		return fmt.Errorf("invalid capture id %q", rec.RequestID)
+	}
+	rec = s.redactor.Redact(rec)
+	if int64(len(rec.Body)) > s.maxBody {
+		rec.Body = rec.Body[:s.maxBody]
+		rec.BodyTruncated = true
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".capture-*")
+	if err != nil {
+		return fmt.Errorf("write capture: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write capture: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write capture: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, rec.RequestID+".json")); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write capture: %w", err)
+	}
+
+	s.mu.Lock()
+	s.files++
+	due := s.files > s.maxFiles || time.Since(s.lastPrune) > pruneInterval
+	s.mu.Unlock()
+	if due {
+		return s.Prune(time.Now())
+	}
+	return nil
+}
+
+// Prune removes captures older than the retention and, once the file limit
+// is exceeded, the oldest captures down to 90% of it, so a full store is not
+// rescanned on every Save.
+func (s *Store) Prune(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastPrune = now
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("read capture dir: %w", err)
+	}
+	type capture struct {
+		path string
+		mod  time.Time
+	}
+	var kept []capture
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(s.dir, entry.Name())
+		if now.Sub(info.ModTime()) > s.retention {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("prune capture: %w", err)
+			}
+			continue
+		}
+		kept = append(kept, capture{path: path, mod: info.ModTime()})
+	}
+	if len(kept) > s.maxFiles {
+		extra := len(kept) - (s.maxFiles - s.maxFiles/10)
+		sort.Slice(kept, func(i, j int) bool { return kept[i].mod.Before(kept[j].mod) })
+		for _, c := range kept[:extra] {
+			if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("prune capture: %w", err)
+			}
+		}
+		kept = kept[extra:]
+	}
+	s.files = len(kept)
+	return nil
+}
+
+// Load reads the capture of one request from dir.
+func Load(dir, requestID string) (Record, error) {
+	if !validID.MatchString(requestID) {
+		return Record{}, fmt.Errorf("invalid request id %q", requestID)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, requestID+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return Record{}, fmt.Errorf("no capture for request %s", requestID)
+	}
+	if err != nil {
+		return Record{}, fmt.Errorf("read capture: %w", err)
+	}
+	var rec Record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return Record{}, fmt.Errorf("parse capture %s: %w", requestID, err)
+	}
+	return rec, nil
+}
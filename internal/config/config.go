@@ -11,6 +11,7 @@ type Config struct {
 	Rules         []Rule            `yaml:"rules"`
 	Logging       LoggingConfig     `yaml:"logging"`
 	Metrics       MetricsConfig     `yaml:"metrics"`
+	Capture       CaptureConfig     `yaml:"capture"`
 
 	baseDir string `yaml:"-"`
 }
@@ -27,14 +28,34 @@ type TLSConfig struct {
 }
 
 type Upstream struct {
-	Name string `yaml:"name"`
-	URL  string `yaml:"url"`
+	Name          string              `yaml:"name"`
+	URL           string              `yaml:"url"`
+	Targets       []string            `yaml:"targets"`
+	Balancer      string              `yaml:"balancer"`
+	HealthCheck   HealthCheckConfig   `yaml:"healthCheck"`
+	PassiveHealth PassiveHealthConfig `yaml:"passiveHealth"`
+}
+
+type HealthCheckConfig struct {
+	Enabled            bool          `yaml:"enabled"`
+	Path               string        `yaml:"path"`
+	Interval           time.Duration `yaml:"interval"`
+	Timeout            time.Duration `yaml:"timeout"`
+	HealthyThreshold   int           `yaml:"healthyThreshold"`
+	UnhealthyThreshold int           `yaml:"unhealthyThreshold"`
+}
+
+type PassiveHealthConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	MaxFailures   int           `yaml:"maxFailures"`
+	EjectDuration time.Duration `yaml:"ejectDuration"`
 }
 
 type Route struct {
-	Match    RouteMatch `yaml:"match"`
-	Upstream string     `yaml:"upstream"`
-	Policy   string     `yaml:"policy"`
+	Match      RouteMatch  `yaml:"match"`
+	Upstream   string      `yaml:"upstream"`
+	Policy     string      `yaml:"policy"`
+	Exclusions []Exclusion `yaml:"exclusions"`
 }
 
 type RouteMatch struct {
@@ -49,6 +70,27 @@ type Policy struct {
 	Contract         ContractConfig   `yaml:"contract"`
 	RateLimit        RateLimitConfig  `yaml:"rateLimit"`
 	Actions          PolicyActionSpec `yaml:"actions"`
+	Exclusions       []Exclusion      `yaml:"exclusions"`
+
+	// CategoryThresholds blocks when the score summed over the rules tagged
+	// with a category reaches its threshold, independently of
+	// AnomalyThreshold. ParanoiaLevel (1-4, default 1) enables the rules
+	// annotated with that level or lower.
+	CategoryThresholds map[string]int `yaml:"categoryThresholds"`
+	ParanoiaLevel      int            `yaml:"paranoiaLevel"`
+
+	Inspection InspectionBudget `yaml:"inspection"`
+}
+
+// InspectionBudget bounds the bytes the rules scan and the time they take per
+// request; zero means unlimited. OnExceed decides what happens to a request
+// whose rules did not all run: fail_open (default) keeps the matches found so
+// far, fail_closed blocks it and score adds Penalty to its anomaly score.
+type InspectionBudget struct {
+	MaxBytes int64         `yaml:"maxBytes"`
+	MaxTime  time.Duration `yaml:"maxTime"`
+	OnExceed string        `yaml:"onExceed"`
+	Penalty  int           `yaml:"penalty"`
 }
 
 type Limits struct {
@@ -62,6 +104,26 @@ type ContractConfig struct {
 	LearnWindow time.Duration `yaml:"learnWindow"`
 	MinSamples  int           `yaml:"minSamples"`
 	Enforcement string        `yaml:"enforcement"`
+	Drift       DriftConfig   `yaml:"drift"`
+
+	// MinFrequency and MinClients drop learned items seen in fewer requests
+	// or from fewer distinct client IPs when the contract is saved. Client
+	// counts stop at contract.MaxTrackedClients (256), so MinClients may not
+	// exceed it.
+	MinFrequency int `yaml:"minFrequency"`
+	MinClients   int `yaml:"minClients"`
+}
+
+// DriftConfig keeps learning on top of an enforced contract and is ignored in
+// other modes. Additions seen at least MinCount times from MinClients
+// distinct clients are proposed in PendingPath or, with action promote,
+// added to the live contract.
+type DriftConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	MinCount    int    `yaml:"minCount"`
+	MinClients  int    `yaml:"minClients"`
+	Action      string `yaml:"action"`
+	PendingPath string `yaml:"pendingPath"`
 }
 
 type RateLimitConfig struct {
@@ -77,38 +139,197 @@ type PolicyActionSpec struct {
 	BlockBody       string `yaml:"blockBody"`
 }
 
+// Exclusion tunes the rules selected by ID or tag for requests of a route or
+// policy, optionally limited to PathPrefix. Targets (ARGS:name, HEADERS:name,
+// COOKIES:name) are removed from the rule input and Score replaces the rule
+// score; with neither set the rules are disabled.
+type Exclusion struct {
+	ID         string   `yaml:"id"`
+	Rules      []string `yaml:"rules"`
+	Tags       []string `yaml:"tags"`
+	PathPrefix string   `yaml:"pathPrefix"`
+	Targets    []string `yaml:"targets"`
+	Score      *int     `yaml:"score"`
+}
+
 type Rule struct {
-	ID         string    `yaml:"id"`
-	Phase      string    `yaml:"phase"`
-	Score      int       `yaml:"score"`
-	Tags       []string  `yaml:"tags"`
-	Transforms []string  `yaml:"transforms"`
-	Match      RuleMatch `yaml:"match"`
+	ID string `yaml:"id"`
+	// Message describes what the rule detects and is informational only.
+	Message    string     `yaml:"message,omitempty"`
+	Phase      string     `yaml:"phase,omitempty"`
+	Score      int        `yaml:"score,omitempty"`
+	Tags       []string   `yaml:"tags,omitempty"`
+	Transforms []string   `yaml:"transforms,omitempty"`
+	Match      RuleMatch  `yaml:"match,omitempty"`
+	Condition  *Condition `yaml:"condition,omitempty"`
+	Action     RuleAction `yaml:"action,omitempty"`
+	// ParanoiaLevel is the lowest policy paranoia level that runs the rule.
+	ParanoiaLevel int `yaml:"paranoiaLevel,omitempty"`
+}
+
+// RuleAction is taken as soon as the rule matches instead of, or besides,
+// adding its score. An empty type only scores.
+type RuleAction struct {
+	Type       string        `yaml:"type,omitempty"`
+	StatusCode int           `yaml:"statusCode,omitempty"`
+	Location   string        `yaml:"location,omitempty"`
+	Delay      time.Duration `yaml:"delay,omitempty"`
+}
+
+// Condition is either a compound of nested conditions (all, any, not) or a
+// single test of op against the values of target, such as ARGS:username or
+// BODY_JSON:/user/name. A target with several values matches when any value
+// does.
+type Condition struct {
+	All    []Condition `yaml:"all,omitempty"`
+	Any    []Condition `yaml:"any,omitempty"`
+	Not    *Condition  `yaml:"not,omitempty"`
+	Target string      `yaml:"target,omitempty"`
+	Op     string      `yaml:"op,omitempty"`
+	Value  string      `yaml:"value,omitempty"`
+	Values []string    `yaml:"values,omitempty"`
 }
 
 type RuleMatch struct {
-	Type         string `yaml:"type"`
-	Pattern      string `yaml:"pattern"`
-	PatternsFile string `yaml:"patternsFile"`
+	Type         string   `yaml:"type,omitempty"`
+	Pattern      string   `yaml:"pattern,omitempty"`
+	PatternsFile string   `yaml:"patternsFile,omitempty"`
+	Signals      []string `yaml:"signals,omitempty"`
 }
 
 type LoggingConfig struct {
-	Level       string `yaml:"level"`
-	Format      string `yaml:"format"`
-	DecisionLog string `yaml:"decisionLog"`
+	Level       string        `yaml:"level"`
+	Format      string        `yaml:"format"`
+	DecisionLog string        `yaml:"decisionLog"`
+	Replay      ReplayLogging `yaml:"replay"`
+}
+
+// ReplayLogging adds the request headers and up to MaxBodyBytes of the body,
+// redacted as for captures, to each decision so it can be replayed.
+type ReplayLogging struct {
+	Enabled      bool  `yaml:"enabled"`
+	MaxBodyBytes int64 `yaml:"maxBodyBytes"`
 }
 
+// DefaultReplayBodyBytes is the body size recorded when
+// logging.replay.maxBodyBytes is 0.
+const DefaultReplayBodyBytes = 8192
+
+// CaptureConfig stores the full request line, headers and body of blocked and
+// shadow-flagged requests, one file per request ID under Dir. Zero limits
+// take the defaults below.
+type CaptureConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	Dir          string        `yaml:"dir"`
+	MaxBodyBytes int64         `yaml:"maxBodyBytes"`
+	MaxFiles     int           `yaml:"maxFiles"`
+	Retention    time.Duration `yaml:"retention"`
+	Redact       CaptureRedact `yaml:"redact"`
+}
+
+// CaptureRedact lists what is replaced with <redacted> before a capture is
+// written, on top of the Authorization header and cookie values: header
+// values by name, query, form and JSON body parameters by name, and regex
+// matches anywhere in the request.
+type CaptureRedact struct {
+	Headers  []string `yaml:"headers"`
+	Params   []string `yaml:"params"`
+	Patterns []string `yaml:"patterns"`
+}
+
+const (
+	DefaultCaptureBodyBytes = 64 << 10
+	DefaultCaptureMaxFiles  = 10000
+	DefaultCaptureRetention = 7 * 24 * time.Hour
+)
+
 type MetricsConfig struct {
 	Enabled bool   `yaml:"enabled"`
 	Listen  string `yaml:"listen"`
 }
 
+const (
+	BalancerRoundRobin     = "round_robin"
+	BalancerLeastConn      = "least_conn"
+	BalancerConsistentHash = "consistent_hash"
+)
+
+// Condition targets. ARGS, HEADERS and COOKIES take an optional :name
+// selector; BODY_JSON requires a JSON pointer selector.
+const (
+	TargetArgs       = "ARGS"
+	TargetArgsNames  = "ARGS_NAMES"
+	TargetHeaders    = "HEADERS"
+	TargetCookies    = "COOKIES"
+	TargetPath       = "PATH"
+	TargetMethod     = "METHOD"
+	TargetQuery      = "QUERY"
+	TargetBody       = "BODY"
+	TargetBodyJSON   = "BODY_JSON"
+	TargetRemoteAddr = "REMOTE_ADDR"
+)
+
+const (
+	OpRegex      = "regex"
+	OpEquals     = "equals"
+	OpContains   = "contains"
+	OpStartsWith = "startsWith"
+	OpEndsWith   = "endsWith"
+	OpGreater    = "gt"
+	OpLess       = "lt"
+	OpLengthGt   = "lengthGt"
+	OpLengthLt   = "lengthLt"
+	OpIPInCIDR   = "ipInCIDR"
+	OpExists     = "exists"
+)
+
+// MaxConditionDepth bounds how deeply all, any and not may nest.
+const MaxConditionDepth = 8
+
+// Rule action types, from highest to lowest precedence. log and tag never
+// change the outcome and do not add to the score.
+const (
+	RuleActionAllow    = "allow"
+	RuleActionBlock    = "block"
+	RuleActionRedirect = "redirect"
+	RuleActionTarpit   = "tarpit"
+	RuleActionLog      = "log"
+	RuleActionTag      = "tag"
+)
+
+const MaxParanoiaLevel = 4
+
+const (
+	BudgetFailOpen   = "fail_open"
+	BudgetFailClosed = "fail_closed"
+	BudgetScore      = "score"
+)
+
+// MaxTarpitDelay bounds how long a tarpit rule holds a request.
+const MaxTarpitDelay = 30 * time.Second
+
+const (
+	DriftPropose = "propose"
+	DriftPromote = "promote"
+)
+
 const (
 	ModeLearn   = "learn"
 	ModeEnforce = "enforce"
 	ModeShadow  = "shadow"
 )
 
+// TargetURLs returns the upstream targets, treating url as a single-target pool.
+func (u Upstream) TargetURLs() []string {
+	if len(u.Targets) > 0 {
+		return u.Targets
+	}
+	if u.URL != "" {
+		return []string{u.URL}
+	}
+	return nil
+}
+
 func (c *Config) BaseDir() string {
 	return c.baseDir
 }
@@ -9,7 +9,11 @@ import (
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/klyr/klyr/internal/contract"
+	"github.com/klyr/klyr/internal/normalize"
 )
 
 type ValidationError struct {
@@ -54,12 +58,18 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.Logging.Replay.MaxBodyBytes < 0 {
+		v.Add("logging.replay.maxBodyBytes must be >= 0")
+	}
+
 	if c.Metrics.Enabled {
 		if err := validateListen(c.Metrics.Listen); err != nil {
 			v.Add("metrics.listen invalid: %v", err)
 		}
 	}
 
+	validateCapture(v, c.Capture)
+
 	upstreamNames := map[string]struct{}{}
 	for i, upstream := range c.Upstreams {
 		if upstream.Name == "" {
@@ -70,10 +80,58 @@ func (c *Config) Validate() error {
 			upstreamNames[upstream.Name] = struct{}{}
 		}
 
-		if upstream.URL == "" {
-			v.Add("upstreams[%d].url is required", i)
-		} else if err := validateURL(upstream.URL); err != nil {
-			v.Add("upstreams[%d].url invalid: %v", i, err)
+		switch {
+		case upstream.URL == "" && len(upstream.Targets) == 0:
+			v.Add("upstreams[%d].url or targets is required", i)
+		case upstream.URL != "" && len(upstream.Targets) > 0:
+			v.Add("upstreams[%d] must set url or targets, not both", i)
+		case upstream.URL != "":
+			if err := validateURL(upstream.URL); err != nil {
+				v.Add("upstreams[%d].url invalid: %v", i, err)
+			}
+		default:
+			seen := map[string]struct{}{}
+			for j, target := range upstream.Targets {
+				if err := validateURL(target); err != nil {
+					v.Add("upstreams[%d].targets[%d] invalid: %v", i, j, err)
+				} else if _, exists := seen[target]; exists {
+					v.Add("upstreams[%d].targets[%d] %q is duplicated", i, j, target)
+				}
+				seen[target] = struct{}{}
+			}
+		}
+
+		switch upstream.Balancer {
+		case "", BalancerRoundRobin, BalancerLeastConn, BalancerConsistentHash:
+		default:
+			v.Add("upstreams[%d].balancer must be round_robin|least_conn|consistent_hash", i)
+		}
+
+		if hc := upstream.HealthCheck; hc.Enabled {
+			if !strings.HasPrefix(hc.Path, "/") {
+				v.Add("upstreams[%d].healthCheck.path must start with /", i)
+			}
+			if hc.Interval <= 0 {
+				v.Add("upstreams[%d].healthCheck.interval must be > 0", i)
+			}
+			if hc.Timeout < 0 {
+				v.Add("upstreams[%d].healthCheck.timeout must be >= 0", i)
+			}
+			if hc.HealthyThreshold <= 0 {
+				v.Add("upstreams[%d].healthCheck.healthyThreshold must be > 0", i)
+			}
+			if hc.UnhealthyThreshold <= 0 {
+				v.Add("upstreams[%d].healthCheck.unhealthyThreshold must be > 0", i)
+			}
+		}
+
+		if ph := upstream.PassiveHealth; ph.Enabled {
+			if ph.MaxFailures <= 0 {
+				v.Add("upstreams[%d].passiveHealth.maxFailures must be > 0", i)
+			}
+			if ph.EjectDuration <= 0 {
+				v.Add("upstreams[%d].passiveHealth.ejectDuration must be > 0", i)
+			}
 		}
 	}
 
@@ -99,6 +157,19 @@ func (c *Config) Validate() error {
 			v.Add("policies.%s.anomalyThreshold must be >= 0", name)
 		}
 
+		if policy.ParanoiaLevel < 0 || policy.ParanoiaLevel > MaxParanoiaLevel {
+			v.Add("policies.%s.paranoiaLevel must be between 1 and %d", name, MaxParanoiaLevel)
+		}
+		for category, threshold := range policy.CategoryThresholds {
+			if category == "" {
+				v.Add("policies.%s.categoryThresholds has an empty category", name)
+			} else if threshold <= 0 {
+				v.Add("policies.%s.categoryThresholds.%s must be > 0", name, category)
+			}
+		}
+
+		validateInspection(v, name, policy.Inspection)
+
 		if policy.Limits.MaxBodyBytes <= 0 {
 			v.Add("policies.%s.limits.maxBodyBytes must be > 0", name)
 		}
@@ -117,6 +188,27 @@ func (c *Config) Validate() error {
 			}
 		}
 
+		if policy.Contract.MinFrequency < 0 {
+			v.Add("policies.%s.contract.minFrequency must be >= 0", name)
+		}
+		if policy.Contract.MinClients < 0 || policy.Contract.MinClients > contract.MaxTrackedClients {
+			v.Add("policies.%s.contract.minClients must be between 0 and %d", name, contract.MaxTrackedClients)
+		}
+
+		if drift := policy.Contract.Drift; drift.Enabled {
+			if drift.MinCount <= 0 {
+				v.Add("policies.%s.contract.drift.minCount must be > 0", name)
+			}
+			if drift.MinClients <= 0 {
+				v.Add("policies.%s.contract.drift.minClients must be > 0", name)
+			}
+			switch drift.Action {
+			case "", DriftPropose, DriftPromote:
+			default:
+				v.Add("policies.%s.contract.drift.action must be propose|promote", name)
+			}
+		}
+
 		if policy.RateLimit.Enabled {
 			if policy.RateLimit.RPS <= 0 {
 				v.Add("policies.%s.rateLimit.rps must be > 0", name)
@@ -153,6 +245,19 @@ func (c *Config) Validate() error {
 			ruleIDs[rule.ID] = struct{}{}
 		}
 
+		c.validateRuleAction(v, fmt.Sprintf("rules[%d].action", i), rule.Action)
+		if rule.ParanoiaLevel < 0 || rule.ParanoiaLevel > MaxParanoiaLevel {
+			v.Add("rules[%d].paranoiaLevel must be between 1 and %d", i, MaxParanoiaLevel)
+		}
+
+		if rule.Condition != nil {
+			if rule.Match.Type != "" {
+				v.Add("rules[%d] must set match or condition, not both", i)
+			}
+			validateCondition(v, fmt.Sprintf("rules[%d].condition", i), *rule.Condition, 0)
+			continue
+		}
+
 		if rule.Match.Type == "" {
 			v.Add("rules[%d].match.type is required", i)
 		}
@@ -170,8 +275,30 @@ func (c *Config) Validate() error {
 			} else if _, err := regexp.Compile(rule.Match.Pattern); err != nil {
 				v.Add("rules[%d].match.pattern invalid: %v", i, err)
 			}
+		case "signal":
+			if len(rule.Match.Signals) == 0 {
+				v.Add("rules[%d].match.signals is required for signal", i)
+			}
+			for _, signal := range rule.Match.Signals {
+				if _, ok := normalize.ParseFlag(signal); !ok {
+					v.Add("rules[%d].match.signals %q must be double_encoded|invalid_encoding|overlong_utf8|invalid_utf8|null_byte|mixed_separators", i, signal)
+				}
+			}
+		case "sqli", "xss":
 		default:
-			v.Add("rules[%d].match.type must be aho|regex", i)
+			v.Add("rules[%d].match.type must be aho|regex|signal|sqli|xss", i)
+		}
+	}
+
+	exclusionIDs := map[string]struct{}{}
+	for name, policy := range c.Policies {
+		for j, exclusion := range policy.Exclusions {
+			c.validateExclusion(v, fmt.Sprintf("policies.%s.exclusions[%d]", name, j), exclusion, ruleIDs, exclusionIDs)
+		}
+	}
+	for i, route := range c.Routes {
+		for j, exclusion := range route.Exclusions {
+			c.validateExclusion(v, fmt.Sprintf("routes[%d].exclusions[%d]", i, j), exclusion, ruleIDs, exclusionIDs)
 		}
 	}
 
@@ -182,6 +309,175 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+func (c *Config) validateRuleAction(v *ValidationError, field string, action RuleAction) {
+	switch action.Type {
+	case "", RuleActionAllow, RuleActionLog, RuleActionTag:
+	case RuleActionBlock:
+		if action.StatusCode != 0 && (action.StatusCode < 400 || action.StatusCode > 599) {
+			v.Add("%s.statusCode must be 4xx or 5xx for block", field)
+		}
+	case RuleActionRedirect:
+		if action.Location == "" {
+			v.Add("%s.location is required for redirect", field)
+		} else if !strings.HasPrefix(action.Location, "/") {
+			if err := validateURL(action.Location); err != nil {
+				v.Add("%s.location invalid: %v", field, err)
+			}
+		}
+		if action.StatusCode != 0 && (action.StatusCode < 300 || action.StatusCode > 399) {
+			v.Add("%s.statusCode must be 3xx for redirect", field)
+		}
+	case RuleActionTarpit:
+		if action.Delay <= 0 {
+			v.Add("%s.delay must be > 0 for tarpit", field)
+		} else if action.Delay > MaxTarpitDelay {
+			v.Add("%s.delay must be <= %s", field, MaxTarpitDelay)
+		}
+	default:
+		v.Add("%s.type must be block|allow|log|tag|redirect|tarpit", field)
+	}
+}
+
+func (c *Config) validateExclusion(v *ValidationError, field string, exclusion Exclusion, ruleIDs, seen map[string]struct{}) {
+	if exclusion.ID == "" {
+		v.Add("%s.id is required", field)
+	} else if _, exists := seen[exclusion.ID]; exists {
+		v.Add("%s.id %q is duplicated", field, exclusion.ID)
+	} else {
+		seen[exclusion.ID] = struct{}{}
+	}
+
+	if len(exclusion.Rules) == 0 && len(exclusion.Tags) == 0 {
+		v.Add("%s must select rules or tags", field)
+	}
+	for _, id := range exclusion.Rules {
+		if _, exists := ruleIDs[id]; !exists {
+			v.Add("%s.rules %q does not exist", field, id)
+		}
+	}
+	if exclusion.PathPrefix != "" && !strings.HasPrefix(exclusion.PathPrefix, "/") {
+		v.Add("%s.pathPrefix must start with /", field)
+	}
+	for _, target := range exclusion.Targets {
+		name, selector, _ := strings.Cut(target, ":")
+		switch name {
+		case TargetArgs, TargetHeaders, TargetCookies:
+			if selector == "" {
+				v.Add("%s.targets %q needs a name such as %s:id", field, target, name)
+			}
+		default:
+			v.Add("%s.targets %q must be ARGS:name|HEADERS:name|COOKIES:name", field, target)
+		}
+	}
+	if exclusion.Score != nil && *exclusion.Score < 0 {
+		v.Add("%s.score must be >= 0", field)
+	}
+}
+
+func validateCondition(v *ValidationError, field string, cond Condition, depth int) {
+	if depth >= MaxConditionDepth {
+		v.Add("%s nests deeper than %d levels", field, MaxConditionDepth)
+		return
+	}
+
+	set := 0
+	for _, present := range []bool{len(cond.All) > 0, len(cond.Any) > 0, cond.Not != nil, cond.Target != ""} {
+		if present {
+			set++
+		}
+	}
+	if set != 1 {
+		v.Add("%s must set exactly one of all|any|not|target", field)
+		return
+	}
+
+	switch {
+	case len(cond.All) > 0:
+		for i, child := range cond.All {
+			validateCondition(v, fmt.Sprintf("%s.all[%d]", field, i), child, depth+1)
+		}
+	case len(cond.Any) > 0:
+		for i, child := range cond.Any {
+			validateCondition(v, fmt.Sprintf("%s.any[%d]", field, i), child, depth+1)
+		}
+	case cond.Not != nil:
+		validateCondition(v, field+".not", *cond.Not, depth+1)
+	default:
+		if err := ValidateTarget(cond.Target); err != nil {
+			v.Add("%s.target invalid: %v", field, err)
+		}
+		validateOperator(v, field, cond)
+	}
+}
+
+// ValidateTarget checks a condition target and its selector.
+func ValidateTarget(target string) error {
+	name, selector, hasSelector := strings.Cut(target, ":")
+	switch name {
+	case TargetArgs, TargetHeaders, TargetCookies:
+		if hasSelector && selector == "" {
+			return errors.New("selector after : is empty")
+		}
+	case TargetBodyJSON:
+		if !strings.HasPrefix(selector, "/") {
+			return errors.New("BODY_JSON needs a JSON pointer such as BODY_JSON:/user/name")
+		}
+	case TargetArgsNames, TargetPath, TargetMethod, TargetQuery, TargetBody, TargetRemoteAddr:
+		if hasSelector {
+			return fmt.Errorf("%s does not take a selector", name)
+		}
+	default:
+		return fmt.Errorf("unknown target %q", name)
+	}
+	return nil
+}
+
+func validateOperator(v *ValidationError, field string, cond Condition) {
+	values := cond.Values
+	if cond.Value != "" {
+		values = append([]string{cond.Value}, values...)
+	}
+
+	switch cond.Op {
+	case OpExists:
+		if len(values) > 0 {
+			v.Add("%s.value is not used by exists", field)
+		}
+		return
+	case OpRegex, OpEquals, OpContains, OpStartsWith, OpEndsWith,
+		OpGreater, OpLess, OpLengthGt, OpLengthLt, OpIPInCIDR:
+	default:
+		v.Add("%s.op must be regex|equals|contains|startsWith|endsWith|gt|lt|lengthGt|lengthLt|ipInCIDR|exists", field)
+		return
+	}
+
+	if len(values) == 0 {
+		v.Add("%s.value or values is required for %s", field, cond.Op)
+		return
+	}
+
+	for _, value := range values {
+		var err error
+		switch cond.Op {
+		case OpRegex:
+			_, err = regexp.Compile(value)
+		case OpGreater, OpLess:
+			_, err = strconv.ParseFloat(value, 64)
+		case OpLengthGt, OpLengthLt:
+			var n int
+			n, err = strconv.Atoi(value)
+			if err == nil && n < 0 {
+				err = errors.New("length must be >= 0")
+			}
+		case OpIPInCIDR:
+			_, _, err = net.ParseCIDR(value)
+		}
+		if err != nil {
+			v.Add("%s.value %q invalid: %v", field, value, err)
+		}
+	}
+}
+
 func validateListen(addr string) error {
 	if strings.TrimSpace(addr) == "" {
 		return errors.New("address is required")
@@ -257,3 +553,54 @@ func ensureWritable(path string) error {
 	}
 	return os.Remove(name)
 }
+
+func validateInspection(v *ValidationError, name string, budget InspectionBudget) {
+	if budget.MaxBytes < 0 {
+		v.Add("policies.%s.inspection.maxBytes must be >= 0", name)
+	}
+	if budget.MaxTime < 0 {
+		v.Add("policies.%s.inspection.maxTime must be >= 0", name)
+	}
+	switch budget.OnExceed {
+	case "", BudgetFailOpen, BudgetFailClosed:
+		if budget.Penalty != 0 {
+			v.Add("policies.%s.inspection.penalty requires onExceed score", name)
+		}
+	case BudgetScore:
+		if budget.Penalty <= 0 {
+			v.Add("policies.%s.inspection.penalty must be > 0 for onExceed score", name)
+		}
+	default:
+		v.Add("policies.%s.inspection.onExceed must be fail_open|fail_closed|score", name)
+	}
+}
+
+func validateCapture(v *ValidationError, capture CaptureConfig) {
+	if capture.Enabled && capture.Dir == "" {
+		v.Add("capture.dir is required when capture is enabled")
+	}
+	if capture.MaxBodyBytes < 0 {
+		v.Add("capture.maxBodyBytes must be >= 0")
+	}
+	if capture.MaxFiles < 0 {
+		v.Add("capture.maxFiles must be >= 0")
+	}
+	if capture.Retention < 0 {
+		v.Add("capture.retention must be >= 0")
+	}
+	for i, name := range capture.Redact.Headers {
+		if strings.TrimSpace(name) == "" {
+			v.Add("capture.redact.headers[%d] is empty", i)
+		}
+	}
+	for i, name := range capture.Redact.Params {
+		if name == "" {
+			v.Add("capture.redact.params[%d] is empty", i)
+		}
+	}
+	for i, pattern := range capture.Redact.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			v.Add("capture.redact.patterns[%d] invalid: %v", i, err)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package contract
 
-import "time"
+import (
+	"sort"
+	"strings"
+	"time"
+)
 
 type Enforcement string
 
@@ -21,8 +25,33 @@ type Contract struct {
 	HeaderNames  map[string]bool `json:"header_names"`
 	MaxBodyBytes int64           `json:"max_body_bytes"`
 	ObservedMax  int64           `json:"observed_max_body_bytes"`
+
+	QueryParamValues map[string]*ValueProfile `json:"query_param_values,omitempty"`
+	HeaderValues     map[string]*ValueProfile `json:"header_values,omitempty"`
+	Endpoints        map[string]*Endpoint     `json:"endpoints,omitempty"`
+	DroppedEndpoints int                      `json:"dropped_endpoints,omitempty"`
+	Stats            map[string]*ItemStats    `json:"stats,omitempty"`
+}
+
+// Endpoint is the learned profile of one path template within a route.
+type Endpoint struct {
+	Template     string          `json:"template"`
+	Samples      int             `json:"samples"`
+	Methods      map[string]bool `json:"methods"`
+	ContentTypes map[string]bool `json:"content_types"`
+	QueryParams  map[string]bool `json:"query_params"`
+	MaxBodyBytes int64           `json:"max_body_bytes"`
+	ObservedMax  int64           `json:"observed_max_body_bytes"`
+
+	QueryParamValues map[string]*ValueProfile `json:"query_param_values,omitempty"`
+	BodySchema       *Schema                  `json:"body_schema,omitempty"`
+	Stats            map[string]*ItemStats    `json:"stats,omitempty"`
 }
 
+// maxEndpoints bounds how many templates a contract learns so that paths
+// that do not template well cannot grow it without limit.
+const maxEndpoints = 512
+
 func New(routeID, policy string) *Contract {
 	return &Contract{
 		RouteID:      routeID,
@@ -32,7 +61,59 @@ func New(routeID, policy string) *Contract {
 		ContentTypes: map[string]bool{},
 		QueryParams:  map[string]bool{},
 		HeaderNames:  map[string]bool{},
+
+		QueryParamValues: map[string]*ValueProfile{},
+		HeaderValues:     map[string]*ValueProfile{},
+		Endpoints:        map[string]*Endpoint{},
+	}
+}
+
+func newEndpoint(template string) *Endpoint {
+	return &Endpoint{
+		Template:     template,
+		Methods:      map[string]bool{},
+		ContentTypes: map[string]bool{},
+		QueryParams:  map[string]bool{},
+
+		QueryParamValues: map[string]*ValueProfile{},
+	}
+}
+
+// MatchEndpoint returns the endpoint whose template fits path. An exact
+// template hit wins; otherwise the candidate with the most literal segments
+// is chosen.
+func (c *Contract) MatchEndpoint(path string) *Endpoint {
+	if c == nil || len(c.Endpoints) == 0 {
+		return nil
+	}
+	if ep, ok := c.Endpoints[TemplatePath(path)]; ok {
+		return ep
+	}
+
+	templates := make([]string, 0, len(c.Endpoints))
+	for template := range c.Endpoints {
+		templates = append(templates, template)
+	}
+	sort.Strings(templates)
+
+	var best *Endpoint
+	bestLiterals := -1
+	for _, template := range templates {
+		if !MatchTemplate(template, path) {
+			continue
+		}
+		literals := 0
+		for _, seg := range strings.Split(template, "/") {
+			if !isPlaceholder(seg) {
+				literals++
+			}
+		}
+		if literals > bestLiterals {
+			best = c.Endpoints[template]
+			bestLiterals = literals
+		}
 	}
+	return best
 }
 
 func (c *Contract) Finalize(marginBytes int64) {
@@ -40,4 +121,112 @@ func (c *Contract) Finalize(marginBytes int64) {
 		marginBytes = 0
 	}
 	c.MaxBodyBytes = c.ObservedMax + marginBytes
+	finalizeProfiles(c.QueryParamValues)
+	finalizeProfiles(c.HeaderValues)
+	for _, ep := range c.Endpoints {
+		ep.MaxBodyBytes = ep.ObservedMax + marginBytes
+		finalizeProfiles(ep.QueryParamValues)
+	}
+}
+
+func finalizeProfiles(profiles map[string]*ValueProfile) {
+	for _, p := range profiles {
+		p.finalize()
+	}
+}
+
+// Merge folds the observations in other into c. Sample counts are summed and
+// the observed body maximum is the larger of the two.
+func (c *Contract) Merge(other *Contract) {
+	if other == nil {
+		return
+	}
+	c.Samples += other.Samples
+	mergeSet(&c.Methods, other.Methods)
+	mergeSet(&c.ContentTypes, other.ContentTypes)
+	mergeSet(&c.QueryParams, other.QueryParams)
+	mergeSet(&c.HeaderNames, other.HeaderNames)
+	if other.ObservedMax > c.ObservedMax {
+		c.ObservedMax = other.ObservedMax
+	}
+	mergeProfiles(&c.QueryParamValues, other.QueryParamValues)
+	mergeProfiles(&c.HeaderValues, other.HeaderValues)
+	mergeStats(&c.Stats, other.Stats)
+	c.DroppedEndpoints += other.DroppedEndpoints
+
+	for template, src := range other.Endpoints {
+		if c.Endpoints == nil {
+			c.Endpoints = map[string]*Endpoint{}
+		}
+		dst, ok := c.Endpoints[template]
+		if !ok {
+			if len(c.Endpoints) >= maxEndpoints {
+				c.DroppedEndpoints += src.Samples
+				continue
+			}
+			dst = newEndpoint(template)
+			c.Endpoints[template] = dst
+		}
+		dst.merge(src)
+	}
+}
+
+func (e *Endpoint) merge(other *Endpoint) {
+	e.Samples += other.Samples
+	mergeSet(&e.Methods, other.Methods)
+	mergeSet(&e.ContentTypes, other.ContentTypes)
+	mergeSet(&e.QueryParams, other.QueryParams)
+	mergeProfiles(&e.QueryParamValues, other.QueryParamValues)
+	mergeStats(&e.Stats, other.Stats)
+	if other.BodySchema != nil {
+		if e.BodySchema == nil {
+			e.BodySchema = newSchema()
+		}
+		e.BodySchema.merge(other.BodySchema)
+	}
+	if other.ObservedMax > e.ObservedMax {
+		e.ObservedMax = other.ObservedMax
+	}
+	if other.MaxBodyBytes > e.MaxBodyBytes {
+		e.MaxBodyBytes = other.MaxBodyBytes
+	}
+}
+
+func mergeSet(dst *map[string]bool, src map[string]bool) {
+	if len(src) == 0 {
+		return
+	}
+	if *dst == nil {
+		*dst = map[string]bool{}
+	}
+	for key, ok := range src {
+		if ok {
+			(*dst)[key] = true
+		}
+	}
+}
+
+func mergeProfiles(dst *map[string]*ValueProfile, src map[string]*ValueProfile) {
+	if len(src) == 0 {
+		return
+	}
+	if *dst == nil {
+		*dst = map[string]*ValueProfile{}
+	}
+	for name, p := range src {
+		if existing, ok := (*dst)[name]; ok {
+			existing.merge(p)
+		} else {
+			(*dst)[name] = p.clone()
+		}
+	}
+}
+
+func profileFor(profiles map[string]*ValueProfile, name string) *ValueProfile {
+	p, ok := profiles[name]
+	if !ok {
+		p = newValueProfile(name)
+		profiles[name] = p
+	}
+	return p
 }
@@ -21,8 +21,8 @@ func TestContractObserveAndFinalize(t *testing.T) {
 		},
 	}
 
-	c.Observe(req, 128)
-	c.Observe(req, 64)
+	c.Observe(req, nil, 128)
+	c.Observe(req, nil, 64)
 
 	if c.Samples != 2 {
 		t.Fatalf("expected 2 samples, got %d", c.Samples)
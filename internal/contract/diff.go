@@ -0,0 +1,295 @@
+package contract
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// Change is one difference between two contracts. A widening change lets
+// through traffic that the old contract would have flagged.
+type Change struct {
+	Kind     string `json:"kind"`
+	Scope    string `json:"scope"`
+	Endpoint string `json:"endpoint,omitempty"`
+	Name     string `json:"name,omitempty"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	Widening bool   `json:"widening"`
+}
+
+func (c Change) String() string {
+	var b strings.Builder
+	if c.Widening {
+		b.WriteString("! ")
+	} else {
+		b.WriteString("  ")
+	}
+	fmt.Fprintf(&b, "%s %s", c.Kind, c.Scope)
+	if c.Endpoint != "" {
+		fmt.Fprintf(&b, " [%s]", c.Endpoint)
+	}
+	if c.Name != "" {
+		fmt.Fprintf(&b, " %s", c.Name)
+	}
+	if c.Kind == ChangeChanged {
+		fmt.Fprintf(&b, ": %s -> %s", c.From, c.To)
+	}
+	return b.String()
+}
+
+// Widens reports whether any change loosens the contract.
+func Widens(changes []Change) bool {
+	for _, c := range changes {
+		if c.Widening {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff lists what changed from old to updated, contract-wide first and then
+// per endpoint, in a stable order.
+func Diff(old, updated *Contract) []Change {
+	if old == nil {
+		old = New("", "")
+	}
+	if updated == nil {
+		updated = New("", "")
+	}
+
+	var changes []Change
+	// An empty method or content type set accepts anything, so the first
+	// entry narrows the contract rather than widening it.
+	changes = diffSet(changes, "method", "", old.Methods, updated.Methods, true)
+	changes = diffSet(changes, "content_type", "", old.ContentTypes, updated.ContentTypes, true)
+	changes = diffSet(changes, "query_param", "", old.QueryParams, updated.QueryParams, false)
+	changes = diffSet(changes, "header", "", old.HeaderNames, updated.HeaderNames, false)
+	changes = diffBodyLimit(changes, "", old.MaxBodyBytes, updated.MaxBodyBytes)
+	changes = diffProfiles(changes, "query_param_value", "", updated.QueryParams, old.QueryParamValues, updated.QueryParamValues)
+	changes = diffProfiles(changes, "header_value", "", updated.HeaderNames, old.HeaderValues, updated.HeaderValues)
+
+	for _, template := range unionKeys(old.Endpoints, updated.Endpoints) {
+		before, after := old.Endpoints[template], updated.Endpoints[template]
+		switch {
+		case before == nil:
+			changes = append(changes, Change{Kind: ChangeAdded, Scope: "endpoint", Endpoint: template, Widening: len(old.Endpoints) > 0})
+		case after == nil:
+			changes = append(changes, Change{Kind: ChangeRemoved, Scope: "endpoint", Endpoint: template})
+		default:
+			changes = diffSet(changes, "method", template, before.Methods, after.Methods, true)
+			changes = diffSet(changes, "content_type", template, before.ContentTypes, after.ContentTypes, true)
+			changes = diffSet(changes, "query_param", template, before.QueryParams, after.QueryParams, false)
+			changes = diffBodyLimit(changes, template, before.MaxBodyBytes, after.MaxBodyBytes)
+			changes = diffProfiles(changes, "query_param_value", template, after.QueryParams, before.QueryParamValues, after.QueryParamValues)
+			changes = diffSchema(changes, template, "", before.BodySchema, after.BodySchema)
+		}
+	}
+	if len(old.Endpoints) == 0 && len(updated.Endpoints) > 0 {
+		changes = append(changes, Change{Kind: ChangeAdded, Scope: "endpoints", Name: "per-endpoint profiles"})
+	} else if len(old.Endpoints) > 0 && len(updated.Endpoints) == 0 {
+		changes = append(changes, Change{Kind: ChangeRemoved, Scope: "endpoints", Name: "per-endpoint profiles", Widening: true})
+	}
+	return changes
+}
+
+func diffSet(changes []Change, scope, endpoint string, old, updated map[string]bool, emptyAllowsAll bool) []Change {
+	for _, name := range unionKeys(old, updated) {
+		switch {
+		case updated[name] && !old[name]:
+			changes = append(changes, Change{Kind: ChangeAdded, Scope: scope, Endpoint: endpoint, Name: name, Widening: !emptyAllowsAll || len(old) > 0})
+		case old[name] && !updated[name]:
+			changes = append(changes, Change{Kind: ChangeRemoved, Scope: scope, Endpoint: endpoint, Name: name, Widening: emptyAllowsAll && len(updated) == 0})
+		}
+	}
+	return changes
+}
+
+func diffBodyLimit(changes []Change, endpoint string, old, updated int64) []Change {
+	if old == updated {
+		return changes
+	}
+	// Zero disables the size check.
+	widening := updated == 0 || (old != 0 && updated > old)
+	return append(changes, Change{
+		Kind:     ChangeChanged,
+		Scope:    "max_body_bytes",
+		Endpoint: endpoint,
+		From:     fmt.Sprint(old),
+		To:       fmt.Sprint(updated),
+		Widening: widening,
+	})
+}
+
+// diffProfiles compares value profiles for the names in both contracts;
+// names that come or go are already reported by diffSet.
+func diffProfiles(changes []Change, scope, endpoint string, names map[string]bool, old, updated map[string]*ValueProfile) []Change {
+	for _, name := range unionKeys(old, updated) {
+		before, after := old[name], updated[name]
+		if before == nil || after == nil {
+			// A profile dropped for a name that is still allowed leaves its
+			// values unchecked.
+			if before != nil && names[name] {
+				changes = append(changes, Change{Kind: ChangeRemoved, Scope: scope, Endpoint: endpoint, Name: name, Widening: true})
+			}
+			continue
+		}
+		from, to := before.summary(), after.summary()
+		if from == to {
+			continue
+		}
+		changes = append(changes, Change{
+			Kind:     ChangeChanged,
+			Scope:    scope,
+			Endpoint: endpoint,
+			Name:     name,
+			From:     from,
+			To:       to,
+			Widening: profileWidens(before, after),
+		})
+	}
+	return changes
+}
+
+func (p *ValueProfile) summary() string {
+	var parts []string
+	switch p.Type {
+	case ValueInteger:
+		parts = append(parts, fmt.Sprintf("integer[%d..%d]", p.Min, p.Max))
+	case ValueEnum:
+		parts = append(parts, "enum("+strings.Join(p.Enum, "|")+")")
+	default:
+		parts = append(parts, string(p.Type))
+	}
+	if p.MaxLength > 0 {
+		parts = append(parts, fmt.Sprintf("max_length=%d", p.MaxLength))
+	}
+	if len(p.CharClasses) > 0 {
+		classes := append([]string(nil), p.CharClasses...)
+		sort.Strings(classes)
+		parts = append(parts, "chars="+strings.Join(classes, ","))
+	}
+	if p.Symbols != "" {
+		parts = append(parts, fmt.Sprintf("symbols=%q", p.Symbols))
+	}
+	return strings.Join(parts, " ")
+}
+
+func profileWidens(old, updated *ValueProfile) bool {
+	if old.Type != updated.Type {
+		// A string profile already accepts any shape.
+		return old.Type != ValueString && old.Type != ""
+	}
+	if old.MaxLength > 0 && (updated.MaxLength == 0 || updated.MaxLength > old.MaxLength) {
+		return true
+	}
+	if updated.Type == ValueInteger && (updated.Min < old.Min || updated.Max > old.Max) {
+		return true
+	}
+	for _, value := range updated.Enum {
+		if !containsString(old.Enum, value) {
+			return true
+		}
+	}
+	if len(old.CharClasses) > 0 {
+		if len(updated.CharClasses) == 0 {
+			return true
+		}
+		for _, class := range updated.CharClasses {
+			if !containsString(old.CharClasses, class) {
+				return true
+			}
+		}
+		for _, r := range updated.Symbols {
+			if !strings.ContainsRune(old.Symbols, r) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func diffSchema(changes []Change, endpoint, pointer string, old, updated *Schema) []Change {
+	name := pointer
+	if name == "" {
+		name = "/"
+	}
+	switch {
+	case old == nil && updated == nil:
+		return changes
+	case old == nil:
+		// Without a schema the body was not checked at all.
+		return append(changes, Change{Kind: ChangeAdded, Scope: "body_schema", Endpoint: endpoint, Name: name})
+	case updated == nil:
+		return append(changes, Change{Kind: ChangeRemoved, Scope: "body_schema", Endpoint: endpoint, Name: name, Widening: true})
+	}
+
+	for _, kind := range unionKeys(old.Types, updated.Types) {
+		before, after := old.Types[kind] > 0, updated.Types[kind] > 0
+		switch {
+		case after && !before:
+			changes = append(changes, Change{Kind: ChangeAdded, Scope: "body_type", Endpoint: endpoint, Name: name + " " + kind, Widening: !old.allows(kind)})
+		case before && !after:
+			changes = append(changes, Change{Kind: ChangeRemoved, Scope: "body_type", Endpoint: endpoint, Name: name + " " + kind})
+		}
+	}
+	if old.Open != updated.Open {
+		changes = append(changes, Change{Kind: ChangeChanged, Scope: "body_open", Endpoint: endpoint, Name: name, From: fmt.Sprint(old.Open), To: fmt.Sprint(updated.Open), Widening: updated.Open})
+	}
+	if old.Open && updated.Open {
+		return changes
+	}
+
+	oldRequired, newRequired := old.Required(), updated.Required()
+	for _, field := range oldRequired {
+		if !containsString(newRequired, field) {
+			changes = append(changes, Change{Kind: ChangeRemoved, Scope: "body_required", Endpoint: endpoint, Name: pointer + "/" + escapePointer(field), Widening: true})
+		}
+	}
+	for _, field := range newRequired {
+		if !containsString(oldRequired, field) {
+			changes = append(changes, Change{Kind: ChangeAdded, Scope: "body_required", Endpoint: endpoint, Name: pointer + "/" + escapePointer(field)})
+		}
+	}
+	if old.MaxItems != updated.MaxItems && (old.Types[jsonArray] > 0 || updated.Types[jsonArray] > 0) {
+		changes = append(changes, Change{Kind: ChangeChanged, Scope: "body_max_items", Endpoint: endpoint, Name: name, From: fmt.Sprint(old.MaxItems), To: fmt.Sprint(updated.MaxItems), Widening: updated.MaxItems > old.MaxItems})
+	}
+	if old.MaxLength != updated.MaxLength && (old.Types[jsonString] > 0 || updated.Types[jsonString] > 0) {
+		changes = append(changes, Change{Kind: ChangeChanged, Scope: "body_max_length", Endpoint: endpoint, Name: name, From: fmt.Sprint(old.MaxLength), To: fmt.Sprint(updated.MaxLength), Widening: updated.MaxLength > old.MaxLength})
+	}
+
+	for _, field := range unionKeys(old.Properties, updated.Properties) {
+		child := pointer + "/" + escapePointer(field)
+		before, after := old.Properties[field], updated.Properties[field]
+		switch {
+		case before == nil:
+			changes = append(changes, Change{Kind: ChangeAdded, Scope: "body_field", Endpoint: endpoint, Name: child, Widening: !old.Open})
+		case after == nil:
+			changes = append(changes, Change{Kind: ChangeRemoved, Scope: "body_field", Endpoint: endpoint, Name: child, Widening: updated.Open})
+		default:
+			changes = diffSchema(changes, endpoint, child, before, after)
+		}
+	}
+	changes = diffSchema(changes, endpoint, pointer+"/*", old.Items, updated.Items)
+	return changes
+}
+
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,102 @@
+package contract
+
+import "testing"
+
+func TestDiffReportsWidening(t *testing.T) {
+	old := New("route-0", "api")
+	old.Methods["GET"] = true
+	old.QueryParams["limit"] = true
+	old.QueryParamValues["limit"] = &ValueProfile{Type: ValueInteger, Min: 1, Max: 50, MaxLength: 10}
+	old.MaxBodyBytes = 1024
+	old.Endpoints["/users/{id}"] = &Endpoint{
+		Template: "/users/{id}",
+		Methods:  map[string]bool{"GET": true},
+		BodySchema: &Schema{
+			Types:      map[string]int{"object": 2},
+			Objects:    2,
+			Properties: map[string]*Schema{"name": {Types: map[string]int{"string": 2}, MaxLength: 12}},
+		},
+	}
+
+	updated := New("route-0", "api")
+	updated.Methods["GET"] = true
+	updated.Methods["POST"] = true
+	updated.QueryParams["limit"] = true
+	updated.QueryParamValues["limit"] = &ValueProfile{Type: ValueInteger, Min: 1, Max: 500, MaxLength: 10}
+	updated.MaxBodyBytes = 512
+	updated.Endpoints["/users/{id}"] = &Endpoint{
+		Template: "/users/{id}",
+		Methods:  map[string]bool{"GET": true},
+		BodySchema: &Schema{
+			Types:   map[string]int{"object": 2},
+			Objects: 2,
+			Properties: map[string]*Schema{
+				"name": {Types: map[string]int{"string": 2}, MaxLength: 12},
+				"role": {Types: map[string]int{"string": 1}, MaxLength: 5},
+			},
+		},
+	}
+	updated.Endpoints["/health"] = newEndpoint("/health")
+
+	changes := Diff(old, updated)
+	got := map[string]bool{}
+	for _, c := range changes {
+		got[c.Kind+" "+c.Scope+" "+c.Endpoint+" "+c.Name] = c.Widening
+	}
+	expected := map[string]bool{
+		"added method  POST":                 true,
+		"changed max_body_bytes  ":           false,
+		"changed query_param_value  limit":   true,
+		"added endpoint /health ":            true,
+		"added body_field /users/{id} /role": true,
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d changes, got %v", len(expected), changes)
+	}
+	for key, widening := range expected {
+		w, ok := got[key]
+		if !ok {
+			t.Fatalf("missing change %q in %v", key, changes)
+		}
+		if w != widening {
+			t.Fatalf("change %q: expected widening=%v", key, widening)
+		}
+	}
+	if !Widens(changes) {
+		t.Fatalf("expected diff to widen")
+	}
+}
+
+func TestDiffNarrowingOnly(t *testing.T) {
+	old := New("route-0", "api")
+	old.Methods["GET"] = true
+	old.Methods["DELETE"] = true
+	old.HeaderNames["X-Debug"] = true
+
+	updated := New("route-0", "api")
+	updated.Methods["GET"] = true
+
+	changes := Diff(old, updated)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %v", changes)
+	}
+	if Widens(changes) {
+		t.Fatalf("removals should not widen: %v", changes)
+	}
+	if len(Diff(old, old)) != 0 {
+		t.Fatalf("expected no changes against itself")
+	}
+}
+
+func TestDiffEmptyMethodSetAcceptsAll(t *testing.T) {
+	old := New("route-0", "api")
+	updated := New("route-0", "api")
+	updated.Methods["GET"] = true
+
+	if Widens(Diff(old, updated)) {
+		t.Fatalf("restricting methods should not widen")
+	}
+	if !Widens(Diff(updated, old)) {
+		t.Fatalf("dropping the last method should widen")
+	}
+}
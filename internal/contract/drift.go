@@ -0,0 +1,474 @@
+package contract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	CandidateObserving = "observing"
+	CandidateProposed  = "proposed"
+	CandidatePromoted  = "promoted"
+)
+
+// maxDriftCandidates bounds how many distinct additions are tracked per
+// contract so that scanners cannot grow the tracker without limit.
+const maxDriftCandidates = 1024
+
+// Candidate is an addition to an enforced contract seen on traffic that
+// otherwise passed. Kind uses the scopes reported by Diff. Clients counts
+// distinct client IPs only up to MinClients, the most the threshold needs, so
+// it is a floor rather than a total.
+type Candidate struct {
+	Kind      string    `json:"kind"`
+	Endpoint  string    `json:"endpoint,omitempty"`
+	Name      string    `json:"name"`
+	Count     int       `json:"count"`
+	Clients   int       `json:"clients"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	State     string    `json:"state"`
+	Methods   []string  `json:"methods,omitempty"`
+	JSONType  string    `json:"json_type,omitempty"`
+
+	clients map[string]struct{}
+}
+
+func (c *Candidate) key() string {
+	return c.Kind + "|" + c.Endpoint + "|" + c.Name
+}
+
+func (c Candidate) String() string {
+	if c.Endpoint != "" {
+		return fmt.Sprintf("%s %s [%s]", c.Kind, c.Name, c.Endpoint)
+	}
+	return c.Kind + " " + c.Name
+}
+
+type DriftOptions struct {
+	MinCount   int
+	MinClients int
+	// Promote applies candidates to the live contract once they cross the
+	// thresholds instead of only proposing them.
+	Promote bool
+}
+
+// DriftTracker keeps learning on top of an enforced contract. Requests whose
+// only violations are additions (a new parameter, header, method, content
+// type, endpoint or body field) are counted per addition, and an addition sent
+// often enough by enough distinct clients is proposed or promoted.
+type DriftTracker struct {
+	opts    DriftOptions
+	current atomic.Pointer[Contract]
+
+	mu         sync.Mutex
+	candidates map[string]*Candidate
+	writeMu    sync.Mutex
+}
+
+func NewDriftTracker(c *Contract, opts DriftOptions) *DriftTracker {
+	opts.MinCount = max(opts.MinCount, 1)
+	opts.MinClients = max(opts.MinClients, 1)
+	t := &DriftTracker{opts: opts, candidates: map[string]*Candidate{}}
+	t.current.Store(c)
+	return t
+}
+
+// Contract returns the live contract, including promoted candidates.
+func (t *DriftTracker) Contract() *Contract {
+	return t.current.Load()
+}
+
+// Rebase starts a tracker for a reloaded contract that keeps the candidates
+// seen so far and re-applies the promoted ones.
+func (t *DriftTracker) Rebase(c *Contract, opts DriftOptions) (*DriftTracker, error) {
+	next := NewDriftTracker(c, opts)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var promoted []*Candidate
+	for key, cand := range t.candidates {
+		copied := *cand
+		copied.clients = make(map[string]struct{}, len(cand.clients))
+		for client := range cand.clients {
+			copied.clients[client] = struct{}{}
+		}
+		next.candidates[key] = &copied
+		if copied.State == CandidatePromoted {
+			promoted = append(promoted, &copied)
+		}
+	}
+	if len(promoted) > 0 {
+		if err := next.promote(promoted); err != nil {
+			return nil, err
+		}
+	}
+	return next, nil
+}
+
+// LoadPending restores the proposed and promoted candidates written by
+// WritePending and re-applies the promoted ones, so they survive a restart.
+// A missing file is not an error.
+func (t *DriftTracker) LoadPending(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var pending []Candidate
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var promoted []*Candidate
+	for i := range pending {
+		cand := &pending[i]
+		if cand.State != CandidateProposed && cand.State != CandidatePromoted {
+			continue
+		}
+		if _, ok := t.candidates[cand.key()]; ok || len(t.candidates) >= maxDriftCandidates {
+			continue
+		}
+		cand.clients = map[string]struct{}{}
+		t.candidates[cand.key()] = cand
+		if cand.State == CandidatePromoted {
+			promoted = append(promoted, cand)
+		}
+	}
+	if len(promoted) > 0 {
+		return t.promote(promoted)
+	}
+	return nil
+}
+
+// Observe records the additions in violations and returns the candidates that
+// crossed the thresholds with this request, already proposed or promoted.
+// Requests with any other kind of violation are ignored.
+func (t *DriftTracker) Observe(violations []Violation, req *http.Request, body []byte, clientIP string, now time.Time) ([]Candidate, error) {
+	if len(violations) == 0 || req == nil {
+		return nil, nil
+	}
+	c := t.current.Load()
+
+	var endpoint string
+	var bodySchema *Schema
+	if ep := c.MatchEndpoint(req.URL.Path); ep != nil {
+		endpoint = ep.Template
+		bodySchema = ep.BodySchema
+	}
+
+	var value any
+	decoded := false
+	additions := make([]Candidate, 0, len(violations))
+	for _, v := range violations {
+		cand := Candidate{Endpoint: endpoint, Name: v.Field}
+		switch v.Type {
+		case "method_unexpected":
+			cand.Kind = "method"
+		case "content_type_unexpected":
+			cand.Kind = "content_type"
+		case "query_param_unexpected":
+			cand.Kind = "query_param"
+		case "header_unexpected":
+			cand.Kind, cand.Endpoint = "header", ""
+		case "path_unexpected":
+			cand.Kind, cand.Name, cand.Endpoint = "endpoint", TemplatePath(v.Field), ""
+			cand.Methods = []string{req.Method}
+		case "body_field_unexpected":
+			if !decoded {
+				value, decoded = decodeJSON(body)
+				if !decoded {
+					return nil, nil
+				}
+			}
+			pointer, ok := normalizePointer(bodySchema, v.Field)
+			field, found := lookupPointer(value, v.Field)
+			if !ok || !found {
+				return nil, nil
+			}
+			cand.Kind, cand.Name, cand.JSONType = "body_field", pointer, jsonKind(field)
+		default:
+			return nil, nil
+		}
+		additions = append(additions, cand)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var ready []*Candidate
+	for i := range additions {
+		addition := &additions[i]
+		cand, ok := t.candidates[addition.key()]
+		if !ok {
+			if len(t.candidates) >= maxDriftCandidates {
+				continue
+			}
+			cand = addition
+			cand.State = CandidateObserving
+			cand.FirstSeen = now
+			cand.clients = map[string]struct{}{}
+			t.candidates[cand.key()] = cand
+		} else {
+			for _, method := range addition.Methods {
+				if !containsString(cand.Methods, method) {
+					cand.Methods = append(cand.Methods, method)
+				}
+			}
+		}
+		cand.Count++
+		cand.LastSeen = now
+		if cand.Clients < t.opts.MinClients {
+			if _, seen := cand.clients[clientIP]; !seen {
+				cand.clients[clientIP] = struct{}{}
+				cand.Clients++
+			}
+		}
+		if cand.State == CandidateObserving && cand.Count >= t.opts.MinCount && cand.Clients >= t.opts.MinClients {
+			ready = append(ready, cand)
+		}
+	}
+	if len(ready) == 0 {
+		return nil, nil
+	}
+
+	state := CandidateProposed
+	if t.opts.Promote {
+		if err := t.promote(ready); err != nil {
+			return nil, err
+		}
+		state = CandidatePromoted
+	}
+	out := make([]Candidate, len(ready))
+	for i, cand := range ready {
+		cand.State = state
+		out[i] = *cand
+	}
+	return out, nil
+}
+
+// promote applies candidates to a copy of the live contract and swaps it in;
+// requests already evaluating keep the contract they loaded.
+func (t *DriftTracker) promote(candidates []*Candidate) error {
+	next, err := t.current.Load().Clone()
+	if err != nil {
+		return err
+	}
+	for _, cand := range candidates {
+		next.apply(cand)
+	}
+	t.current.Store(next)
+	return nil
+}
+
+// Candidates returns every tracked candidate, most frequent first.
+func (t *DriftTracker) Candidates() []Candidate {
+	t.mu.Lock()
+	out := make([]Candidate, 0, len(t.candidates))
+	for _, cand := range t.candidates {
+		copied := *cand
+		copied.clients = nil
+		out = append(out, copied)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].key() < out[j].key()
+	})
+	return out
+}
+
+// DriftCount is the number of candidates in one state for a route.
+type DriftCount struct {
+	RouteID    string
+	Policy     string
+	State      string
+	Candidates int
+}
+
+// Counts returns how many candidates are in each state.
+func (t *DriftTracker) Counts(routeID, policy string) []DriftCount {
+	counts := map[string]int{}
+	t.mu.Lock()
+	for _, cand := range t.candidates {
+		counts[cand.State]++
+	}
+	t.mu.Unlock()
+
+	out := make([]DriftCount, 0, 3)
+	for _, state := range []string{CandidateObserving, CandidateProposed, CandidatePromoted} {
+		out = append(out, DriftCount{RouteID: routeID, Policy: policy, State: state, Candidates: counts[state]})
+	}
+	return out
+}
+
+// WritePending writes the proposed and promoted candidates to path for
+// review.
+func (t *DriftTracker) WritePending(path string) error {
+	var pending []Candidate
+	for _, cand := range t.Candidates() {
+		if cand.State != CandidateObserving {
+			pending = append(pending, cand)
+		}
+	}
+	if pending == nil {
+		pending = []Candidate{}
+	}
+	data, err := json.MarshalIndent(pending, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Clone returns a deep copy of the enforced profile of c. Learning state is
+// not copied.
+func (c *Contract) Clone() (*Contract, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var out Contract
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Contract) apply(cand *Candidate) {
+	ep := c.Endpoints[cand.Endpoint]
+	switch cand.Kind {
+	case "method":
+		if ep != nil {
+			setAdd(&ep.Methods, cand.Name)
+		}
+		setAdd(&c.Methods, cand.Name)
+	case "content_type":
+		if ep != nil {
+			setAdd(&ep.ContentTypes, cand.Name)
+		}
+		setAdd(&c.ContentTypes, cand.Name)
+	case "query_param":
+		if ep != nil {
+			setAdd(&ep.QueryParams, cand.Name)
+		}
+		setAdd(&c.QueryParams, cand.Name)
+	case "header":
+		setAdd(&c.HeaderNames, http.CanonicalHeaderKey(cand.Name))
+	case "endpoint":
+		if _, ok := c.Endpoints[cand.Name]; ok {
+			return
+		}
+		if c.Endpoints == nil {
+			c.Endpoints = map[string]*Endpoint{}
+		}
+		added := newEndpoint(cand.Name)
+		for _, method := range cand.Methods {
+			added.Methods[method] = true
+		}
+		c.Endpoints[cand.Name] = added
+	case "body_field":
+		if ep == nil || ep.BodySchema == nil {
+			return
+		}
+		segments := strings.Split(strings.TrimPrefix(cand.Name, "/"), "/")
+		parent := ep.BodySchema
+		for _, seg := range segments[:len(segments)-1] {
+			if seg == "*" {
+				parent = parent.Items
+			} else {
+				parent = parent.Properties[unescapePointer(seg)]
+			}
+			if parent == nil {
+				return
+			}
+		}
+		if parent.Properties == nil {
+			parent.Properties = map[string]*Schema{}
+		}
+		// The promoted field is left open: only its presence was learned.
+		parent.Properties[unescapePointer(segments[len(segments)-1])] = &Schema{Types: map[string]int{cand.JSONType: 1}, Open: true}
+	}
+}
+
+func setAdd(set *map[string]bool, name string) {
+	if *set == nil {
+		*set = map[string]bool{}
+	}
+	(*set)[name] = true
+}
+
+// normalizePointer rewrites array indexes in a violation pointer to "*" so
+// that the same field reported at different positions is one candidate.
+func normalizePointer(schema *Schema, pointer string) (string, bool) {
+	if schema == nil || !strings.HasPrefix(pointer, "/") {
+		return "", false
+	}
+	segments := strings.Split(pointer[1:], "/")
+	node := schema
+	for i, seg := range segments[:len(segments)-1] {
+		if _, err := strconv.Atoi(seg); err == nil && node.Items != nil {
+			segments[i] = "*"
+			node = node.Items
+			continue
+		}
+		node = node.Properties[unescapePointer(seg)]
+		if node == nil {
+			return "", false
+		}
+	}
+	return "/" + strings.Join(segments, "/"), true
+}
+
+func lookupPointer(value any, pointer string) (any, bool) {
+	for _, seg := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		switch v := value.(type) {
+		case map[string]any:
+			next, ok := v[unescapePointer(seg)]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []any:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func unescapePointer(seg string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(seg, "~1", "/"), "~0", "~")
+}
@@ -0,0 +1,131 @@
+package contract
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDriftTrackerProposes(t *testing.T) {
+	c := New("route-0", "api")
+	c.Methods["POST"] = true
+	ep := newEndpoint("/users")
+	ep.Methods["POST"] = true
+	ep.ContentTypes["application/json"] = true
+	ep.BodySchema = &Schema{
+		Types:      map[string]int{"object": 1},
+		Objects:    1,
+		Properties: map[string]*Schema{"name": {Types: map[string]int{"string": 1}}},
+	}
+	c.Endpoints["/users"] = ep
+
+	tracker := NewDriftTracker(c, DriftOptions{MinCount: 2, MinClients: 1})
+	body := `{"name":"a","role":"admin"}`
+	now := time.Now()
+
+	observe := func() []Candidate {
+		req := httptest.NewRequest("POST", "/users", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		violations := Evaluate(tracker.Contract(), req, []byte(body), int64(len(body)), EnforcementModerate)
+		ready, err := tracker.Observe(violations, req, []byte(body), "203.0.113.1", now)
+		if err != nil {
+			t.Fatalf("observe: %v", err)
+		}
+		return ready
+	}
+
+	if ready := observe(); len(ready) != 0 {
+		t.Fatalf("expected no candidate after one request, got %v", ready)
+	}
+	ready := observe()
+	if len(ready) != 1 || ready[0].Kind != "body_field" || ready[0].Name != "/role" || ready[0].State != CandidateProposed {
+		t.Fatalf("expected proposed /role, got %+v", ready)
+	}
+	if ready := observe(); len(ready) != 0 {
+		t.Fatalf("expected candidate to be reported once, got %v", ready)
+	}
+	if tracker.Contract() != c {
+		t.Fatalf("propose must not change the live contract")
+	}
+}
+
+func TestDriftTrackerPromotes(t *testing.T) {
+	c := New("route-0", "api")
+	c.Methods["GET"] = true
+	c.Endpoints["/users/{id}"] = newEndpoint("/users/{id}")
+	c.Endpoints["/users/{id}"].Methods["GET"] = true
+
+	tracker := NewDriftTracker(c, DriftOptions{MinCount: 1, MinClients: 2, Promote: true})
+	req := httptest.NewRequest("GET", "/orders/7?expand=1", nil)
+
+	for i, client := range []string{"a", "a", "b"} {
+		violations := Evaluate(tracker.Contract(), req, nil, 0, EnforcementModerate)
+		ready, err := tracker.Observe(violations, req, nil, client, time.Now())
+		if err != nil {
+			t.Fatalf("observe: %v", err)
+		}
+		if i < 2 && len(ready) != 0 {
+			t.Fatalf("request %d: expected no promotion before a second client, got %v", i, ready)
+		}
+		if i == 2 && (len(ready) != 1 || ready[0].Name != "/orders/{id}" || ready[0].State != CandidatePromoted) {
+			t.Fatalf("expected /orders/{id} promoted, got %+v", ready)
+		}
+	}
+
+	promoted := tracker.Contract()
+	if promoted == c || c.Endpoints["/orders/{id}"] != nil {
+		t.Fatalf("promotion must swap in a copy and leave the original untouched")
+	}
+	if ep := promoted.Endpoints["/orders/{id}"]; ep == nil || !ep.Methods["GET"] {
+		t.Fatalf("expected promoted endpoint with GET, got %+v", ep)
+	}
+
+	reloaded := New("route-0", "api")
+	reloaded.Methods["GET"] = true
+	reloaded.Endpoints["/users/{id}"] = newEndpoint("/users/{id}")
+	rebased, err := tracker.Rebase(reloaded, DriftOptions{MinCount: 1, MinClients: 2, Promote: true})
+	if err != nil {
+		t.Fatalf("rebase: %v", err)
+	}
+	if rebased.Contract().Endpoints["/orders/{id}"] == nil {
+		t.Fatalf("expected promotion to be re-applied after rebase")
+	}
+
+	pending := filepath.Join(t.TempDir(), "contract.pending.json")
+	if err := tracker.WritePending(pending); err != nil {
+		t.Fatalf("write pending: %v", err)
+	}
+	restarted := NewDriftTracker(reloaded, DriftOptions{MinCount: 1, MinClients: 2, Promote: true})
+	if err := restarted.LoadPending(pending); err != nil {
+		t.Fatalf("load pending: %v", err)
+	}
+	if restarted.Contract().Endpoints["/orders/{id}"] == nil {
+		t.Fatalf("expected promotion to be re-applied after restart")
+	}
+	violations := Evaluate(restarted.Contract(), req, nil, 0, EnforcementModerate)
+	if ready, err := restarted.Observe(violations, req, nil, "c", time.Now()); err != nil || len(ready) != 0 {
+		t.Fatalf("expected restored promotion not to be reported again, got %v, %v", ready, err)
+	}
+	if err := NewDriftTracker(reloaded, DriftOptions{}).LoadPending(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Fatalf("expected a missing pending file to be ignored, got %v", err)
+	}
+}
+
+func TestDriftTrackerIgnoresOtherViolations(t *testing.T) {
+	c := New("route-0", "api")
+	c.QueryParams["id"] = true
+	c.QueryParamValues["id"] = &ValueProfile{Type: ValueInteger, Min: 1, Max: 10}
+	tracker := NewDriftTracker(c, DriftOptions{MinCount: 1, MinClients: 1, Promote: true})
+
+	req := httptest.NewRequest("GET", "/?id=abc&extra=1", nil)
+	violations := Evaluate(c, req, nil, 0, EnforcementModerate)
+	if len(violations) != 2 {
+		t.Fatalf("expected type mismatch and unexpected param, got %v", violations)
+	}
+	ready, err := tracker.Observe(violations, req, nil, "a", time.Now())
+	if err != nil || len(ready) != 0 || len(tracker.Candidates()) != 0 {
+		t.Fatalf("expected request with a type mismatch to be ignored, got %v %v", ready, err)
+	}
+}
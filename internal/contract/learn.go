@@ -5,29 +5,87 @@ import (
 	"strings"
 )
 
-func (c *Contract) Observe(req *http.Request, bodySize int64) {
+// Observe records one request. It is not safe for concurrent use; the
+// gateway learns through a Learner.
+func (c *Contract) Observe(req *http.Request, body []byte, bodySize int64) {
 	if req == nil {
 		return
 	}
 
+	client := requestClient(req.RemoteAddr)
 	c.Samples++
 	c.Methods[req.Method] = true
+	observeStat(&c.Stats, statMethod, req.Method, client)
 
-	if ct := parseContentType(req.Header.Get("Content-Type")); ct != "" {
+	ct := parseContentType(req.Header.Get("Content-Type"))
+	if ct != "" {
 		c.ContentTypes[ct] = true
+		observeStat(&c.Stats, statContentType, ct, client)
 	}
 
-	for name := range req.URL.Query() {
+	query := req.URL.Query()
+	for name, values := range query {
 		c.QueryParams[name] = true
+		observeStat(&c.Stats, statQueryParam, name, client)
+		observeValues(ensureProfiles(&c.QueryParamValues), name, values)
 	}
 
-	for name := range req.Header {
-		c.HeaderNames[http.CanonicalHeaderKey(name)] = true
+	for name, values := range req.Header {
+		canon := http.CanonicalHeaderKey(name)
+		c.HeaderNames[canon] = true
+		observeStat(&c.Stats, statHeader, canon, client)
+		observeValues(ensureProfiles(&c.HeaderValues), canon, values)
 	}
 
 	if bodySize > c.ObservedMax {
 		c.ObservedMax = bodySize
 	}
+
+	ep := c.endpointFor(req.URL.Path)
+	if ep == nil {
+		return
+	}
+	observeStat(&c.Stats, statEndpoint, ep.Template, client)
+	ep.Samples++
+	ep.Methods[req.Method] = true
+	observeStat(&ep.Stats, statMethod, req.Method, client)
+	if ct != "" {
+		ep.ContentTypes[ct] = true
+		observeStat(&ep.Stats, statContentType, ct, client)
+	}
+	for name, values := range query {
+		ep.QueryParams[name] = true
+		observeStat(&ep.Stats, statQueryParam, name, client)
+		observeValues(ensureProfiles(&ep.QueryParamValues), name, values)
+	}
+	if bodySize > ep.ObservedMax {
+		ep.ObservedMax = bodySize
+	}
+	if len(body) > 0 && isJSONContentType(ct) {
+		if value, ok := decodeJSON(body); ok {
+			if ep.BodySchema == nil {
+				ep.BodySchema = newSchema()
+			}
+			ep.BodySchema.observe(value, 0)
+		}
+	}
+}
+
+func (c *Contract) endpointFor(path string) *Endpoint {
+	template := TemplatePath(path)
+	if ep, ok := c.Endpoints[template]; ok {
+		return ep
+	}
+	if c.Endpoints == nil {
+		c.Endpoints = map[string]*Endpoint{}
+	}
+	if len(c.Endpoints) >= maxEndpoints {
+		c.DroppedEndpoints++
+		return nil
+	}
+	ep := newEndpoint(template)
+	c.Endpoints[template] = ep
+	return ep
 }
 
 func parseContentType(value string) string {
@@ -37,3 +95,17 @@ func parseContentType(value string) string {
 	parts := strings.Split(value, ";")
 	return strings.TrimSpace(strings.ToLower(parts[0]))
 }
+
+func ensureProfiles(m *map[string]*ValueProfile) map[string]*ValueProfile {
+	if *m == nil {
+		*m = map[string]*ValueProfile{}
+	}
+	return *m
+}
+
+func observeValues(profiles map[string]*ValueProfile, name string, values []string) {
+	p := profileFor(profiles, name)
+	for _, value := range values {
+		p.observe(value)
+	}
+}
@@ -0,0 +1,78 @@
+package contract
+
+import (
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Learner accumulates observations from concurrent requests. Each request is
+// recorded into one of several independently locked shards so goroutines
+// rarely contend, and the shards are merged when a contract is requested.
+type Learner struct {
+	routeID string
+	policy  string
+	shards  []learnerShard
+	next    uint64
+}
+
+type learnerShard struct {
+	mu sync.Mutex
+	c  *Contract
+}
+
+func NewLearner(routeID, policy string) *Learner {
+	return NewLearnerWithShards(routeID, policy, runtime.GOMAXPROCS(0))
+}
+
+func NewLearnerWithShards(routeID, policy string, shards int) *Learner {
+	if shards <= 0 {
+		shards = 1
+	}
+	l := &Learner{routeID: routeID, policy: policy, shards: make([]learnerShard, shards)}
+	for i := range l.shards {
+		l.shards[i].c = New(routeID, policy)
+	}
+	return l
+}
+
+// Observe is safe for concurrent use.
+func (l *Learner) Observe(req *http.Request, body []byte, bodySize int64) {
+	if l == nil || req == nil {
+		return
+	}
+	idx := atomic.AddUint64(&l.next, 1) % uint64(len(l.shards))
+	shard := &l.shards[idx]
+	shard.mu.Lock()
+	shard.c.Observe(req, body, bodySize)
+	shard.mu.Unlock()
+}
+
+// Snapshot merges all shards into a new contract. Learning continues
+// unaffected.
+func (l *Learner) Snapshot() *Contract {
+	if l == nil {
+		return nil
+	}
+	out := New(l.routeID, l.policy)
+	for i := range l.shards {
+		shard := &l.shards[i]
+		shard.mu.Lock()
+		out.Merge(shard.c)
+		shard.mu.Unlock()
+	}
+	return out
+}
+
+// Finalize returns the merged contract with rarely seen items pruned and body
+// limits computed, along with the items that were pruned.
+func (l *Learner) Finalize(marginBytes int64, prune PruneOptions) (*Contract, []Rejected) {
+	c := l.Snapshot()
+	if c == nil {
+		return nil, nil
+	}
+	rejected := c.Prune(prune)
+	c.Finalize(marginBytes)
+	return c, rejected
+}
@@ -0,0 +1,75 @@
+package contract
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLearnerConcurrentMatchesSequential(t *testing.T) {
+	requests := make([]*http.Request, 0, 400)
+	for i := 0; i < 400; i++ {
+		method := "GET"
+		if i%3 == 0 {
+			method = "POST"
+		}
+		requests = append(requests, &http.Request{
+			Method: method,
+			URL: &url.URL{
+				Path:     "/api/items",
+				RawQuery: fmt.Sprintf("page=%d&p%d=1", i, i%17),
+			},
+			Header: http.Header{
+				"Content-Type":            []string{"application/json"},
+				fmt.Sprintf("X-H%d", i%5): []string{"1"},
+			},
+		})
+	}
+
+	bodies := make([][]byte, len(requests))
+	for i := range bodies {
+		bodies[i] = []byte(fmt.Sprintf(`{"id":%d,"name":"n%d","tags":["t%d"],"f%d":true}`, i, i, i%3, i%7))
+	}
+
+	sequential := New("route-0", "default")
+	for i, req := range requests {
+		sequential.Observe(req, bodies[i], int64(i))
+	}
+	sequential.Finalize(64)
+
+	learner := NewLearnerWithShards("route-0", "default", 4)
+	var wg sync.WaitGroup
+	const workers = 16
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(requests); i += workers {
+				learner.Observe(requests[i], bodies[i], int64(i))
+			}
+		}(w)
+	}
+
+	// Snapshots taken while learning is in progress must not race with Observe.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			_ = learner.Snapshot()
+		}
+	}()
+
+	wg.Wait()
+	<-done
+
+	concurrent, _ := learner.Finalize(64, PruneOptions{})
+	concurrent.GeneratedAt = time.Time{}
+	sequential.GeneratedAt = time.Time{}
+	if !reflect.DeepEqual(sequential, concurrent) {
+		t.Fatalf("concurrent contract differs from sequential:\nseq=%+v\ncon=%+v", sequential, concurrent)
+	}
+}
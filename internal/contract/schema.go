@@ -0,0 +1,271 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	jsonObject  = "object"
+	jsonArray   = "array"
+	jsonString  = "string"
+	jsonInteger = "integer"
+	jsonNumber  = "number"
+	jsonBoolean = "boolean"
+	jsonNull    = "null"
+)
+
+const (
+	maxSchemaDepth      = 16
+	maxSchemaProperties = 256
+	maxBodyViolations   = 10
+)
+
+// Schema is the JSON structure inferred for request bodies at one position.
+// Counts are kept rather than flags so that schemas from separate learn runs
+// merge into the same result as a single run.
+type Schema struct {
+	Types   map[string]int `json:"types"`
+	Objects int            `json:"objects,omitempty"`
+	// RequiredFields are declared rather than learned, e.g. by an OpenAPI
+	// import, and are required regardless of counts.
+	RequiredFields []string           `json:"required_fields,omitempty"`
+	Properties     map[string]*Schema `json:"properties,omitempty"`
+	Open           bool               `json:"open,omitempty"`
+	Items          *Schema            `json:"items,omitempty"`
+	MaxItems       int                `json:"max_items,omitempty"`
+	MaxLength      int                `json:"max_length,omitempty"`
+}
+
+func newSchema() *Schema {
+	return &Schema{Types: map[string]int{}}
+}
+
+// Required lists the properties present in every object seen at this node.
+func (s *Schema) Required() []string {
+	if s == nil {
+		return nil
+	}
+	out := append([]string(nil), s.RequiredFields...)
+	if s.Objects > 0 {
+		for name, prop := range s.Properties {
+			if prop.count() >= s.Objects && !containsString(s.RequiredFields, name) {
+				out = append(out, name)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (s *Schema) count() int {
+	n := 0
+	for _, c := range s.Types {
+		n += c
+	}
+	return n
+}
+
+func (s *Schema) observe(value any, depth int) {
+	kind := jsonKind(value)
+	s.Types[kind]++
+	if depth >= maxSchemaDepth {
+		s.Open = true
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		s.Objects++
+		for name, child := range v {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if len(s.Properties) >= maxSchemaProperties {
+					s.Open = true
+					continue
+				}
+				if s.Properties == nil {
+					s.Properties = map[string]*Schema{}
+				}
+				prop = newSchema()
+				s.Properties[name] = prop
+			}
+			prop.observe(child, depth+1)
+		}
+	case []any:
+		if len(v) > s.MaxItems {
+			s.MaxItems = len(v)
+		}
+		for _, item := range v {
+			if s.Items == nil {
+				s.Items = newSchema()
+			}
+			s.Items.observe(item, depth+1)
+		}
+	case string:
+		if n := utf8.RuneCountInString(v); n > s.MaxLength {
+			s.MaxLength = n
+		}
+	}
+}
+
+func (s *Schema) merge(other *Schema) {
+	if other == nil {
+		return
+	}
+	if s.Types == nil {
+		s.Types = map[string]int{}
+	}
+	for kind, n := range other.Types {
+		s.Types[kind] += n
+	}
+	s.Objects += other.Objects
+	for _, name := range other.RequiredFields {
+		if !containsString(s.RequiredFields, name) {
+			s.RequiredFields = append(s.RequiredFields, name)
+		}
+	}
+	s.Open = s.Open || other.Open
+	if other.MaxItems > s.MaxItems {
+		s.MaxItems = other.MaxItems
+	}
+	if other.MaxLength > s.MaxLength {
+		s.MaxLength = other.MaxLength
+	}
+	for name, prop := range other.Properties {
+		if s.Properties == nil {
+			s.Properties = map[string]*Schema{}
+		}
+		dst, ok := s.Properties[name]
+		if !ok {
+			dst = newSchema()
+			s.Properties[name] = dst
+		}
+		dst.merge(prop)
+	}
+	if other.Items != nil {
+		if s.Items == nil {
+			s.Items = newSchema()
+		}
+		s.Items.merge(other.Items)
+	}
+}
+
+func (s *Schema) clone() *Schema {
+	out := newSchema()
+	out.merge(s)
+	return out
+}
+
+func (s *Schema) allows(kind string) bool {
+	if s.Types[kind] > 0 {
+		return true
+	}
+	return kind == jsonInteger && s.Types[jsonNumber] > 0
+}
+
+// check walks value against the schema and appends violations with the JSON
+// pointer of the offending position in Field.
+func (s *Schema) check(value any, pointer string, enforcement Enforcement, violations []Violation) []Violation {
+	if len(violations) >= maxBodyViolations {
+		return violations
+	}
+
+	kind := jsonKind(value)
+	if !s.allows(kind) {
+		return append(violations, Violation{Type: "body_type_mismatch", Field: pointer})
+	}
+	if s.Open {
+		return violations
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := s.Properties[name]
+			if !ok {
+				violations = append(violations, Violation{Type: "body_field_unexpected", Field: pointer + "/" + escapePointer(name)})
+				continue
+			}
+			violations = prop.check(v[name], pointer+"/"+escapePointer(name), enforcement, violations)
+		}
+		for _, name := range s.Required() {
+			if _, ok := v[name]; !ok {
+				violations = append(violations, Violation{Type: "body_field_missing", Field: pointer + "/" + escapePointer(name)})
+			}
+		}
+	case []any:
+		if atLeast(enforcement, EnforcementStrict) && len(v) > lengthLimit(s.MaxItems) {
+			violations = append(violations, Violation{Type: "body_value_too_long", Field: pointer})
+		}
+		if s.Items == nil {
+			break
+		}
+		for i, item := range v {
+			violations = s.Items.check(item, pointer+"/"+strconv.Itoa(i), enforcement, violations)
+			if len(violations) >= maxBodyViolations {
+				break
+			}
+		}
+	case string:
+		if atLeast(enforcement, EnforcementStrict) && utf8.RuneCountInString(v) > lengthLimit(s.MaxLength) {
+			violations = append(violations, Violation{Type: "body_value_too_long", Field: pointer})
+		}
+	}
+
+	if len(violations) > maxBodyViolations {
+		violations = violations[:maxBodyViolations]
+	}
+	return violations
+}
+
+func lengthLimit(observed int) int {
+	return observed + max(minLengthSlack, observed/2)
+}
+
+func jsonKind(value any) string {
+	switch v := value.(type) {
+	case map[string]any:
+		return jsonObject
+	case []any:
+		return jsonArray
+	case string:
+		return jsonString
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			return jsonNumber
+		}
+		return jsonInteger
+	case bool:
+		return jsonBoolean
+	default:
+		return jsonNull
+	}
+}
+
+func escapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+func isJSONContentType(ct string) bool {
+	return ct == "application/json" || strings.HasSuffix(ct, "+json")
+}
+
+func decodeJSON(body []byte) (any, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, false
+	}
+	return value, true
+}
@@ -0,0 +1,73 @@
+package contract
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestSchemaInferenceAndEnforcement(t *testing.T) {
+	c := New("route-0", "default")
+	bodies := []string{
+		`{"user":{"name":"alice","age":30},"tags":["a","b"]}`,
+		`{"user":{"name":"bob"},"tags":[]}`,
+	}
+	for _, body := range bodies {
+		c.Observe(jsonRequest("/users"), []byte(body), int64(len(body)))
+	}
+	c.Finalize(1024)
+
+	schema := c.Endpoints["/users"].BodySchema
+	if schema == nil {
+		t.Fatal("expected body schema")
+	}
+	if got := schema.Required(); !reflect.DeepEqual(got, []string{"tags", "user"}) {
+		t.Fatalf("expected required [tags user], got %v", got)
+	}
+	if got := schema.Properties["user"].Required(); !reflect.DeepEqual(got, []string{"name"}) {
+		t.Fatalf("expected user.name required only, got %v", got)
+	}
+
+	cases := []struct {
+		body        string
+		enforcement Enforcement
+		want        []Violation
+	}{
+		{`{"user":{"name":"carol","age":41},"tags":["c"]}`, EnforcementModerate, nil},
+		{`{"user":{"name":5,"admin":true},"tags":[]}`, EnforcementModerate, []Violation{
+			{Type: "body_field_unexpected", Field: "/user/admin"},
+			{Type: "body_type_mismatch", Field: "/user/name"},
+		}},
+		{`{"tags":[1]}`, EnforcementModerate, []Violation{
+			{Type: "body_type_mismatch", Field: "/tags/0"},
+			{Type: "body_field_missing", Field: "/user"},
+		}},
+		{`{"user":{"name":"xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"},"tags":[]}`, EnforcementStrict, []Violation{
+			{Type: "body_value_too_long", Field: "/user/name"},
+		}},
+		{`[1,2]`, EnforcementModerate, []Violation{{Type: "body_type_mismatch", Field: ""}}},
+		{`{"user":5}`, EnforcementLenient, nil},
+	}
+
+	for _, tt := range cases {
+		got := Evaluate(c, jsonRequest("/users"), []byte(tt.body), int64(len(tt.body)), tt.enforcement)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s at %s: expected %v, got %v", tt.body, tt.enforcement, tt.want, got)
+		}
+	}
+}
+
+func TestEscapePointer(t *testing.T) {
+	if got := escapePointer("a/b~c"); got != "a~1b~0c" {
+		t.Fatalf("expected escaped pointer, got %q", got)
+	}
+}
+
+func jsonRequest(path string) *http.Request {
+	return &http.Request{
+		Method: "POST",
+		URL:    &url.URL{Path: path},
+		Header: http.Header{"Content-Type": []string{"application/json"}},
+	}
+}
@@ -0,0 +1,200 @@
+package contract
+
+import (
+	"net"
+	"sort"
+)
+
+// MaxTrackedClients bounds the distinct clients remembered per item. Client
+// counts saturate there, so minClients thresholds above it never pass.
+const MaxTrackedClients = 256
+
+const (
+	statMethod      = "method"
+	statContentType = "content_type"
+	statQueryParam  = "query_param"
+	statHeader      = "header"
+	statEndpoint    = "endpoint"
+)
+
+// ItemStats counts how often an allowlisted item was seen while learning and
+// from how many distinct client IPs.
+type ItemStats struct {
+	Count   int `json:"count"`
+	Clients int `json:"clients"`
+
+	clients map[string]struct{}
+}
+
+func (s *ItemStats) observe(client string) {
+	s.Count++
+	if client == "" || len(s.clients) >= MaxTrackedClients {
+		return
+	}
+	if s.clients == nil {
+		s.clients = map[string]struct{}{}
+	}
+	if _, ok := s.clients[client]; !ok {
+		s.clients[client] = struct{}{}
+		s.Clients = max(s.Clients, len(s.clients))
+	}
+}
+
+// merge sums counts. Client sets are unioned while both sides still have
+// them; a loaded contract only has the count, which is then a lower bound.
+func (s *ItemStats) merge(other *ItemStats) {
+	s.Count += other.Count
+	for client := range other.clients {
+		if len(s.clients) >= MaxTrackedClients {
+			break
+		}
+		if s.clients == nil {
+			s.clients = map[string]struct{}{}
+		}
+		s.clients[client] = struct{}{}
+	}
+	s.Clients = max(s.Clients, other.Clients, len(s.clients))
+}
+
+func (s *ItemStats) clone() *ItemStats {
+	out := &ItemStats{Count: s.Count, Clients: s.Clients}
+	if s.clients != nil {
+		out.clients = make(map[string]struct{}, len(s.clients))
+		for client := range s.clients {
+			out.clients[client] = struct{}{}
+		}
+	}
+	return out
+}
+
+func statKey(kind, name string) string {
+	return kind + ":" + name
+}
+
+func observeStat(stats *map[string]*ItemStats, kind, name, client string) {
+	if *stats == nil {
+		*stats = map[string]*ItemStats{}
+	}
+	key := statKey(kind, name)
+	s, ok := (*stats)[key]
+	if !ok {
+		s = &ItemStats{}
+		(*stats)[key] = s
+	}
+	s.observe(client)
+}
+
+func mergeStats(dst *map[string]*ItemStats, src map[string]*ItemStats) {
+	if len(src) == 0 {
+		return
+	}
+	if *dst == nil {
+		*dst = map[string]*ItemStats{}
+	}
+	for key, s := range src {
+		if existing, ok := (*dst)[key]; ok {
+			existing.merge(s)
+		} else {
+			(*dst)[key] = s.clone()
+		}
+	}
+}
+
+func requestClient(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+type PruneOptions struct {
+	MinFrequency int
+	MinClients   int
+}
+
+// Rejected is an item dropped from a learned contract because too few
+// requests or clients sent it.
+type Rejected struct {
+	Kind     string `json:"kind"`
+	Endpoint string `json:"endpoint,omitempty"`
+	Name     string `json:"name"`
+	Count    int    `json:"count"`
+	Clients  int    `json:"clients"`
+	Reason   string `json:"reason"`
+}
+
+// Prune drops methods, content types, query parameters, headers and
+// endpoints seen fewer than MinFrequency times or from fewer than MinClients
+// client IPs, so that a single scanner during the learn window does not widen
+// the contract. Items without stats, such as those of imported contracts, are
+// kept.
+func (c *Contract) Prune(opts PruneOptions) []Rejected {
+	if c == nil || (opts.MinFrequency <= 0 && opts.MinClients <= 0) {
+		return nil
+	}
+
+	var rejected []Rejected
+	reject := func(stats map[string]*ItemStats, endpoint, kind, name string) bool {
+		s, ok := stats[statKey(kind, name)]
+		if !ok {
+			return false
+		}
+		reason := ""
+		switch {
+		case opts.MinFrequency > 0 && s.Count < opts.MinFrequency:
+			reason = "min_frequency"
+		case opts.MinClients > 0 && s.Clients < opts.MinClients:
+			reason = "min_clients"
+		default:
+			return false
+		}
+		rejected = append(rejected, Rejected{Kind: kind, Endpoint: endpoint, Name: name, Count: s.Count, Clients: s.Clients, Reason: reason})
+		delete(stats, statKey(kind, name))
+		return true
+	}
+	pruneSet := func(stats map[string]*ItemStats, endpoint, kind string, set map[string]bool, profiles map[string]*ValueProfile) {
+		for _, name := range sortedNames(set) {
+			if reject(stats, endpoint, kind, name) {
+				delete(set, name)
+				delete(profiles, name)
+			}
+		}
+	}
+
+	pruneSet(c.Stats, "", statMethod, c.Methods, nil)
+	pruneSet(c.Stats, "", statContentType, c.ContentTypes, nil)
+	pruneSet(c.Stats, "", statQueryParam, c.QueryParams, c.QueryParamValues)
+	pruneSet(c.Stats, "", statHeader, c.HeaderNames, c.HeaderValues)
+
+	for _, template := range sortedNames(c.Endpoints) {
+		if reject(c.Stats, "", statEndpoint, template) {
+			delete(c.Endpoints, template)
+			continue
+		}
+		ep := c.Endpoints[template]
+		pruneSet(ep.Stats, template, statMethod, ep.Methods, nil)
+		pruneSet(ep.Stats, template, statContentType, ep.ContentTypes, nil)
+		pruneSet(ep.Stats, template, statQueryParam, ep.QueryParams, ep.QueryParamValues)
+	}
+	return rejected
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LearnSummary describes one saved learned contract and what finalizing it
+// rejected.
+type LearnSummary struct {
+	RouteID  string     `json:"route_id"`
+	Policy   string     `json:"policy"`
+	Path     string     `json:"path"`
+	Samples  int        `json:"samples"`
+	Rejected []Rejected `json:"rejected"`
+}
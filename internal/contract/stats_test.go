@@ -0,0 +1,84 @@
+package contract
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPruneDropsRareItems(t *testing.T) {
+	c := New("route-0", "api")
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest("GET", "/search?q=shoes", nil)
+		req.RemoteAddr = fmt.Sprintf("198.51.100.%d:4000", i%5)
+		c.Observe(req, nil, 0)
+	}
+	// One client probing repeatedly passes the frequency threshold but not
+	// the client threshold.
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/search?q=x&debug=1", nil)
+		req.RemoteAddr = "203.0.113.9:5000"
+		req.Header.Set("X-Scanner", "1")
+		c.Observe(req, nil, 0)
+	}
+	scan := httptest.NewRequest("DELETE", "/wp-admin", nil)
+	scan.RemoteAddr = "203.0.113.9:5000"
+	c.Observe(scan, nil, 0)
+
+	rejected := c.Prune(PruneOptions{MinFrequency: 2, MinClients: 2})
+
+	got := map[string]string{}
+	for _, r := range rejected {
+		got[r.Kind+" "+r.Endpoint+" "+r.Name] = r.Reason
+	}
+	expected := map[string]string{
+		"method  DELETE":            "min_frequency",
+		"query_param  debug":        "min_clients",
+		"header  X-Scanner":         "min_clients",
+		"endpoint  /wp-admin":       "min_frequency",
+		"query_param /search debug": "min_clients",
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d rejections, got %+v", len(expected), rejected)
+	}
+	for key, reason := range expected {
+		if got[key] != reason {
+			t.Fatalf("expected %q rejected for %s, got %+v", key, reason, rejected)
+		}
+	}
+
+	if c.QueryParams["debug"] || c.QueryParamValues["debug"] != nil || c.HeaderNames["X-Scanner"] || c.Methods["DELETE"] {
+		t.Fatalf("rejected items left in contract: %+v", c)
+	}
+	if c.Endpoints["/wp-admin"] != nil || c.Endpoints["/search"].QueryParams["debug"] {
+		t.Fatalf("rejected endpoint items left in contract")
+	}
+	if !c.QueryParams["q"] || !c.Endpoints["/search"].QueryParams["q"] {
+		t.Fatalf("expected common parameter to be kept")
+	}
+}
+
+func TestItemStatsMerge(t *testing.T) {
+	a := New("route-0", "api")
+	b := New("route-0", "api")
+	for i, c := range []*Contract{a, b} {
+		req := httptest.NewRequest("GET", "/?q=1", nil)
+		req.RemoteAddr = fmt.Sprintf("198.51.100.%d:4000", i)
+		c.Observe(req, nil, 0)
+	}
+	a.Merge(b)
+
+	s := a.Stats[statKey(statQueryParam, "q")]
+	if s == nil || s.Count != 2 || s.Clients != 2 {
+		t.Fatalf("expected merged count 2 from 2 clients, got %+v", s)
+	}
+
+	loaded, err := b.Clone()
+	if err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	a.Merge(loaded)
+	if s.Count != 3 || s.Clients != 2 {
+		t.Fatalf("expected count 3 with client lower bound 2, got %+v", s)
+	}
+}
@@ -25,6 +25,21 @@ func Save(path string, contract *Contract) error {
 	return os.WriteFile(path, data, 0o600)
 }
 
+// SaveSummary writes a learn summary as JSON.
+func SaveSummary(path string, summary LearnSummary) error {
+	if summary.Rejected == nil {
+		summary.Rejected = []Rejected{}
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
 func Load(path string) (*Contract, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
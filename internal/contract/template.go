@@ -0,0 +1,150 @@
+package contract
+
+import "strings"
+
+const (
+	placeholderID   = "{id}"
+	placeholderUUID = "{uuid}"
+	placeholderHex  = "{hex}"
+	placeholderSlug = "{slug}"
+)
+
+// TemplatePath replaces path segments that look like identifiers with
+// placeholders so that /users/42 and /users/7 learn as /users/{id}.
+func TemplatePath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if placeholder := classifySegment(seg); placeholder != "" {
+			segments[i] = placeholder
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// MatchTemplate reports whether path fits template. Placeholders match
+// segments of their class; unknown placeholders such as {name} match any
+// non-empty segment.
+func MatchTemplate(template, path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	tsegs := strings.Split(template, "/")
+	psegs := strings.Split(path, "/")
+	if len(tsegs) != len(psegs) {
+		return false
+	}
+	for i, tseg := range tsegs {
+		if !matchSegment(tseg, psegs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func classifySegment(seg string) string {
+	switch {
+	case seg == "":
+		return ""
+	case isNumeric(seg):
+		return placeholderID
+	case isUUID(seg):
+		return placeholderUUID
+	case len(seg) >= 8 && isHex(seg) && containsDigit(seg):
+		return placeholderHex
+	case isSlug(seg):
+		return placeholderSlug
+	default:
+		return ""
+	}
+}
+
+func matchSegment(tseg, seg string) bool {
+	if !isPlaceholder(tseg) {
+		return tseg == seg
+	}
+	if seg == "" {
+		return false
+	}
+	switch tseg {
+	case placeholderID:
+		return isNumeric(seg)
+	case placeholderUUID:
+		return isUUID(seg)
+	case placeholderHex:
+		return isHex(seg)
+	case placeholderSlug:
+		return isSlugLike(seg)
+	default:
+		return true
+	}
+}
+
+func isPlaceholder(seg string) bool {
+	return len(seg) > 2 && seg[0] == '{' && seg[len(seg)-1] == '}'
+}
+
+func isNumeric(seg string) bool {
+	for i := 0; i < len(seg); i++ {
+		if seg[i] < '0' || seg[i] > '9' {
+			return false
+		}
+	}
+	return seg != ""
+}
+
+func isHex(seg string) bool {
+	for i := 0; i < len(seg); i++ {
+		c := seg[i]
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') && !(c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return seg != ""
+}
+
+func isUUID(seg string) bool {
+	if len(seg) != 36 {
+		return false
+	}
+	for i := 0; i < len(seg); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if seg[i] != '-' {
+				return false
+			}
+		default:
+			if !isHex(seg[i : i+1]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func containsDigit(seg string) bool {
+	return strings.ContainsAny(seg, "0123456789")
+}
+
+// isSlug matches generated, human-readable identifiers such as
+// my-first-post or order-2024-17. Single-hyphen words such as user-settings
+// are usually fixed routes and stay literal.
+func isSlug(seg string) bool {
+	if !isSlugLike(seg) || seg[0] == '-' || seg[len(seg)-1] == '-' {
+		return false
+	}
+	hyphens := strings.Count(seg, "-")
+	return hyphens >= 2 || (hyphens == 1 && containsDigit(seg))
+}
+
+func isSlugLike(seg string) bool {
+	for i := 0; i < len(seg); i++ {
+		c := seg[i]
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return seg != ""
+}
@@ -0,0 +1,43 @@
+package contract
+
+import "testing"
+
+func TestTemplatePath(t *testing.T) {
+	cases := map[string]string{
+		"/users/42": "/users/{id}",
+		"/orders/3f2504e0-4f89-11d3-9a0c-0305e82c3301/items": "/orders/{uuid}/items",
+		"/blobs/deadbeef01":    "/blobs/{hex}",
+		"/posts/my-first-post": "/posts/{slug}",
+		"/posts/launch-2024":   "/posts/{slug}",
+		"/user-settings":       "/user-settings",
+		"/api/v1/health":       "/api/v1/health",
+		"":                     "/",
+	}
+
+	for input, expected := range cases {
+		if got := TemplatePath(input); got != expected {
+			t.Fatalf("TemplatePath(%q) expected %q, got %q", input, expected, got)
+		}
+	}
+}
+
+func TestMatchTemplate(t *testing.T) {
+	cases := []struct {
+		template string
+		path     string
+		want     bool
+	}{
+		{"/users/{id}", "/users/7", true},
+		{"/users/{id}", "/users/abc", false},
+		{"/users/{id}", "/users/7/posts", false},
+		{"/orders/{uuid}/items", "/orders/3F2504E0-4F89-11D3-9A0C-0305E82C3301/items", true},
+		{"/files/{name}", "/files/report.pdf", true},
+		{"/files/{name}", "/files/", false},
+	}
+
+	for _, tt := range cases {
+		if got := MatchTemplate(tt.template, tt.path); got != tt.want {
+			t.Fatalf("MatchTemplate(%q, %q) expected %v, got %v", tt.template, tt.path, tt.want, got)
+		}
+	}
+}
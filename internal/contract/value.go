@@ -0,0 +1,373 @@
+package contract
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type ValueType string
+
+const (
+	ValueInteger ValueType = "integer"
+	ValueBoolean ValueType = "boolean"
+	ValueUUID    ValueType = "uuid"
+	ValueEmail   ValueType = "email"
+	ValueEnum    ValueType = "enum"
+	ValueString  ValueType = "string"
+)
+
+const (
+	// maxTrackedValues bounds the distinct values remembered per name while
+	// learning; past this the value is treated as free-form.
+	maxTrackedValues = 16
+	// maxEnumValues is the largest distinct set inferred as an enum, and each
+	// value must have been seen at least minEnumRepeats times on average.
+	maxEnumValues  = 8
+	minEnumRepeats = 3
+	minLengthSlack = 8
+)
+
+const (
+	charAlpha    = "alpha"
+	charDigit    = "digit"
+	charSpace    = "space"
+	charNonASCII = "non_ascii"
+	charSymbol   = "symbols"
+)
+
+const (
+	shapeInteger = 1 << iota
+	shapeBoolean
+	shapeUUID
+	shapeEmail
+	shapeAll = shapeInteger | shapeBoolean | shapeUUID | shapeEmail
+)
+
+// ValueProfile describes the values seen for one query parameter or header.
+// The exported fields are the enforced profile; the unexported ones hold
+// learning state and are rebuilt from the profile when a saved contract is
+// merged.
+type ValueProfile struct {
+	Type              ValueType `json:"type"`
+	Count             int       `json:"count"`
+	Min               int64     `json:"min,omitempty"`
+	Max               int64     `json:"max,omitempty"`
+	ObservedMaxLength int       `json:"observed_max_length"`
+	MaxLength         int       `json:"max_length"`
+	CharClasses       []string  `json:"char_classes,omitempty"`
+	Symbols           string    `json:"symbols,omitempty"`
+	Enum              []string  `json:"enum,omitempty"`
+
+	learning  bool
+	nonEmpty  int
+	shapes    int
+	hasRange  bool
+	classes   map[string]bool
+	symbols   map[rune]bool
+	values    map[string]int
+	overflow  bool
+	sensitive bool
+}
+
+func newValueProfile(name string) *ValueProfile {
+	return &ValueProfile{
+		learning:  true,
+		shapes:    shapeAll,
+		classes:   map[string]bool{},
+		symbols:   map[rune]bool{},
+		values:    map[string]int{},
+		sensitive: isSensitiveName(name),
+	}
+}
+
+func (p *ValueProfile) observe(value string) {
+	p.Count++
+	if n := utf8.RuneCountInString(value); n > p.ObservedMaxLength {
+		p.ObservedMaxLength = n
+	}
+	if value == "" {
+		return
+	}
+	p.nonEmpty++
+
+	if p.shapes&shapeInteger != 0 {
+		if n, ok := parseInteger(value); ok {
+			if !p.hasRange || n < p.Min {
+				p.Min = n
+			}
+			if !p.hasRange || n > p.Max {
+				p.Max = n
+			}
+			p.hasRange = true
+		} else {
+			p.shapes &^= shapeInteger
+		}
+	}
+	if p.shapes&shapeBoolean != 0 && !isBoolean(value) {
+		p.shapes &^= shapeBoolean
+	}
+	if p.shapes&shapeUUID != 0 && !isUUID(value) {
+		p.shapes &^= shapeUUID
+	}
+	if p.shapes&shapeEmail != 0 && !isEmail(value) {
+		p.shapes &^= shapeEmail
+	}
+
+	for _, r := range value {
+		class, symbol := classifyRune(r)
+		p.classes[class] = true
+		if symbol {
+			p.symbols[r] = true
+		}
+	}
+
+	if !p.overflow && !p.sensitive {
+		if _, ok := p.values[value]; ok || len(p.values) < maxTrackedValues {
+			p.values[value]++
+		} else {
+			p.overflow = true
+			p.values = nil
+		}
+	}
+}
+
+// finalize infers the value type from the learning state.
+func (p *ValueProfile) finalize() {
+	p.rehydrate()
+
+	p.MaxLength = lengthLimit(p.ObservedMaxLength)
+	p.Enum = nil
+	switch {
+	case p.nonEmpty == 0:
+		p.Type = ValueString
+	case p.shapes&shapeInteger != 0:
+		p.Type = ValueInteger
+	case p.shapes&shapeBoolean != 0:
+		p.Type = ValueBoolean
+	case p.shapes&shapeUUID != 0:
+		p.Type = ValueUUID
+	case p.shapes&shapeEmail != 0:
+		p.Type = ValueEmail
+	case !p.overflow && !p.sensitive && len(p.values) <= maxEnumValues && p.nonEmpty >= minEnumRepeats*len(p.values):
+		p.Type = ValueEnum
+		for value := range p.values {
+			p.Enum = append(p.Enum, value)
+		}
+		sort.Strings(p.Enum)
+	default:
+		p.Type = ValueString
+	}
+	if !p.hasRange {
+		p.Min, p.Max = 0, 0
+	}
+
+	p.CharClasses = p.CharClasses[:0]
+	for class := range p.classes {
+		p.CharClasses = append(p.CharClasses, class)
+	}
+	sort.Strings(p.CharClasses)
+	symbols := make([]rune, 0, len(p.symbols))
+	for r := range p.symbols {
+		symbols = append(symbols, r)
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i] < symbols[j] })
+	p.Symbols = string(symbols)
+}
+
+// rehydrate rebuilds learning state from the exported profile of a contract
+// loaded from disk so it can be merged or finalized again.
+func (p *ValueProfile) rehydrate() {
+	if p.learning {
+		return
+	}
+	p.learning = true
+	p.classes = map[string]bool{}
+	for _, class := range p.CharClasses {
+		p.classes[class] = true
+	}
+	p.symbols = map[rune]bool{}
+	for _, r := range p.Symbols {
+		p.symbols[r] = true
+	}
+	p.nonEmpty = p.Count
+	p.hasRange = p.Type == ValueInteger
+	switch p.Type {
+	case ValueInteger:
+		p.shapes = shapeInteger
+	case ValueBoolean:
+		p.shapes = shapeBoolean
+	case ValueUUID:
+		p.shapes = shapeUUID
+	case ValueEmail:
+		p.shapes = shapeEmail
+	}
+	if p.Type == ValueEnum {
+		p.values = map[string]int{}
+		for _, value := range p.Enum {
+			p.values[value] = minEnumRepeats
+		}
+	} else {
+		p.overflow = true
+	}
+}
+
+func (p *ValueProfile) merge(other *ValueProfile) {
+	p.rehydrate()
+	other.rehydrate()
+
+	p.Count += other.Count
+	p.nonEmpty += other.nonEmpty
+	if other.ObservedMaxLength > p.ObservedMaxLength {
+		p.ObservedMaxLength = other.ObservedMaxLength
+	}
+	if other.hasRange {
+		if !p.hasRange || other.Min < p.Min {
+			p.Min = other.Min
+		}
+		if !p.hasRange || other.Max > p.Max {
+			p.Max = other.Max
+		}
+		p.hasRange = true
+	}
+	if other.nonEmpty > 0 {
+		p.shapes &= other.shapes
+	}
+	for class := range other.classes {
+		p.classes[class] = true
+	}
+	for r := range other.symbols {
+		p.symbols[r] = true
+	}
+	p.sensitive = p.sensitive || other.sensitive
+	switch {
+	case p.sensitive:
+	case p.overflow || other.overflow:
+		p.overflow = true
+		p.values = nil
+	default:
+		for value, n := range other.values {
+			p.values[value] += n
+		}
+		if len(p.values) > maxTrackedValues {
+			p.overflow = true
+			p.values = nil
+		}
+	}
+}
+
+func (p *ValueProfile) clone() *ValueProfile {
+	out := newValueProfile("")
+	out.merge(p)
+	return out
+}
+
+// check returns the violation suffix for value, or "" when it conforms.
+// Type and length problems are reported from moderate; charset and range
+// problems only at strict.
+func (p *ValueProfile) check(value string, enforcement Enforcement) string {
+	if p == nil || p.Type == "" {
+		return ""
+	}
+	if p.MaxLength > 0 && utf8.RuneCountInString(value) > p.MaxLength {
+		return "too_long"
+	}
+	if value == "" {
+		return ""
+	}
+
+	switch p.Type {
+	case ValueInteger:
+		n, ok := parseInteger(value)
+		if !ok {
+			return "type_mismatch"
+		}
+		if atLeast(enforcement, EnforcementStrict) && (n < p.Min || n > p.Max) {
+			return "out_of_range"
+		}
+	case ValueBoolean:
+		if !isBoolean(value) {
+			return "type_mismatch"
+		}
+	case ValueUUID:
+		if !isUUID(value) {
+			return "type_mismatch"
+		}
+	case ValueEmail:
+		if !isEmail(value) {
+			return "type_mismatch"
+		}
+	case ValueEnum:
+		if !containsString(p.Enum, value) {
+			return "type_mismatch"
+		}
+	}
+
+	if atLeast(enforcement, EnforcementStrict) && len(p.CharClasses) > 0 {
+		for _, r := range value {
+			class, symbol := classifyRune(r)
+			if !containsString(p.CharClasses, class) || (symbol && !strings.ContainsRune(p.Symbols, r)) {
+				return "charset_mismatch"
+			}
+		}
+	}
+	return ""
+}
+
+func classifyRune(r rune) (string, bool) {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		return charAlpha, false
+	case r >= '0' && r <= '9':
+		return charDigit, false
+	case r == ' ' || r == '\t':
+		return charSpace, false
+	case r >= utf8.RuneSelf:
+		return charNonASCII, false
+	default:
+		return charSymbol, true
+	}
+}
+
+func parseInteger(value string) (int64, bool) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	return n, err == nil
+}
+
+func isBoolean(value string) bool {
+	switch strings.ToLower(value) {
+	case "true", "false", "yes", "no", "on", "off":
+		return true
+	default:
+		return false
+	}
+}
+
+func isEmail(value string) bool {
+	local, domain, ok := strings.Cut(value, "@")
+	if !ok || local == "" || len(value) > 254 || strings.ContainsAny(value, " \t<>\"'(),;:\\[]") {
+		return false
+	}
+	dot := strings.LastIndexByte(domain, '.')
+	return dot > 0 && dot < len(domain)-1 && !strings.Contains(domain, "@")
+}
+
+func isSensitiveName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, marker := range []string{"password", "passwd", "token", "secret", "key", "auth", "session", "cookie", "signature"} {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
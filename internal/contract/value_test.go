@@ -0,0 +1,77 @@
+package contract
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestValueProfileInference(t *testing.T) {
+	cases := []struct {
+		name   string
+		values []string
+		want   ValueType
+	}{
+		{"page", []string{"1", "20", "3"}, ValueInteger},
+		{"debug", []string{"true", "false", "TRUE"}, ValueBoolean},
+		{"id", []string{"3f2504e0-4f89-11d3-9a0c-0305e82c3301"}, ValueUUID},
+		{"email", []string{"a@example.com", "b.c@example.org"}, ValueEmail},
+		{"sort", []string{"asc", "desc", "asc", "desc", "asc", "desc"}, ValueEnum},
+		{"q", []string{"shoes", "red hat", "blue-jeans"}, ValueString},
+		{"api_key", []string{"abc", "abc", "abc", "abc"}, ValueString},
+	}
+
+	for _, tt := range cases {
+		p := newValueProfile(tt.name)
+		for _, v := range tt.values {
+			p.observe(v)
+		}
+		p.finalize()
+		if p.Type != tt.want {
+			t.Fatalf("%s: expected type %s, got %s", tt.name, tt.want, p.Type)
+		}
+	}
+
+	p := newValueProfile("page")
+	for _, v := range []string{"2", "9", "5"} {
+		p.observe(v)
+	}
+	p.finalize()
+	if p.Min != 2 || p.Max != 9 {
+		t.Fatalf("expected range 2..9, got %d..%d", p.Min, p.Max)
+	}
+}
+
+func TestEvaluateTypedQueryParams(t *testing.T) {
+	c := New("route-0", "default")
+	for _, q := range []string{"id=1&q=shoes", "id=25&q=red%20hat", "id=7&q=boots"} {
+		c.Observe(&http.Request{Method: "GET", URL: &url.URL{Path: "/search", RawQuery: q}, Header: http.Header{}}, nil, 0)
+	}
+	c.Finalize(0)
+
+	cases := []struct {
+		query       string
+		enforcement Enforcement
+		want        string
+	}{
+		{"id=3&q=hats", EnforcementModerate, ""},
+		{"id=1%20or%201%3D1&q=hats", EnforcementModerate, "query_param_type_mismatch"},
+		{"id=3&q=" + url.QueryEscape("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"), EnforcementModerate, "query_param_too_long"},
+		{"id=3&q=x'--", EnforcementModerate, ""},
+		{"id=3&q=x'--", EnforcementStrict, "query_param_charset_mismatch"},
+		{"id=3000&q=hats", EnforcementStrict, "query_param_out_of_range"},
+		{"id=1%20or%201%3D1", EnforcementLenient, ""},
+	}
+
+	for _, tt := range cases {
+		req := &http.Request{Method: "GET", URL: &url.URL{Path: "/search", RawQuery: tt.query}, Header: http.Header{}}
+		violations := Evaluate(c, req, nil, 0, tt.enforcement)
+		got := ""
+		if len(violations) > 0 {
+			got = violations[0].Type
+		}
+		if len(violations) > 1 || got != tt.want {
+			t.Fatalf("%s at %s: expected %q, got %v", tt.query, tt.enforcement, tt.want, violations)
+		}
+	}
+}
@@ -7,46 +7,90 @@ type Violation struct {
 	Field string `json:"field"`
 }
 
-func Evaluate(c *Contract, req *http.Request, bodySize int64, enforcement Enforcement) []Violation {
+func Evaluate(c *Contract, req *http.Request, body []byte, bodySize int64, enforcement Enforcement) []Violation {
 	if c == nil || req == nil {
 		return nil
 	}
 
 	var violations []Violation
 
-	if len(c.Methods) > 0 && !c.Methods[req.Method] {
+	methods, contentTypes, queryParams, maxBody := c.Methods, c.ContentTypes, c.QueryParams, c.MaxBodyBytes
+	queryValues := c.QueryParamValues
+	var bodySchema *Schema
+	profiled := true
+	if len(c.Endpoints) > 0 {
+		ep := c.MatchEndpoint(req.URL.Path)
+		if ep == nil {
+			violations = append(violations, Violation{Type: "path_unexpected", Field: req.URL.Path})
+			profiled = false
+		} else {
+			methods, contentTypes, queryParams = ep.Methods, ep.ContentTypes, ep.QueryParams
+			queryValues = ep.QueryParamValues
+			bodySchema = ep.BodySchema
+			if ep.MaxBodyBytes > 0 {
+				maxBody = ep.MaxBodyBytes
+			}
+		}
+	}
+
+	if profiled && len(methods) > 0 && !methods[req.Method] {
 		violations = append(violations, Violation{Type: "method_unexpected", Field: req.Method})
 	}
 
-	if len(c.ContentTypes) > 0 {
+	if profiled && len(contentTypes) > 0 {
 		if ct := parseContentType(req.Header.Get("Content-Type")); ct != "" {
-			if !c.ContentTypes[ct] {
+			if !contentTypes[ct] {
 				violations = append(violations, Violation{Type: "content_type_unexpected", Field: ct})
 			}
 		}
 	}
 
-	if atLeast(enforcement, EnforcementModerate) {
-		for name := range req.URL.Query() {
-			if !c.QueryParams[name] {
+	if profiled && atLeast(enforcement, EnforcementModerate) {
+		for name, values := range req.URL.Query() {
+			if !queryParams[name] {
 				violations = append(violations, Violation{Type: "query_param_unexpected", Field: name})
+				continue
 			}
+			violations = appendValueViolations(violations, "query_param_", name, queryValues[name], values, enforcement)
 		}
 	}
 
 	if atLeast(enforcement, EnforcementStrict) {
-		for name := range req.Header {
+		for name, values := range req.Header {
 			canon := http.CanonicalHeaderKey(name)
 			if !c.HeaderNames[canon] {
 				violations = append(violations, Violation{Type: "header_unexpected", Field: canon})
+				continue
 			}
+			violations = appendValueViolations(violations, "header_", canon, c.HeaderValues[canon], values, enforcement)
 		}
 	}
 
-	if c.MaxBodyBytes > 0 && bodySize > c.MaxBodyBytes {
+	if maxBody > 0 && bodySize > maxBody {
 		violations = append(violations, Violation{Type: "body_size_exceeded", Field: "body"})
 	}
 
+	if bodySchema != nil && len(body) > 0 && atLeast(enforcement, EnforcementModerate) {
+		if isJSONContentType(parseContentType(req.Header.Get("Content-Type"))) {
+			if value, ok := decodeJSON(body); ok {
+				violations = bodySchema.check(value, "", enforcement, violations)
+			} else {
+				violations = append(violations, Violation{Type: "body_type_mismatch", Field: ""})
+			}
+		}
+	}
+
+	return violations
+}
+
+// appendValueViolations reports at most one value problem per name so a
+// repeated parameter does not flood the decision log.
+func appendValueViolations(violations []Violation, prefix, name string, profile *ValueProfile, values []string, enforcement Enforcement) []Violation {
+	for _, value := range values {
+		if problem := profile.check(value, enforcement); problem != "" {
+			return append(violations, Violation{Type: prefix + problem, Field: name})
+		}
+	}
 	return violations
 }
 
@@ -28,18 +28,52 @@ func TestEvaluateContractStrictness(t *testing.T) {
 		},
 	}
 
-	violations := Evaluate(c, req, 20, EnforcementLenient)
+	violations := Evaluate(c, req, nil, 20, EnforcementLenient)
 	if len(violations) != 3 {
 		t.Fatalf("lenient expected 3 violations, got %d", len(violations))
 	}
 
-	violations = Evaluate(c, req, 20, EnforcementModerate)
+	violations = Evaluate(c, req, nil, 20, EnforcementModerate)
 	if len(violations) != 4 {
 		t.Fatalf("moderate expected 4 violations, got %d", len(violations))
 	}
 
-	violations = Evaluate(c, req, 20, EnforcementStrict)
+	violations = Evaluate(c, req, nil, 20, EnforcementStrict)
 	if len(violations) != 6 {
 		t.Fatalf("strict expected 6 violations, got %d", len(violations))
 	}
 }
+
+func TestEvaluatePerEndpoint(t *testing.T) {
+	c := New("route-0", "default")
+	c.Observe(&http.Request{Method: "GET", URL: &url.URL{Path: "/users/1", RawQuery: "fields=name"}, Header: http.Header{}}, nil, 0)
+	c.Observe(&http.Request{Method: "POST", URL: &url.URL{Path: "/orders"}, Header: http.Header{}}, nil, 10)
+	c.Finalize(4)
+
+	if _, ok := c.Endpoints["/users/{id}"]; !ok {
+		t.Fatalf("expected /users/{id} endpoint, got %v", c.Endpoints)
+	}
+
+	ok := &http.Request{Method: "GET", URL: &url.URL{Path: "/users/99", RawQuery: "fields=email"}, Header: http.Header{}}
+	if violations := Evaluate(c, ok, nil, 0, EnforcementModerate); len(violations) != 0 {
+		t.Fatalf("expected no violations, got %v", violations)
+	}
+
+	wrongMethod := &http.Request{Method: "POST", URL: &url.URL{Path: "/users/99"}, Header: http.Header{}}
+	violations := Evaluate(c, wrongMethod, nil, 0, EnforcementLenient)
+	if len(violations) != 1 || violations[0].Type != "method_unexpected" {
+		t.Fatalf("expected method_unexpected for endpoint, got %v", violations)
+	}
+
+	unknown := &http.Request{Method: "GET", URL: &url.URL{Path: "/admin"}, Header: http.Header{}}
+	violations = Evaluate(c, unknown, nil, 0, EnforcementLenient)
+	if len(violations) != 1 || violations[0].Type != "path_unexpected" || violations[0].Field != "/admin" {
+		t.Fatalf("expected path_unexpected, got %v", violations)
+	}
+
+	tooBig := &http.Request{Method: "GET", URL: &url.URL{Path: "/users/5"}, Header: http.Header{}}
+	violations = Evaluate(c, tooBig, nil, 5, EnforcementLenient)
+	if len(violations) != 1 || violations[0].Type != "body_size_exceeded" {
+		t.Fatalf("expected endpoint body limit, got %v", violations)
+	}
+}
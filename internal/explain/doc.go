@@ -0,0 +1,2 @@
+// Package explain provides functionality for Klyr.
+package explain
@@ -0,0 +1,113 @@
+package explain
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klyr/klyr/internal/config"
+	"github.com/klyr/klyr/internal/gateway"
+)
+
+func TestParseCurl(t *testing.T) {
+	req, err := ParseCurl(`curl -s -XPOST 'https://shop.test/search?q=1 or 1=1' \
+  -H "User-Agent: sqlmap/1.7" -H 'X-Note: it'\''s' \
+  --data-raw 'a=1' -d "b=\"2\"" -b session=abc`)
+	if err != nil {
+		t.Fatalf("ParseCurl error: %v", err)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if req.Method != http.MethodPost || req.Host != "shop.test" || req.URL.Path != "/search" || req.URL.RawQuery != "q=1 or 1=1" {
+		t.Fatalf("unexpected request line %s %s %s?%s", req.Method, req.Host, req.URL.Path, req.URL.RawQuery)
+	}
+	if string(body) != `a=1&b="2"` {
+		t.Fatalf("unexpected body %q", body)
+	}
+	if req.Header.Get("User-Agent") != "sqlmap/1.7" || req.Header.Get("X-Note") != "it's" || req.Header.Get("Cookie") != "session=abc" {
+		t.Fatalf("unexpected headers %v", req.Header)
+	}
+	if req.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		t.Fatalf("expected form content type, got %q", req.Header.Get("Content-Type"))
+	}
+
+	req, err = ParseCurl(`curl -G shop.test/items --data-urlencode "q=a b"`)
+	if err != nil {
+		t.Fatalf("ParseCurl error: %v", err)
+	}
+	if req.Method != http.MethodGet || req.URL.RawQuery != "q=a+b" || req.Body != nil {
+		t.Fatalf("expected -G to move data to the query, got %s %q", req.Method, req.URL.RawQuery)
+	}
+
+	for _, bad := range []string{`curl -H`, `curl -s`, `curl 'unterminated`, `curl a.test b.test`} {
+		if _, err := ParseCurl(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestParseRaw(t *testing.T) {
+	raw := "# request abc123\n\nPOST /login?next=/ HTTP/1.1\nHost: shop.test\nContent-Type: application/json\n\n{\"user\":\"admin'--\"}\n"
+	req, err := ParseRaw([]byte(raw))
+	if err != nil {
+		t.Fatalf("ParseRaw error: %v", err)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if req.Method != http.MethodPost || req.Host != "shop.test" || req.URL.RawQuery != "next=/" || string(body) != `{"user":"admin'--"}` {
+		t.Fatalf("unexpected request %s %s %q %q", req.Method, req.Host, req.URL.RawQuery, body)
+	}
+
+	req, err = ParseRaw([]byte("GET /p?id=1 or 1=1 HTTP/1.1\r\nHost: shop.test\r\n\r\n"))
+	if err != nil || req.URL.Path != "/p" || req.URL.RawQuery != "id=1 or 1=1" {
+		t.Fatalf("expected the raw target to be kept, got %v %v", req, err)
+	}
+
+	if _, err := ParseRaw([]byte("GET / HTTP/1.1\n\n")); err == nil {
+		t.Fatal("expected missing host error")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	gw, err := gateway.NewOffline(&config.Config{
+		Upstreams: []config.Upstream{{Name: "backend", URL: "http://127.0.0.1:1"}},
+		Routes:    []config.Route{{Match: config.RouteMatch{PathPrefix: "/"}, Upstream: "backend", Policy: "default"}},
+		Policies: map[string]config.Policy{
+			"default": {Mode: config.ModeShadow, AnomalyThreshold: 5, Limits: config.Limits{MaxBodyBytes: 1024, MaxHeaderBytes: 1024, Timeout: time.Second}},
+		},
+		Rules: []config.Rule{
+			{ID: "union", Phase: "query", Score: 5, Tags: []string{"sqli"}, Transforms: []string{"lowercase"}, Match: config.RuleMatch{Type: "regex", Pattern: "union select"}},
+			{ID: "admin", Score: 1, Condition: &config.Condition{Target: "PATH", Op: "startsWith", Value: "/admin"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewOffline error: %v", err)
+	}
+	req, err := NewRequest("", "shop.test/search?q=UNION%20SELECT", nil, "")
+	if err != nil {
+		t.Fatalf("NewRequest error: %v", err)
+	}
+	ev, err := gw.Evaluate(req)
+	if err != nil {
+		t.Fatalf("Evaluate error: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := Write(&out, ev); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	for _, want := range []string{
+		`query        "q=UNION%20SELECT"`,
+		`[lowercase] "q=union select"`,
+		`matched          union [query] score 5 evidence "union select"`,
+		`no_match         admin [request]`,
+		`score:      5 / threshold 5`,
+		`categories: sqli=5`,
+		`action:     shadow`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in report:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package explain
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const defaultRemoteAddr = "192.0.2.1:1234"
+
+// NewRequest builds a request for rawURL. The query is kept exactly as
+// written, so payloads need not be escaped. A URL without a scheme is taken
+// as http.
+func NewRequest(method, rawURL string, header http.Header, body string) (*http.Request, error) {
+	if method == "" {
+		method = http.MethodGet
+		if body != "" {
+			method = http.MethodPost
+		}
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	base, query, _ := strings.Cut(rawURL, "?")
+	base, _, _ = strings.Cut(base, "#")
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("parse url: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("url %q has no host", rawURL)
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(strings.ToUpper(method), u.String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.URL.RawQuery = query
+	for name, values := range header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	if host := req.Header.Get("Host"); host != "" {
+		// Servers move Host out of the header map; so does this.
+		req.Host = host
+		req.Header.Del("Host")
+	}
+	if body != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.RemoteAddr = defaultRemoteAddr
+	return req, nil
+}
+
+// ParseRaw reads a request in HTTP/1.x wire format, as copied from a proxy
+// or printed by klyr capture show. Leading blank and # comment lines are
+// skipped, bare LF line endings are accepted, and without a Content-Length
+// everything after the headers is the body.
+func ParseRaw(data []byte) (*http.Request, error) {
+	for {
+		data = bytes.TrimLeft(data, "\r\n")
+		if !bytes.HasPrefix(data, []byte("#")) {
+			break
+		}
+		_, data, _ = bytes.Cut(data, []byte("\n"))
+	}
+
+	// The request target is taken as written, so payloads with spaces survive;
+	// http.ReadRequest only sees a placeholder.
+	line, rest, _ := bytes.Cut(data, []byte("\n"))
+	method, target, ok := strings.Cut(strings.TrimSpace(string(line)), " ")
+	if !ok {
+		return nil, fmt.Errorf("parse raw request: malformed request line %q", line)
+	}
+	proto := "HTTP/1.1"
+	if i := strings.LastIndexByte(target, ' '); i >= 0 && strings.HasPrefix(target[i+1:], "HTTP/") {
+		target, proto = strings.TrimSpace(target[:i]), target[i+1:]
+	}
+	head := []byte(method + " / " + proto + "\r\n")
+	br := bufio.NewReader(bytes.NewReader(append(head, rest...)))
+	req, err := http.ReadRequest(br)
+	if err != nil {
+		return nil, fmt.Errorf("parse raw request: %w", err)
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read raw request body: %w", err)
+	}
+	if req.ContentLength <= 0 && len(req.TransferEncoding) == 0 {
+		rest, _ := io.ReadAll(br)
+		body = append(body, bytes.TrimRight(rest, "\r\n")...)
+	}
+
+	rawURL := target
+	if !strings.Contains(target, "://") {
+		if req.Host == "" {
+			return nil, errors.New("parse raw request: missing Host header")
+		}
+		rawURL = req.Host + target
+	}
+	header := req.Header.Clone()
+	if req.Host != "" {
+		header.Set("Host", req.Host)
+	}
+	return NewRequest(req.Method, rawURL, header, string(body))
+}
+
+// ParseCurl builds a request from a curl command line. It understands the
+// options that shape the request (-X, -H, -d and its variants, -G, -A, -b,
+// -e, -I, --url) and ignores the rest.
+func ParseCurl(cmdline string) (*http.Request, error) {
+	words, err := splitWords(cmdline)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) > 0 && (words[0] == "curl" || strings.HasSuffix(words[0], "/curl")) {
+		words = words[1:]
+	}
+
+	var method, rawURL string
+	var data []string
+	var get bool
+	header := http.Header{}
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		name, value, inline := word, "", false
+		if strings.HasPrefix(word, "--") {
+			name, value, inline = strings.Cut(word, "=")
+		} else if strings.HasPrefix(word, "-") && len(word) > 2 && curlArgs[word[:2]] {
+			name, value, inline = word[:2], word[2:], true
+		}
+		if curlArgs[name] && !inline {
+			if i+1 == len(words) {
+				return nil, fmt.Errorf("curl option %s needs a value", name)
+			}
+			i++
+			value = words[i]
+		}
+
+		switch name {
+		case "-X", "--request":
+			method = value
+		case "-H", "--header":
+			key, val, ok := strings.Cut(value, ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid curl header %q", value)
+			}
+			header.Add(strings.TrimSpace(key), strings.TrimSpace(val))
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii":
+			data = append(data, value)
+		case "--data-urlencode":
+			if key, val, ok := strings.Cut(value, "="); ok {
+				value = key + "=" + url.QueryEscape(val)
+			} else {
+				value = url.QueryEscape(value)
+			}
+			data = append(data, value)
+		case "-A", "--user-agent":
+			header.Set("User-Agent", value)
+		case "-b", "--cookie":
+			header.Add("Cookie", value)
+		case "-e", "--referer":
+			header.Set("Referer", value)
+		case "--url":
+			rawURL = value
+		case "-G", "--get":
+			get = true
+		case "-I", "--head":
+			method = http.MethodHead
+		default:
+			if !strings.HasPrefix(word, "-") {
+				if rawURL != "" {
+					return nil, fmt.Errorf("curl command has more than one url: %q and %q", rawURL, word)
+				}
+				rawURL = word
+			}
+		}
+	}
+	if rawURL == "" {
+		return nil, errors.New("curl command has no url")
+	}
+
+	body := strings.Join(data, "&")
+	if get && body != "" {
+		if strings.Contains(rawURL, "?") {
+			rawURL += "&" + body
+		} else {
+			rawURL += "?" + body
+		}
+		body = ""
+		if method == "" {
+			method = http.MethodGet
+		}
+	}
+	return NewRequest(method, rawURL, header, body)
+}
+
+// curlArgs lists the curl options that take a value.
+var curlArgs = map[string]bool{
+	"-X": true, "--request": true,
+	"-H": true, "--header": true,
+	"-d": true, "--data": true, "--data-raw": true, "--data-binary": true, "--data-ascii": true, "--data-urlencode": true,
+	"-A": true, "--user-agent": true,
+	"-b": true, "--cookie": true,
+	"-e": true, "--referer": true,
+	"-u": true, "--user": true,
+	"-o": true, "--output": true,
+	"-m": true, "--max-time": true,
+	"-x": true, "--proxy": true,
+	"--url": true, "--connect-timeout": true, "--resolve": true, "--cacert": true, "--cert": true, "--key": true,
+}
+
+// splitWords splits a shell command line into words, honouring single and
+// double quotes, backslash escapes and backslash-newline continuations.
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var b strings.Builder
+	inWord := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			if s[i] == '\n' {
+				continue
+			}
+			if s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+				i++
+				continue
+			}
+			b.WriteByte(s[i])
+			inWord = true
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote in command")
+			}
+			b.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) >= 0 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				b.WriteByte(s[i])
+			}
+			if i == len(s) {
+				return nil, errors.New("unterminated double quote in command")
+			}
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, b.String())
+				b.Reset()
+				inWord = false
+			}
+		default:
+			b.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, b.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,160 @@
+package explain
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/klyr/klyr/internal/gateway"
+	"github.com/klyr/klyr/internal/rules"
+)
+
+// Write renders an evaluation as a plain-text report: the request and route,
+// the normalized phase inputs, every rule and its outcome, the score
+// against the threshold, contract violations and the final action.
+func Write(w io.Writer, ev *gateway.Evaluation) error {
+	bw := bufio.NewWriter(w)
+	d := ev.Decision
+
+	target := d.Path
+	if d.Query != "" {
+		target += "?" + d.Query
+	}
+	fmt.Fprintf(bw, "request:    %s %s%s from %s\n", d.Method, d.Host, target, d.ClientIP)
+	fmt.Fprintf(bw, "route:      %s (policy %s, mode %s)\n", d.RouteID, d.Policy, d.Mode)
+
+	if ev.Stage == "limits" {
+		fmt.Fprintf(bw, "\nrequest exceeded the policy size limits; rules and contract were not evaluated\n")
+		writeAction(bw, ev)
+		return bw.Flush()
+	}
+
+	fmt.Fprintf(bw, "\ninputs:\n")
+	phases := []struct {
+		phase rules.Phase
+		raw   string
+	}{
+		{rules.PhaseRequestLine, ev.Context.RequestLine.Raw},
+		{rules.PhaseHeaders, ev.Context.Headers.Raw},
+		{rules.PhaseQuery, ev.Context.Query.Raw},
+		{rules.PhaseBody, ev.Context.Body.Raw},
+	}
+	for _, p := range phases {
+		fmt.Fprintf(bw, "  %-12s %q\n", p.phase, p.raw)
+		for _, in := range ev.Inputs {
+			if in.Phase == p.phase && in.Normalized != in.Raw {
+				fmt.Fprintf(bw, "    %s %q\n", transformList(in.Transforms), in.Normalized)
+			}
+		}
+	}
+
+	matches := make(map[string]rules.Match, len(ev.Result.Matches))
+	for _, m := range ev.Result.Matches {
+		matches[m.RuleID] = m
+	}
+	suppressed := make(map[string]rules.Suppression, len(ev.Result.Suppressed))
+	for _, s := range ev.Result.Suppressed {
+		suppressed[s.RuleID] = s
+	}
+
+	fmt.Fprintf(bw, "\nrules:\n")
+	for _, check := range ev.Trace.Checks {
+		fmt.Fprintf(bw, "  %-16s %s", check.Outcome, check.RuleID)
+		if check.Phase != "" {
+			fmt.Fprintf(bw, " [%s]", check.Phase)
+		}
+		switch check.Outcome {
+		case rules.CheckMatched:
+			m := matches[check.RuleID]
+			fmt.Fprintf(bw, " score %d", m.Score)
+			if m.Action.Type != "" && m.Action.Type != rules.ActionLog {
+				fmt.Fprintf(bw, " action %s", m.Action.Type)
+			}
+			if s, ok := suppressed[check.RuleID]; ok {
+				fmt.Fprintf(bw, " (rescored from %d by exclusion %s)", s.Score, s.ExclusionID)
+			}
+			if check.Evidence != "" {
+				fmt.Fprintf(bw, " evidence %q", check.Evidence)
+			}
+		case rules.CheckSuppressed:
+			if s, ok := suppressed[check.RuleID]; ok {
+				fmt.Fprintf(bw, " by exclusion %s (%s)", s.ExclusionID, s.Effect)
+			}
+		}
+		fmt.Fprintln(bw)
+	}
+	for _, m := range ev.Result.Matches {
+		if m.RuleID == rules.BudgetRuleID {
+			fmt.Fprintf(bw, "  %-16s %s score %d evidence %q\n", rules.CheckMatched, m.RuleID, m.Score, m.Evidence)
+		}
+	}
+	if d.BudgetExceeded != "" {
+		fmt.Fprintf(bw, "  inspection budget exceeded (%s)\n", d.BudgetExceeded)
+	}
+
+	fmt.Fprintf(bw, "\nscore:      %d / threshold %d\n", d.Score, d.Threshold)
+	if len(d.CategoryScores) > 0 {
+		names := make([]string, 0, len(d.CategoryScores))
+		for name := range d.CategoryScores {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		parts := make([]string, len(names))
+		for i, name := range names {
+			parts[i] = fmt.Sprintf("%s=%d", name, d.CategoryScores[name])
+		}
+		fmt.Fprintf(bw, "categories: %s\n", strings.Join(parts, " "))
+	}
+	if d.Category != "" {
+		fmt.Fprintf(bw, "category over threshold: %s\n", d.Category)
+	}
+
+	if ev.Stage == "rules" && ev.Verdict.RuleID != "" {
+		fmt.Fprintf(bw, "\ncontract:   not evaluated, rule %s decided first\n", ev.Verdict.RuleID)
+	} else if len(ev.Violations) == 0 {
+		fmt.Fprintf(bw, "\ncontract:   no violations\n")
+	} else {
+		fmt.Fprintf(bw, "\ncontract:   %d violation(s)\n", len(ev.Violations))
+		for _, v := range ev.Violations {
+			fmt.Fprintf(bw, "  %s %s\n", v.Type, v.Field)
+		}
+	}
+
+	writeAction(bw, ev)
+	return bw.Flush()
+}
+
+func writeAction(w io.Writer, ev *gateway.Evaluation) {
+	d := ev.Decision
+	fmt.Fprintf(w, "\naction:     %s", d.Action)
+	var details []string
+	if d.StatusCode != 0 {
+		details = append(details, fmt.Sprintf("status %d", d.StatusCode))
+	}
+	if ev.Stage != "" {
+		details = append(details, "decided by "+ev.Stage)
+	}
+	if d.ActionRule != "" {
+		details = append(details, "rule "+d.ActionRule)
+	}
+	if d.TarpitMS > 0 {
+		details = append(details, fmt.Sprintf("tarpit %dms", d.TarpitMS))
+	}
+	if len(details) > 0 {
+		fmt.Fprintf(w, " (%s)", strings.Join(details, ", "))
+	}
+	fmt.Fprintln(w)
+}
+
+func transformList(transforms []rules.Transform) string {
+	if len(transforms) == 0 {
+		return "(no transforms)"
+	}
+	names := make([]string, len(transforms))
+	for i, t := range transforms {
+		names[i] = string(t)
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
@@ -0,0 +1,156 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/klyr/klyr/internal/config"
+	"github.com/klyr/klyr/internal/contract"
+	"github.com/klyr/klyr/internal/logging"
+	"github.com/klyr/klyr/internal/policy"
+	"github.com/klyr/klyr/internal/ratelimit"
+	"github.com/klyr/klyr/internal/rules"
+)
+
+// Evaluation is the outcome of running one request through the gateway's
+// checks without proxying it. Decision holds what ServeHTTP would log;
+// StatusCode is left zero for requests that would reach the upstream.
+type Evaluation struct {
+	Decision   logging.Decision
+	Context    rules.EvalContext
+	Result     rules.Result
+	Verdict    policy.Verdict
+	Violations []contract.Violation
+	// Trace and Inputs explain the rule stage: the outcome of every rule and
+	// each phase input after the transforms of the rules scanning it.
+	Trace  rules.Trace
+	Inputs []rules.NormalizedInput
+	// Stage names the check that decided the request: limits, rules,
+	// contract, or empty when it would be proxied.
+	Stage string
+}
+
+// NewOffline returns a gateway for Evaluate. Upstreams are never probed or
+// contacted.
+func NewOffline(cfg *config.Config) (*Gateway, error) {
+	snap, err := buildSnapshot(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	g := &Gateway{limiter: ratelimit.NewLimiter()}
+	g.current.Store(snap)
+	return g, nil
+}
+
+// Evaluate runs r through route matching, size limits, the rules and the
+// contract the same way ServeHTTP does. Rate limits, tarpits, contract
+// learning and drift tracking are skipped, and nothing is logged.
+func (g *Gateway) Evaluate(r *http.Request) (*Evaluation, error) {
+	snap := g.current.Load()
+	route, policyCfg, _, ok := snap.resolveRoute(r)
+	if !ok {
+		return nil, fmt.Errorf("no route for %s%s", r.Host, r.URL.Path)
+	}
+
+	ev := &Evaluation{Decision: logging.Decision{
+		ClientIP:  clientIP(r),
+		Host:      r.Host,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Query:     r.URL.RawQuery,
+		RouteID:   route.ID,
+		Policy:    route.Policy,
+		Mode:      policyCfg.Mode,
+		Threshold: policyCfg.AnomalyThreshold,
+	}}
+	decision := &ev.Decision
+
+	if exceedsHeaderLimit(r.Header, policyCfg.Limits.MaxHeaderBytes) {
+		return ev.blocked("limits", http.StatusRequestHeaderFieldsTooLarge), nil
+	}
+	if policyCfg.Limits.MaxBodyBytes > 0 {
+		if r.ContentLength > policyCfg.Limits.MaxBodyBytes {
+			return ev.blocked("limits", http.StatusRequestEntityTooLarge), nil
+		}
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(nil, r.Body, policyCfg.Limits.MaxBodyBytes)
+		}
+	}
+	body, bodySize, err := readBodyIfNeeded(r, policyCfg, snap.bodyRules)
+	if err != nil {
+		return ev.blocked("limits", http.StatusRequestEntityTooLarge), nil
+	}
+
+	ev.Context, ev.Result, ev.Verdict = snap.evaluateRules(route, policyCfg, r, body, &ev.Trace)
+	ev.Inputs = snap.engine.Inputs(ev.Context)
+	recordRules(decision, ev.Result, ev.Verdict)
+	decision.TarpitMS = ev.Verdict.Delay.Milliseconds()
+	if ev.Verdict.Block && ev.Verdict.RuleID != "" {
+		return ev.ruleBlock(policyCfg), nil
+	}
+
+	ev.Violations = snap.evaluateContract(route.ID, route.Policy, policyCfg, r, body, bodySize)
+	if len(ev.Violations) > 0 {
+		decision.ContractViolations = mapViolations(ev.Violations)
+		if policyCfg.Mode == config.ModeEnforce {
+			return ev.blocked("contract", blockStatus(policyCfg)), nil
+		}
+	}
+
+	decision.Action = string(ev.Verdict.Action)
+	if ev.Verdict.Block {
+		return ev.ruleBlock(policyCfg), nil
+	}
+	return ev, nil
+}
+
+func (ev *Evaluation) blocked(stage string, status int) *Evaluation {
+	ev.Stage = stage
+	ev.Decision.Action = string(policy.ActionBlock)
+	ev.Decision.StatusCode = status
+	return ev
+}
+
+// ruleBlock mirrors blockByRule.
+func (ev *Evaluation) ruleBlock(policyCfg config.Policy) *Evaluation {
+	ev.Stage = "rules"
+	ev.Decision.Action = string(ev.Verdict.Action)
+	ev.Decision.StatusCode = ev.Verdict.StatusCode
+	if ev.Decision.StatusCode == 0 {
+		if ev.Verdict.Action == policy.ActionRedirect {
+			ev.Decision.StatusCode = http.StatusFound
+		} else {
+			ev.Decision.StatusCode = blockStatus(policyCfg)
+		}
+	}
+	return ev
+}
+
+// evaluateRules builds the evaluation context of a request, runs the rules
+// with the route's exclusions and the policy's settings, and decides. trace
+// may be nil.
+func (s *snapshot) evaluateRules(route Route, policyCfg config.Policy, r *http.Request, body []byte, trace *rules.Trace) (rules.EvalContext, rules.Result, policy.Verdict) {
+	evalCtx := buildEvalContext(r, body)
+	result := policy.EvaluateRules(s.engine, evalCtx, rules.EvalOptions{
+		Exclusions:    s.exclusions[route.ID],
+		ParanoiaLevel: policyCfg.ParanoiaLevel,
+		Budget:        rules.NewBudget(policyCfg.Inspection),
+		Trace:         trace,
+	})
+	verdict := policy.Decide(policyCfg.Mode, result, policy.Thresholds{
+		Anomaly:    policyCfg.AnomalyThreshold,
+		Categories: policyCfg.CategoryThresholds,
+	})
+	return evalCtx, result, verdict
+}
+
+func recordRules(decision *logging.Decision, result rules.Result, verdict policy.Verdict) {
+	decision.Score = result.Score
+	decision.BudgetExceeded = result.BudgetExceeded
+	decision.CategoryScores = result.Categories
+	decision.MatchedRules = mapMatches(result.Matches)
+	decision.SuppressedRules = mapSuppressions(result.Suppressed)
+	decision.ActionRule = verdict.RuleID
+	decision.Category = verdict.Category
+	decision.Tags = verdict.Tags
+}
@@ -9,15 +9,19 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/klyr/klyr/internal/capture"
 	"github.com/klyr/klyr/internal/config"
 	"github.com/klyr/klyr/internal/contract"
 	"github.com/klyr/klyr/internal/logging"
@@ -25,27 +29,110 @@ import (
 	"github.com/klyr/klyr/internal/policy"
 	"github.com/klyr/klyr/internal/ratelimit"
 	"github.com/klyr/klyr/internal/rules"
+	"github.com/klyr/klyr/internal/upstream"
 )
 
 const defaultBodyMarginBytes = 1024
 
 type Gateway struct {
-	router    *Router
-	upstreams map[string]*url.URL
-	policies  map[string]config.Policy
-	proxies   map[string]*httputil.ReverseProxy
+	current atomic.Pointer[snapshot]
 
-	engine      *rules.Engine
-	contracts   map[string]*contract.Contract
 	decisionLog *logging.DecisionLogger
+	captures    *capture.Store
 	metrics     *observability.Metrics
 	limiter     *ratelimit.Limiter
-	bodyRules   bool
 
 	requestCount uint64
 }
 
+// snapshot is one generation of configuration-derived state. Requests load the
+// current snapshot once and use it until they complete, so a reload never
+// changes the routing or rules underneath an in-flight request.
+type snapshot struct {
+	generation uint64
+	router     *Router
+	policies   map[string]config.Policy
+	pools      map[string]*upstream.Pool
+	transport  *http.Transport
+
+	engine    *rules.Engine
+	contracts map[string]*contract.Contract
+	learners  map[string]*contract.Learner
+	drift     map[string]*driftState
+	bodyRules bool
+	replay    config.ReplayLogging
+	redactor  *capture.Redactor
+
+	// exclusions holds route exclusions followed by policy exclusions, by
+	// route ID.
+	exclusions map[string][]rules.Exclusion
+}
+
 func New(cfg *config.Config) (*Gateway, error) {
+	snap, err := buildSnapshot(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	snap.start()
+	g := &Gateway{limiter: ratelimit.NewLimiter()}
+	g.current.Store(snap)
+	return g, nil
+}
+
+// Reload builds a new snapshot from cfg and swaps it in. On error the
+// previous snapshot keeps serving. Contracts that are still being learned
+// under the same route and policy carry over so a reload does not discard
+// observations, as does the health of upstream targets that remain.
+func (g *Gateway) Reload(cfg *config.Config) error {
+	prev := g.current.Load()
+	snap, err := buildSnapshot(cfg, prev)
+	if err != nil {
+		return err
+	}
+	snap.start()
+	g.current.Store(snap)
+	prev.close()
+	return nil
+}
+
+// Close stops health probes for the current snapshot.
+func (g *Gateway) Close() {
+	if g == nil {
+		return
+	}
+	g.current.Load().close()
+}
+
+// UpstreamStates reports the health of every upstream target in the current
+// snapshot.
+func (g *Gateway) UpstreamStates() []upstream.TargetState {
+	if g == nil {
+		return nil
+	}
+	snap := g.current.Load()
+	names := make([]string, 0, len(snap.pools))
+	for name := range snap.pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out []upstream.TargetState
+	for _, name := range names {
+		out = append(out, snap.pools[name].States()...)
+	}
+	return out
+}
+
+// Generation returns the number of the snapshot currently serving traffic.
+func (g *Gateway) Generation() uint64 {
+	if g == nil {
+		return 0
+	}
+	return g.current.Load().generation
+}
+
+func buildSnapshot(cfg *config.Config, prev *snapshot) (*snapshot, error) {
 	if cfg == nil {
 		return nil, errors.New("config is required")
 	}
@@ -55,34 +142,17 @@ func New(cfg *config.Config) (*Gateway, error) {
 		return nil, err
 	}
 
-	upstreams := make(map[string]*url.URL, len(cfg.Upstreams))
-	for _, upstream := range cfg.Upstreams {
-		parsed, err := url.Parse(upstream.URL)
-		if err != nil {
-			return nil, fmt.Errorf("parse upstream %s: %w", upstream.Name, err)
-		}
-		upstreams[upstream.Name] = parsed
-	}
-
 	maxTimeout := maxPolicyTimeout(cfg)
 	transport := newTransport(maxTimeout)
 
-	proxies := make(map[string]*httputil.ReverseProxy, len(upstreams))
-	for name, target := range upstreams {
-		proxy := httputil.NewSingleHostReverseProxy(target)
-		proxy.Transport = transport
-		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-			var maxErr *http.MaxBytesError
-			switch {
-			case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
-				http.Error(w, "upstream timeout", http.StatusGatewayTimeout)
-			case errors.As(err, &maxErr):
-				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
-			default:
-				http.Error(w, "upstream error", http.StatusBadGateway)
-			}
+	pools := make(map[string]*upstream.Pool, len(cfg.Upstreams))
+	for _, upstreamCfg := range cfg.Upstreams {
+		pool, err := upstream.NewPool(upstreamCfg, transport, newProxy(transport))
+		if err != nil {
+			return nil, err
 		}
-		proxies[name] = proxy
+		pool.CarryOver(prev.pool(upstreamCfg.Name))
+		pools[upstreamCfg.Name] = pool
 	}
 
 	policies := make(map[string]config.Policy, len(cfg.Policies))
@@ -96,14 +166,25 @@ func New(cfg *config.Config) (*Gateway, error) {
 	}
 
 	contracts := make(map[string]*contract.Contract)
+	learners := make(map[string]*contract.Learner)
+	drift := make(map[string]*driftState)
+	exclusions := make(map[string][]rules.Exclusion)
 	for i, route := range cfg.Routes {
 		routeID := fmt.Sprintf("route-%d", i)
 		policyCfg, ok := policies[route.Policy]
 		if !ok {
 			continue
 		}
+		if len(route.Exclusions) > 0 || len(policyCfg.Exclusions) > 0 {
+			exclusions[routeID] = rules.NewExclusions(append(append([]config.Exclusion(nil), route.Exclusions...), policyCfg.Exclusions...))
+		}
+		key := contractKey(routeID, route.Policy)
 		if policyCfg.Mode == config.ModeLearn {
-			contracts[contractKey(routeID, route.Policy)] = contract.New(routeID, route.Policy)
+			if existing := prev.learner(key, route.Policy); existing != nil {
+				learners[key] = existing
+			} else {
+				learners[key] = contract.NewLearner(routeID, route.Policy)
+			}
 		}
 		if policyCfg.Mode == config.ModeEnforce {
 			path := cfg.ResolvePath(policyCfg.Contract.Path)
@@ -111,42 +192,188 @@ func New(cfg *config.Config) (*Gateway, error) {
 			if err != nil {
 				return nil, fmt.Errorf("load contract for %s: %w", route.Policy, err)
 			}
-			contracts[contractKey(routeID, route.Policy)] = loaded
+			contracts[key] = loaded
+
+			if driftCfg := policyCfg.Contract.Drift; driftCfg.Enabled {
+				opts := contract.DriftOptions{
+					MinCount:   driftCfg.MinCount,
+					MinClients: driftCfg.MinClients,
+					Promote:    driftCfg.Action == config.DriftPromote,
+				}
+				pendingPath := cfg.ResolvePath(driftPendingPath(policyCfg.Contract))
+				tracker := contract.NewDriftTracker(loaded, opts)
+				if existing := prev.driftTracker(key, route.Policy); existing != nil {
+					tracker, err = existing.Rebase(loaded, opts)
+					if err != nil {
+						return nil, fmt.Errorf("carry over contract drift for %s: %w", route.Policy, err)
+					}
+				} else if err := tracker.LoadPending(pendingPath); err != nil {
+					return nil, fmt.Errorf("load pending contract drift for %s: %w", route.Policy, err)
+				}
+				drift[key] = &driftState{tracker: tracker, pendingPath: pendingPath}
+			}
 		}
 	}
 
-	return &Gateway{
-		router:    router,
-		upstreams: upstreams,
-		policies:  policies,
-		proxies:   proxies,
-		engine:    engine,
-		contracts: contracts,
-		limiter:   ratelimit.NewLimiter(),
-		bodyRules: hasBodyRules(engine),
+	redactor, err := capture.NewRedactor(cfg.Capture.Redact)
+	if err != nil {
+		return nil, err
+	}
+
+	var generation uint64 = 1
+	if prev != nil {
+		generation = prev.generation + 1
+	}
+
+	return &snapshot{
+		generation: generation,
+		router:     router,
+		policies:   policies,
+		pools:      pools,
+		transport:  transport,
+		engine:     engine,
+		contracts:  contracts,
+		learners:   learners,
+		drift:      drift,
+		exclusions: exclusions,
+		bodyRules:  hasBodyRules(engine),
+		replay:     cfg.Logging.Replay,
+		redactor:   redactor,
 	}, nil
 }
 
+func newProxy(transport http.RoundTripper) upstream.ProxyFactory {
+	return func(target *url.URL) *httputil.ReverseProxy {
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		proxy.Transport = transport
+		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			var maxErr *http.MaxBytesError
+			switch {
+			case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+				http.Error(w, "upstream timeout", http.StatusGatewayTimeout)
+			case errors.As(err, &maxErr):
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			default:
+				http.Error(w, "upstream error", http.StatusBadGateway)
+			}
+		}
+		return proxy
+	}
+}
+
+// start begins health probes for the snapshot's upstream pools.
+func (s *snapshot) start() {
+	for _, pool := range s.pools {
+		pool.Start()
+	}
+}
+
+// close stops background work owned by the snapshot. Requests still running
+// against it are unaffected.
+func (s *snapshot) close() {
+	if s == nil {
+		return
+	}
+	for _, pool := range s.pools {
+		pool.Close()
+	}
+	if s.transport != nil {
+		s.transport.CloseIdleConnections()
+	}
+}
+
+func (s *snapshot) pool(name string) *upstream.Pool {
+	if s == nil {
+		return nil
+	}
+	return s.pools[name]
+}
+
+func (s *snapshot) learner(key, policyName string) *contract.Learner {
+	if s == nil {
+		return nil
+	}
+	if policyCfg, ok := s.policies[policyName]; !ok || policyCfg.Mode != config.ModeLearn {
+		return nil
+	}
+	return s.learners[key]
+}
+
+func (s *snapshot) driftTracker(key, policyName string) *contract.DriftTracker {
+	if s == nil {
+		return nil
+	}
+	if policyCfg, ok := s.policies[policyName]; !ok || policyCfg.Mode != config.ModeEnforce {
+		return nil
+	}
+	if d, ok := s.drift[key]; ok {
+		return d.tracker
+	}
+	return nil
+}
+
 func (g *Gateway) SetDecisionLogger(logger *logging.DecisionLogger) {
 	g.decisionLog = logger
 }
 
+// SetCaptureStore enables forensic capture of blocked and shadow-flagged
+// requests.
+func (g *Gateway) SetCaptureStore(store *capture.Store) {
+	g.captures = store
+}
+
 func (g *Gateway) SetMetrics(metrics *observability.Metrics) {
 	g.metrics = metrics
+	metrics.SetUpstreamSource(g.UpstreamStates)
+	metrics.SetDriftSource(g.DriftCounts)
+}
+
+// DriftCounts reports contract drift candidates per state for every enforced
+// route with drift detection enabled.
+func (g *Gateway) DriftCounts() []contract.DriftCount {
+	if g == nil {
+		return nil
+	}
+	snap := g.current.Load()
+	keys := make([]string, 0, len(snap.drift))
+	for key := range snap.drift {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var out []contract.DriftCount
+	for _, key := range keys {
+		routeID, policyName, _ := strings.Cut(key, "|")
+		out = append(out, snap.drift[key].tracker.Counts(routeID, policyName)...)
+	}
+	return out
 }
 
 func (g *Gateway) Contract(routeID, policyName string) *contract.Contract {
 	if g == nil {
 		return nil
 	}
-	return g.contracts[contractKey(routeID, policyName)]
+	snap := g.current.Load()
+	key := contractKey(routeID, policyName)
+	if l, ok := snap.learners[key]; ok {
+		return l.Snapshot()
+	}
+	if d, ok := snap.drift[key]; ok {
+		return d.tracker.Contract()
+	}
+	return snap.contracts[key]
 }
 
-func (g *Gateway) SaveContracts(cfg *config.Config) error {
+// SaveContracts finalizes and writes every learned contract, and next to each
+// one a summary of the items dropped by the policy's minFrequency and
+// minClients thresholds.
+func (g *Gateway) SaveContracts(cfg *config.Config) ([]contract.LearnSummary, error) {
 	if cfg == nil {
-		return nil
+		return nil, nil
 	}
 
+	snap := g.current.Load()
+	var summaries []contract.LearnSummary
 	for i, route := range cfg.Routes {
 		routeID := fmt.Sprintf("route-%d", i)
 		policyCfg, ok := cfg.Policies[route.Policy]
@@ -157,21 +384,34 @@ func (g *Gateway) SaveContracts(cfg *config.Config) error {
 			continue
 		}
 		key := contractKey(routeID, route.Policy)
-		c, ok := g.contracts[key]
+		l, ok := snap.learners[key]
 		if !ok {
 			continue
 		}
-		c.Finalize(defaultBodyMarginBytes)
+		c, rejected := l.Finalize(defaultBodyMarginBytes, contract.PruneOptions{
+			MinFrequency: policyCfg.Contract.MinFrequency,
+			MinClients:   policyCfg.Contract.MinClients,
+		})
 		path := cfg.ResolvePath(policyCfg.Contract.Path)
 		if err := contract.Save(path, c); err != nil {
-			return err
+			return nil, err
+		}
+		summary := contract.LearnSummary{RouteID: routeID, Policy: route.Policy, Path: path, Samples: c.Samples, Rejected: rejected}
+		if err := contract.SaveSummary(learnSummaryPath(path), summary); err != nil {
+			return nil, err
 		}
+		summaries = append(summaries, summary)
 	}
-	return nil
+	return summaries, nil
+}
+
+func learnSummaryPath(contractPath string) string {
+	return strings.TrimSuffix(contractPath, filepath.Ext(contractPath)) + ".summary.json"
 }
 
 func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	route, policyCfg, proxy, ok := g.resolveRoute(r)
+	snap := g.current.Load()
+	route, policyCfg, pool, ok := snap.resolveRoute(r)
 	if !ok {
 		http.NotFound(w, r)
 		return
@@ -195,6 +435,7 @@ func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if exceedsHeaderLimit(r.Header, policyCfg.Limits.MaxHeaderBytes) {
 		decision.Action = string(policy.ActionBlock)
 		decision.StatusCode = http.StatusRequestHeaderFieldsTooLarge
+		decision.Captured = g.captureRequest(decision, r, nil)
 		g.writeDecision(decision, start, 0, "rule", nil, nil, "")
 		http.Error(w, "request headers too large", http.StatusRequestHeaderFieldsTooLarge)
 		return
@@ -204,6 +445,7 @@ func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if r.ContentLength > policyCfg.Limits.MaxBodyBytes {
 			decision.Action = string(policy.ActionBlock)
 			decision.StatusCode = http.StatusRequestEntityTooLarge
+			decision.Captured = g.captureRequest(decision, r, nil)
 			g.writeDecision(decision, start, 0, "rule", nil, nil, "")
 			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
 			return
@@ -214,14 +456,18 @@ func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), policyCfg.Limits.Timeout)
 	defer cancel()
 
-	body, bodySize, bodyErr := readBodyIfNeeded(r, policyCfg, g.bodyRules)
+	body, bodySize, bodyErr := readBodyIfNeeded(r, policyCfg, snap.bodyRules || snap.replay.Enabled || g.captures != nil)
 	if bodyErr != nil {
 		decision.Action = string(policy.ActionBlock)
 		decision.StatusCode = http.StatusRequestEntityTooLarge
+		decision.Captured = g.captureRequest(decision, r, nil)
 		g.writeDecision(decision, start, 0, "rule", nil, nil, "")
 		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
 		return
 	}
+	if snap.replay.Enabled {
+		decision.Request = recordRequest(r, body, snap.replay.MaxBodyBytes, snap.redactor)
+	}
 
 	rlKey := ""
 	ratelimitLabel := ""
@@ -239,76 +485,161 @@ func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	evalCtx := buildEvalContext(r, body)
-	result := policy.EvaluateRules(g.engine, evalCtx)
-	decision.Score = result.Score
-	decision.MatchedRules = mapMatches(result.Matches)
+	_, result, verdict := snap.evaluateRules(route, policyCfg, r, body, nil)
+	recordRules(&decision, result, verdict)
+	if verdict.Delay > 0 {
+		decision.TarpitMS = verdict.Delay.Milliseconds()
+		if !tarpit(r.Context(), verdict.Delay) {
+			decision.Action = string(verdict.Action)
+			g.writeDecision(decision, start, 0, "tarpit", decision.MatchedRules, nil, ratelimitLabel)
+			return
+		}
+	}
+	// Rule actions block before the contract is checked.
+	if verdict.Block && verdict.RuleID != "" {
+		g.blockByRule(w, r, body, decision, verdict, policyCfg, start, ratelimitLabel)
+		return
+	}
 
-	contractViolations := g.checkContract(route.ID, route.Policy, policyCfg, r, bodySize)
+	contractViolations := snap.checkContract(route.ID, route.Policy, policyCfg, r, body, bodySize)
+	if len(contractViolations) > 0 && policyCfg.Mode == config.ModeEnforce {
+		drift, promoted := snap.observeDrift(route.ID, route.Policy, r, body, decision.ClientIP, verdict.Block, contractViolations)
+		decision.ContractDrift = drift
+		if promoted {
+			contractViolations = snap.checkContract(route.ID, route.Policy, policyCfg, r, body, bodySize)
+		}
+	}
 	if len(contractViolations) > 0 {
 		decision.ContractViolations = mapViolations(contractViolations)
 		if policyCfg.Mode == config.ModeEnforce {
 			decision.Action = string(policy.ActionBlock)
 			decision.StatusCode = blockStatus(policyCfg)
+			decision.Captured = g.captureRequest(decision, r, body)
 			g.writeDecision(decision, start, 0, "contract", decision.MatchedRules, decision.ContractViolations, ratelimitLabel)
 			http.Error(w, policyCfg.Actions.BlockBody, decision.StatusCode)
 			return
 		}
 	}
 
-	action, shouldBlock := policy.DecideAction(policyCfg.Mode, result.Score, policyCfg.AnomalyThreshold)
-	decision.Action = string(action)
-	if shouldBlock {
-		decision.StatusCode = blockStatus(policyCfg)
-		g.writeDecision(decision, start, 0, "rule", decision.MatchedRules, decision.ContractViolations, ratelimitLabel)
-		http.Error(w, policyCfg.Actions.BlockBody, decision.StatusCode)
+	decision.Action = string(verdict.Action)
+	if verdict.Block {
+		g.blockByRule(w, r, body, decision, verdict, policyCfg, start, ratelimitLabel)
+		return
+	}
+
+	target, skipped, err := pool.Pick(decision.ClientIP)
+	decision.SkippedTargets = skipped
+	if err != nil {
+		decision.StatusCode = http.StatusServiceUnavailable
+		g.writeDecision(decision, start, 0, "upstream_unavailable", decision.MatchedRules, decision.ContractViolations, ratelimitLabel)
+		http.Error(w, "no healthy upstream", http.StatusServiceUnavailable)
 		return
 	}
+	decision.UpstreamTarget = target.String()
 
 	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
 	req := r.WithContext(ctx)
-	proxy.ServeHTTP(rec, req)
+	target.ServeHTTP(rec, req)
 	decision.StatusCode = rec.status
 	decision.UpstreamMS = time.Since(start).Milliseconds()
+	if verdict.Action == policy.ActionShadow {
+		decision.Captured = g.captureRequest(decision, r, body)
+	}
 	g.writeDecision(decision, start, decision.UpstreamMS, "", decision.MatchedRules, decision.ContractViolations, ratelimitLabel)
 }
 
-func (g *Gateway) checkContract(routeID, policyName string, policyCfg config.Policy, r *http.Request, bodySize int64) []contract.Violation {
+func (s *snapshot) checkContract(routeID, policyName string, policyCfg config.Policy, r *http.Request, body []byte, bodySize int64) []contract.Violation {
 	key := contractKey(routeID, policyName)
-	c, ok := g.contracts[key]
-	if !ok {
+
+	if policyCfg.Mode == config.ModeLearn {
+		s.learners[key].Observe(r, body, bodySize)
 		return nil
 	}
+	return s.evaluateContract(routeID, policyName, policyCfg, r, body, bodySize)
+}
 
-	enforcement := parseEnforcement(policyCfg.Contract.Enforcement)
-
-	switch policyCfg.Mode {
-	case config.ModeLearn:
-		c.Observe(r, bodySize)
+// evaluateContract checks a request against the live contract of an
+// enforced route without learning from it.
+func (s *snapshot) evaluateContract(routeID, policyName string, policyCfg config.Policy, r *http.Request, body []byte, bodySize int64) []contract.Violation {
+	if policyCfg.Mode != config.ModeEnforce {
 		return nil
-	case config.ModeEnforce:
-		return contract.Evaluate(c, r, bodySize, enforcement)
-	default:
+	}
+	key := contractKey(routeID, policyName)
+	c, ok := s.contracts[key]
+	if !ok {
 		return nil
 	}
+	if d, ok := s.drift[key]; ok {
+		c = d.tracker.Contract()
+	}
+	return contract.Evaluate(c, r, body, bodySize, parseEnforcement(policyCfg.Contract.Enforcement))
+}
+
+type driftState struct {
+	tracker     *contract.DriftTracker
+	pendingPath string
 }
 
-func (g *Gateway) resolveRoute(r *http.Request) (Route, config.Policy, *httputil.ReverseProxy, bool) {
-	route, ok := g.router.Match(r)
+// observeDrift feeds a request the rules allowed but the contract flagged to
+// the drift tracker. It reports whether a promotion changed the live contract
+// so the caller can evaluate the request again.
+func (s *snapshot) observeDrift(routeID, policyName string, r *http.Request, body []byte, clientIP string, blocked bool, violations []contract.Violation) ([]logging.ContractDrift, bool) {
+	d, ok := s.drift[contractKey(routeID, policyName)]
+	if !ok || blocked {
+		return nil, false
+	}
+
+	candidates, err := d.tracker.Observe(violations, r, body, clientIP, time.Now().UTC())
+	if err != nil {
+		log.Printf("contract drift for %s: %v", policyName, err)
+		return nil, false
+	}
+	if len(candidates) == 0 {
+		return nil, false
+	}
+	if err := d.tracker.WritePending(d.pendingPath); err != nil {
+		log.Printf("contract drift for %s: write pending changes: %v", policyName, err)
+	}
+
+	out := make([]logging.ContractDrift, len(candidates))
+	promoted := false
+	for i, cand := range candidates {
+		out[i] = logging.ContractDrift{
+			Kind:     cand.Kind,
+			Endpoint: cand.Endpoint,
+			Name:     cand.Name,
+			State:    cand.State,
+			Count:    cand.Count,
+			Clients:  cand.Clients,
+		}
+		promoted = promoted || cand.State == contract.CandidatePromoted
+	}
+	return out, promoted
+}
+
+func driftPendingPath(cfg config.ContractConfig) string {
+	if cfg.Drift.PendingPath != "" {
+		return cfg.Drift.PendingPath
+	}
+	return strings.TrimSuffix(cfg.Path, filepath.Ext(cfg.Path)) + ".pending.json"
+}
+
+func (s *snapshot) resolveRoute(r *http.Request) (Route, config.Policy, *upstream.Pool, bool) {
+	route, ok := s.router.Match(r)
 	if !ok {
 		return Route{}, config.Policy{}, nil, false
 	}
 
-	policyCfg, ok := g.policies[route.Policy]
+	policyCfg, ok := s.policies[route.Policy]
 	if !ok {
 		return Route{}, config.Policy{}, nil, false
 	}
-	proxy, ok := g.proxies[route.Upstream]
+	pool, ok := s.pools[route.Upstream]
 	if !ok {
 		return Route{}, config.Policy{}, nil, false
 	}
 
-	return route, policyCfg, proxy, true
+	return route, policyCfg, pool, true
 }
 
 func (g *Gateway) writeDecision(decision logging.Decision, start time.Time, upstreamMS int64, reason string, matches []logging.MatchedRule, violations []logging.ContractViolation, ratelimitKey string) {
@@ -322,6 +653,15 @@ func (g *Gateway) writeDecision(decision logging.Decision, start time.Time, upst
 	}
 }
 
+// captureRequest queues r for the capture store when one is set and reports
+// whether it was queued. Write failures are logged by the store.
+func (g *Gateway) captureRequest(decision logging.Decision, r *http.Request, body []byte) bool {
+	if g.captures == nil {
+		return false
+	}
+	return g.captures.Enqueue(capture.NewRecord(decision, r, body))
+}
+
 func (g *Gateway) newRequestID() string {
 	var buf [12]byte
 	if _, err := rand.Read(buf[:]); err == nil {
@@ -343,6 +683,25 @@ func mapMatches(matches []rules.Match) []logging.MatchedRule {
 			Score:    m.Score,
 			Tags:     append([]string(nil), m.Tags...),
 			Evidence: redactSecrets(m.Evidence),
+			Patterns: append([]string(nil), m.Patterns...),
+			Action:   string(m.Action.Type),
+		}
+	}
+	return out
+}
+
+func mapSuppressions(suppressed []rules.Suppression) []logging.SuppressedRule {
+	if len(suppressed) == 0 {
+		return nil
+	}
+	out := make([]logging.SuppressedRule, len(suppressed))
+	for i, s := range suppressed {
+		out[i] = logging.SuppressedRule{
+			ID:        s.RuleID,
+			Exclusion: s.ExclusionID,
+			Effect:    s.Effect,
+			Score:     s.Score,
+			Evidence:  redactSecrets(s.Evidence),
 		}
 	}
 	return out
@@ -357,12 +716,49 @@ func mapViolations(violations []contract.Violation) []logging.ContractViolation
 }
 
 func buildEvalContext(r *http.Request, body []byte) rules.EvalContext {
+	decoded := jsonForEval(r, body)
 	return rules.EvalContext{
 		RequestLine: rules.Field{Raw: fmt.Sprintf("%s %s", r.Method, r.URL.Path)},
 		Headers:     rules.Field{Raw: headersForEval(r.Header)},
 		Query:       rules.Field{Raw: r.URL.RawQuery},
-		Body:        rules.Field{Raw: bodyForEval(r, body)},
+		Body:        rules.Field{Raw: bodyText(body, decoded)},
+		Method:      r.Method,
+		Path:        r.URL.Path,
+		RemoteAddr:  clientIP(r),
+		Args:        argsForEval(r, body),
+		Header:      r.Header,
+		Cookies:     cookiesForEval(r),
+		JSON:        decoded,
+	}
+}
+
+// argsForEval merges query parameters with urlencoded form fields.
+func argsForEval(r *http.Request, body []byte) url.Values {
+	args := r.URL.Query()
+	ct := strings.ToLower(r.Header.Get("Content-Type"))
+	if len(body) == 0 || !strings.HasPrefix(ct, "application/x-www-form-urlencoded") {
+		return args
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return args
+	}
+	for name, values := range form {
+		args[name] = append(args[name], values...)
+	}
+	return args
+}
+
+func cookiesForEval(r *http.Request) url.Values {
+	cookies := r.Cookies()
+	if len(cookies) == 0 {
+		return nil
+	}
+	out := make(url.Values, len(cookies))
+	for _, cookie := range cookies {
+		out[cookie.Name] = append(out[cookie.Name], cookie.Value)
 	}
+	return out
 }
 
 func headersForEval(headers http.Header) string {
@@ -384,6 +780,30 @@ func headersForEval(headers http.Header) string {
 	return b.String()
 }
 
+// recordRequest keeps the headers and up to maxBody bytes of the body of r
+// for replay, redacted the same way as captures.
+func recordRequest(r *http.Request, body []byte, maxBody int64, redactor *capture.Redactor) *logging.RecordedRequest {
+	if maxBody == 0 {
+		maxBody = config.DefaultReplayBodyBytes
+	}
+	rec := &logging.RecordedRequest{Headers: make(map[string][]string, len(r.Header))}
+	for name, values := range r.Header {
+		canon := http.CanonicalHeaderKey(name)
+		for _, value := range values {
+			rec.Headers[canon] = append(rec.Headers[canon], redactor.Header(canon, value))
+		}
+	}
+	body = redactor.Body(r.Header.Get("Content-Type"), body)
+	if int64(len(body)) > maxBody {
+		body = body[:maxBody]
+		rec.BodyTruncated = true
+	}
+	if len(body) > 0 {
+		rec.Body = append([]byte(nil), body...)
+	}
+	return rec
+}
+
 func isSensitiveHeader(name string) bool {
 	switch strings.ToLower(name) {
 	case "authorization", "cookie", "set-cookie":
@@ -407,28 +827,36 @@ func redactSecrets(input string) string {
 	return redacted
 }
 
-func bodyForEval(r *http.Request, body []byte) string {
-	if len(body) == 0 || r == nil {
+func bodyText(body []byte, decoded any) string {
+	if len(body) == 0 {
 		return ""
 	}
-	ct := strings.ToLower(r.Header.Get("Content-Type"))
-	if strings.Contains(ct, "application/json") {
-		text := shallowJSON(body)
-		if text != "" {
-			return text
-		}
+	if text := shallowJSON(decoded); text != "" {
+		return text
 	}
 	return string(body)
 }
 
-func shallowJSON(body []byte) string {
+// jsonForEval decodes a JSON body once for both the flattened body field and
+// BODY_JSON conditions.
+func jsonForEval(r *http.Request, body []byte) any {
+	if len(body) == 0 || r == nil {
+		return nil
+	}
+	ct := strings.ToLower(r.Header.Get("Content-Type"))
+	if !strings.Contains(ct, "application/json") {
+		return nil
+	}
 	var value any
 	decoder := json.NewDecoder(bytes.NewReader(body))
 	decoder.UseNumber()
 	if err := decoder.Decode(&value); err != nil {
-		return ""
+		return nil
 	}
+	return value
+}
 
+func shallowJSON(value any) string {
 	obj, ok := value.(map[string]any)
 	if !ok {
 		return ""
@@ -549,6 +977,41 @@ func parseEnforcement(level string) contract.Enforcement {
 	}
 }
 
+// blockByRule answers a request the rule stage blocked, either by anomaly
+// score or by a block or redirect rule action.
+func (g *Gateway) blockByRule(w http.ResponseWriter, r *http.Request, body []byte, decision logging.Decision, verdict policy.Verdict, policyCfg config.Policy, start time.Time, ratelimitLabel string) {
+	decision.Action = string(verdict.Action)
+	decision.StatusCode = verdict.StatusCode
+	if verdict.Action == policy.ActionRedirect {
+		if decision.StatusCode == 0 {
+			decision.StatusCode = http.StatusFound
+		}
+		decision.Captured = g.captureRequest(decision, r, body)
+		g.writeDecision(decision, start, 0, "rule", decision.MatchedRules, decision.ContractViolations, ratelimitLabel)
+		http.Redirect(w, r, verdict.Location, decision.StatusCode)
+		return
+	}
+	if decision.StatusCode == 0 {
+		decision.StatusCode = blockStatus(policyCfg)
+	}
+	decision.Captured = g.captureRequest(decision, r, body)
+	g.writeDecision(decision, start, 0, "rule", decision.MatchedRules, decision.ContractViolations, ratelimitLabel)
+	http.Error(w, policyCfg.Actions.BlockBody, decision.StatusCode)
+}
+
+// tarpit holds the request for delay and reports false if the client went
+// away first.
+func tarpit(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
 func blockStatus(policyCfg config.Policy) int {
 	if policyCfg.Actions.BlockStatusCode > 0 {
 		return policyCfg.Actions.BlockStatusCode
@@ -574,12 +1037,7 @@ func hasBodyRules(engine *rules.Engine) bool {
 	if engine == nil {
 		return false
 	}
-	for _, rule := range engine.Rules {
-		if rule.Phase == rules.PhaseBody {
-			return true
-		}
-	}
-	return false
+	return engine.NeedsBody()
 }
 
 func exceedsHeaderLimit(headers http.Header, maxBytes int64) bool {
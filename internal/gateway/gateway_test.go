@@ -2,14 +2,20 @@ package gateway
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/klyr/klyr/internal/capture"
 	"github.com/klyr/klyr/internal/config"
+	"github.com/klyr/klyr/internal/contract"
+	"github.com/klyr/klyr/internal/logging"
 )
 
 func TestGatewayProxy(t *testing.T) {
@@ -81,6 +87,266 @@ func TestGatewayRejectsLargeHeaders(t *testing.T) {
 	}
 }
 
+func TestGatewayReload(t *testing.T) {
+	first := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("first"))
+	}))
+	defer first.Close()
+	second := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("second"))
+	}))
+	defer second.Close()
+
+	gw, err := New(sampleConfig(first.URL, 1024, 1024))
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+
+	get := func() string {
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+		return rec.Body.String()
+	}
+
+	if got := get(); got != "first" {
+		t.Fatalf("expected first upstream, got %q", got)
+	}
+
+	if err := gw.Reload(sampleConfig(second.URL, 1024, 1024)); err != nil {
+		t.Fatalf("Reload error: %v", err)
+	}
+	if got := get(); got != "second" {
+		t.Fatalf("expected second upstream after reload, got %q", got)
+	}
+	if gw.Generation() != 2 {
+		t.Fatalf("expected generation 2, got %d", gw.Generation())
+	}
+
+	broken := sampleConfig(first.URL, 1024, 1024)
+	broken.Rules = []config.Rule{{ID: "missing", Phase: "query", Match: config.RuleMatch{Type: "aho", PatternsFile: "does-not-exist.txt"}}}
+	if err := gw.Reload(broken); err == nil {
+		t.Fatal("expected reload error for missing patterns file")
+	}
+	if got := get(); got != "second" {
+		t.Fatalf("expected previous generation to keep serving, got %q", got)
+	}
+	if gw.Generation() != 2 {
+		t.Fatalf("expected generation to stay 2, got %d", gw.Generation())
+	}
+}
+
+func TestGatewaySkipsEjectedTargets(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	downURL := down.URL
+	down.Close()
+
+	cfg := sampleConfig("", 1024, 1024)
+	cfg.Upstreams = []config.Upstream{{
+		Name:          "backend",
+		Targets:       []string{downURL},
+		PassiveHealth: config.PassiveHealthConfig{Enabled: true, MaxFailures: 1, EjectDuration: time.Minute},
+	}}
+	gw, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	var logs bytes.Buffer
+	gw.SetDecisionLogger(logging.NewDecisionLogger(&logs))
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502 from unreachable target, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 once target is ejected, got %d", rec.Code)
+	}
+	if !strings.Contains(logs.String(), `"skipped_targets":["`+downURL+`"]`) {
+		t.Fatalf("expected skipped target in decision log, got %s", logs.String())
+	}
+
+	if err := gw.Reload(cfg); err != nil {
+		t.Fatalf("Reload error: %v", err)
+	}
+	if states := gw.UpstreamStates(); len(states) != 1 || !states[0].Ejected {
+		t.Fatalf("expected target to stay ejected across reload, got %+v", states)
+	}
+	rec = httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 after reload while target is ejected, got %d", rec.Code)
+	}
+}
+
+func TestGatewayPromotesContractDrift(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	dir := t.TempDir()
+	enforced := contract.New("route-0", "default")
+	enforced.Methods["GET"] = true
+	enforced.QueryParams["q"] = true
+	if err := contract.Save(filepath.Join(dir, "contract.json"), enforced); err != nil {
+		t.Fatalf("save contract: %v", err)
+	}
+
+	cfg := sampleConfig(backend.URL, 1024, 1024)
+	policyCfg := cfg.Policies["default"]
+	policyCfg.Mode = config.ModeEnforce
+	policyCfg.AnomalyThreshold = 10
+	policyCfg.Contract = config.ContractConfig{
+		Path:        filepath.Join(dir, "contract.json"),
+		Enforcement: string(contract.EnforcementModerate),
+		Drift:       config.DriftConfig{Enabled: true, MinCount: 2, MinClients: 2, Action: config.DriftPromote},
+	}
+	cfg.Policies["default"] = policyCfg
+
+	gw, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	var logs bytes.Buffer
+	gw.SetDecisionLogger(logging.NewDecisionLogger(&logs))
+
+	get := func(client string) int {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/search?q=a&page=2", nil)
+		req.RemoteAddr = client + ":1234"
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := get("203.0.113.1"); code != http.StatusForbidden {
+		t.Fatalf("expected unknown param to be blocked, got %d", code)
+	}
+	if code := get("203.0.113.1"); code != http.StatusForbidden {
+		t.Fatalf("expected block until a second client is seen, got %d", code)
+	}
+	if code := get("203.0.113.2"); code != http.StatusOK {
+		t.Fatalf("expected request to pass once promoted, got %d", code)
+	}
+	if code := get("203.0.113.3"); code != http.StatusOK {
+		t.Fatalf("expected promoted param to stay allowed, got %d", code)
+	}
+
+	if !strings.Contains(logs.String(), `"contract_drift":[{"kind":"query_param","name":"page","state":"promoted"`) {
+		t.Fatalf("expected promotion in decision log, got %s", logs.String())
+	}
+	pending, err := os.ReadFile(filepath.Join(dir, "contract.pending.json"))
+	if err != nil || !strings.Contains(string(pending), `"name": "page"`) {
+		t.Fatalf("expected pending changes file, got %q (%v)", pending, err)
+	}
+
+	if err := gw.Reload(cfg); err != nil {
+		t.Fatalf("Reload error: %v", err)
+	}
+	if code := get("203.0.113.4"); code != http.StatusOK {
+		t.Fatalf("expected promotion to survive reload, got %d", code)
+	}
+}
+
+func TestGatewayLogsSuppressedRules(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	cfg := sampleConfig(backend.URL, 1024, 1024)
+	cfg.Rules = []config.Rule{
+		{ID: "quote", Score: 5, Tags: []string{"sqli"}, Condition: &config.Condition{Target: "ARGS", Op: "contains", Value: "'"}},
+	}
+	cfg.Routes[0].Exclusions = []config.Exclusion{
+		{ID: "search-names", Tags: []string{"sqli"}, PathPrefix: "/search", Targets: []string{"ARGS:name"}},
+	}
+
+	gw, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	var logs bytes.Buffer
+	gw.SetDecisionLogger(logging.NewDecisionLogger(&logs))
+
+	get := func(target string) {
+		gw.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	get("http://example.com/search?name=O'Brien")
+	if !strings.Contains(logs.String(), `"suppressed_rules":[{"id":"quote","exclusion":"search-names","effect":"target_excluded"`) {
+		t.Fatalf("expected suppression in decision log, got %s", logs.String())
+	}
+	if strings.Contains(logs.String(), `"matched_rules":[{`) {
+		t.Fatalf("expected no rule match, got %s", logs.String())
+	}
+
+	logs.Reset()
+	get("http://example.com/other?name=O'Brien")
+	if !strings.Contains(logs.String(), `"matched_rules":[{"id":"quote"`) {
+		t.Fatalf("expected rule to match outside /search, got %s", logs.String())
+	}
+}
+
+func TestGatewayRuleActions(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	cfg := sampleConfig(backend.URL, 1024, 1024)
+	cfg.Rules = []config.Rule{
+		{ID: "health", Condition: &config.Condition{Target: "PATH", Op: "equals", Value: "/healthz"}, Action: config.RuleAction{Type: config.RuleActionAllow}},
+		{ID: "scanner", Score: 1, Condition: &config.Condition{Target: "HEADERS:User-Agent", Op: "contains", Value: "sqlmap"}, Action: config.RuleAction{Type: config.RuleActionBlock}},
+		{ID: "legacy", Condition: &config.Condition{Target: "PATH", Op: "startsWith", Value: "/v1/"}, Action: config.RuleAction{Type: config.RuleActionRedirect, Location: "/v2/"}},
+		{ID: "noisy", Score: 10, Condition: &config.Condition{Target: "ARGS:debug", Op: "exists"}},
+	}
+	policyCfg := cfg.Policies["default"]
+	policyCfg.Mode = config.ModeEnforce
+	policyCfg.AnomalyThreshold = 10
+	policyCfg.Contract.Path = filepath.Join(t.TempDir(), "contract.json")
+	cfg.Policies["default"] = policyCfg
+	enforced := contract.New("route-0", "default")
+	enforced.Methods["GET"] = true
+	enforced.QueryParams["debug"] = true
+	if err := contract.Save(policyCfg.Contract.Path, enforced); err != nil {
+		t.Fatalf("save contract: %v", err)
+	}
+
+	gw, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	var logs bytes.Buffer
+	gw.SetDecisionLogger(logging.NewDecisionLogger(&logs))
+
+	serve := func(target, agent string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("User-Agent", agent)
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("http://example.com/search", "sqlmap/1.7"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected block action below threshold, got %d", rec.Code)
+	}
+	if !strings.Contains(logs.String(), `"action":"block","action_rule":"scanner"`) {
+		t.Fatalf("expected deciding rule in decision log, got %s", logs.String())
+	}
+	if rec := serve("http://example.com/healthz?debug=1", "sqlmap/1.7"); rec.Code != http.StatusOK {
+		t.Fatalf("expected allow rule to bypass scoring, got %d", rec.Code)
+	}
+	if rec := serve("http://example.com/v1/items", "curl"); rec.Code != http.StatusFound || rec.Header().Get("Location") != "/v2/" {
+		t.Fatalf("expected redirect, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := serve("http://example.com/search?debug=1", "curl"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected score threshold block, got %d", rec.Code)
+	}
+}
+
 func sampleConfig(upstreamURL string, maxBodyBytes, maxHeaderBytes int64) *config.Config {
 	return &config.Config{
 		Upstreams: []config.Upstream{
@@ -138,12 +404,12 @@ func TestHeadersForEvalRedactsSensitive(t *testing.T) {
 	}
 }
 
-func TestBodyForEvalJSON(t *testing.T) {
+func TestBodyTextJSON(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "http://example.com/", strings.NewReader(`{"user":"alice","password":"secret","count":2}`))
 	req.Header.Set("Content-Type", "application/json")
 	body, _ := io.ReadAll(req.Body)
 
-	out := bodyForEval(req, body)
+	out := bodyText(body, jsonForEval(req, body))
 	if !strings.Contains(out, "user=alice") {
 		t.Fatalf("expected user field, got %q", out)
 	}
@@ -154,3 +420,130 @@ func TestBodyForEvalJSON(t *testing.T) {
 		t.Fatalf("expected password redaction, got %q", out)
 	}
 }
+
+func TestGatewayEvaluate(t *testing.T) {
+	cfg := sampleConfig("http://127.0.0.1:1", 8, 1024)
+	cfg.Rules = []config.Rule{
+		{ID: "scanner", Score: 1, Condition: &config.Condition{Target: "HEADERS:User-Agent", Op: "contains", Value: "sqlmap"}, Action: config.RuleAction{Type: config.RuleActionBlock}},
+		{ID: "quote", Score: 5, Condition: &config.Condition{Target: "ARGS", Op: "contains", Value: "'"}},
+	}
+	policyCfg := cfg.Policies["default"]
+	policyCfg.Mode = config.ModeEnforce
+	policyCfg.AnomalyThreshold = 5
+	policyCfg.Contract.Path = filepath.Join(t.TempDir(), "contract.json")
+	cfg.Policies["default"] = policyCfg
+	enforced := contract.New("route-0", "default")
+	enforced.Methods["POST"] = true
+	if err := contract.Save(policyCfg.Contract.Path, enforced); err != nil {
+		t.Fatalf("save contract: %v", err)
+	}
+
+	gw, err := NewOffline(cfg)
+	if err != nil {
+		t.Fatalf("NewOffline error: %v", err)
+	}
+
+	cases := []struct {
+		name, method, target, agent, body string
+		wantAction, wantStage             string
+		wantStatus                        int
+	}{
+		{"clean", "POST", "http://example.com/search?q=shoes", "curl", "", "allow", "", 0},
+		{"rule action", "POST", "http://example.com/search", "sqlmap/1.7", "", "block", "rules", http.StatusForbidden},
+		{"score", "POST", "http://example.com/search?q=O'Brien", "curl", "", "block", "rules", http.StatusForbidden},
+		{"contract", "DELETE", "http://example.com/search", "curl", "", "block", "contract", http.StatusForbidden},
+		{"body limit", "POST", "http://example.com/upload", "curl", "too large", "block", "limits", http.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			req.Header.Set("User-Agent", tc.agent)
+			ev, err := gw.Evaluate(req)
+			if err != nil {
+				t.Fatalf("Evaluate error: %v", err)
+			}
+			if ev.Decision.Action != tc.wantAction || ev.Stage != tc.wantStage || ev.Decision.StatusCode != tc.wantStatus {
+				t.Fatalf("expected %s/%s/%d, got %s/%s/%d", tc.wantAction, tc.wantStage, tc.wantStatus, ev.Decision.Action, ev.Stage, ev.Decision.StatusCode)
+			}
+		})
+	}
+}
+
+func TestGatewayCapturesFlaggedRequests(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	cfg := sampleConfig(backend.URL, 1024, 1024)
+	cfg.Rules = []config.Rule{
+		{ID: "quote", Score: 5, Condition: &config.Condition{Target: "ARGS", Op: "contains", Value: "'"}},
+	}
+	policyCfg := cfg.Policies["default"]
+	policyCfg.AnomalyThreshold = 5
+	cfg.Policies["default"] = policyCfg
+
+	gw, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	dir := t.TempDir()
+	store, err := capture.Open(dir, config.CaptureConfig{})
+	if err != nil {
+		t.Fatalf("capture.Open error: %v", err)
+	}
+	gw.SetCaptureStore(store)
+	var logs bytes.Buffer
+	gw.SetDecisionLogger(logging.NewDecisionLogger(&logs))
+
+	post := func(target string) logging.Decision {
+		logs.Reset()
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader("comment=hello"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		gw.ServeHTTP(httptest.NewRecorder(), req)
+		var d logging.Decision
+		if err := json.Unmarshal(logs.Bytes(), &d); err != nil {
+			t.Fatalf("decode decision: %v", err)
+		}
+		return d
+	}
+
+	if d := post("http://example.com/search?q=shoes"); d.Captured {
+		t.Fatalf("expected clean request not to be captured, got %+v", d)
+	}
+	d := post("http://example.com/search?q=O'Brien")
+	if d.Action != "shadow" || !d.Captured {
+		t.Fatalf("expected shadow-flagged request to be captured, got %+v", d)
+	}
+	store.Close()
+	rec, err := capture.Load(dir, d.RequestID)
+	if err != nil {
+		t.Fatalf("capture.Load error: %v", err)
+	}
+	if rec.RequestLine != "POST /search?q=O'Brien HTTP/1.1" || string(rec.Body) != "comment=hello" {
+		t.Fatalf("unexpected capture %+v", rec)
+	}
+}
+
+func TestRecordRequestRedactsBody(t *testing.T) {
+	redactor, err := capture.NewRedactor(config.CaptureRedact{Params: []string{"ssn"}})
+	if err != nil {
+		t.Fatalf("NewRedactor error: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer secret")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+
+	rec := recordRequest(req, []byte("user=admin&password=hunter2&api_key=k1&ssn=1"), 0, redactor)
+	if string(rec.Body) != "user=admin&password=<redacted>&api_key=<redacted>&ssn=<redacted>" {
+		t.Fatalf("expected form secrets redacted, got %q", rec.Body)
+	}
+	if rec.Headers["Authorization"][0] != "<redacted>" || rec.Headers["Cookie"][0] != "session=<redacted>" {
+		t.Fatalf("expected credentials redacted, got %v", rec.Headers)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	rec = recordRequest(req, []byte(`{"user":{"name":"a","token":"t"}}`), 0, redactor)
+	if string(rec.Body) != `{"user":{"name":"a","token":"<redacted>"}}` {
+		t.Fatalf("expected nested JSON secret redacted, got %q", rec.Body)
+	}
+}
@@ -23,14 +23,37 @@ type Decision struct {
 	Policy             string              `json:"policy"`
 	Mode               string              `json:"mode"`
 	Score              int                 `json:"score"`
+	CategoryScores     map[string]int      `json:"category_scores,omitempty"`
+	Category           string              `json:"category,omitempty"`
 	Threshold          int                 `json:"threshold"`
 	Action             string              `json:"action"`
+	ActionRule         string              `json:"action_rule,omitempty"`
+	Tags               []string            `json:"tags,omitempty"`
+	TarpitMS           int64               `json:"tarpit_ms,omitempty"`
 	StatusCode         int                 `json:"status_code"`
 	MatchedRules       []MatchedRule       `json:"matched_rules"`
+	SuppressedRules    []SuppressedRule    `json:"suppressed_rules,omitempty"`
 	ContractViolations []ContractViolation `json:"contract_violations"`
+	ContractDrift      []ContractDrift     `json:"contract_drift,omitempty"`
+	BudgetExceeded     string              `json:"budget_exceeded,omitempty"`
 	RateLimited        bool                `json:"rate_limited"`
+	UpstreamTarget     string              `json:"upstream_target,omitempty"`
+	SkippedTargets     []string            `json:"skipped_targets,omitempty"`
 	DurationMS         int64               `json:"duration_ms"`
 	UpstreamMS         int64               `json:"upstream_ms"`
+	Request            *RecordedRequest    `json:"request,omitempty"`
+	// Captured reports that the full request was queued for the capture
+	// store under RequestID.
+	Captured bool `json:"captured,omitempty"`
+}
+
+// RecordedRequest is the part of a request the decision does not already
+// carry, recorded when logging.replay is enabled. Credentials in headers are
+// redacted and the body is capped.
+type RecordedRequest struct {
+	Headers       map[string][]string `json:"headers,omitempty"`
+	Body          []byte              `json:"body,omitempty"`
+	BodyTruncated bool                `json:"body_truncated,omitempty"`
 }
 
 type MatchedRule struct {
@@ -39,6 +62,18 @@ type MatchedRule struct {
 	Score    int      `json:"score"`
 	Tags     []string `json:"tags"`
 	Evidence string   `json:"evidence"`
+	Patterns []string `json:"patterns,omitempty"`
+	Action   string   `json:"action,omitempty"`
+}
+
+// SuppressedRule is a match a route or policy exclusion disabled, removed by
+// excluding a target, or rescored.
+type SuppressedRule struct {
+	ID        string `json:"id"`
+	Exclusion string `json:"exclusion"`
+	Effect    string `json:"effect"`
+	Score     int    `json:"score"`
+	Evidence  string `json:"evidence,omitempty"`
 }
 
 type ContractViolation struct {
@@ -46,6 +81,17 @@ type ContractViolation struct {
 	Field string `json:"field"`
 }
 
+// ContractDrift is a contract addition proposed or promoted while serving
+// the request.
+type ContractDrift struct {
+	Kind     string `json:"kind"`
+	Endpoint string `json:"endpoint,omitempty"`
+	Name     string `json:"name"`
+	State    string `json:"state"`
+	Count    int    `json:"count"`
+	Clients  int    `json:"clients"`
+}
+
 type DecisionLogger struct {
 	w io.Writer
 }
@@ -68,6 +114,7 @@ func OpenDecisionLog(path string) (*DecisionLogger, func() error, error) {
 
 func (l *DecisionLogger) Write(decision Decision) error {
 	decision.MatchedRules = sanitizeMatchedRules(decision.MatchedRules)
+	decision.SuppressedRules = sanitizeSuppressedRules(decision.SuppressedRules)
 
 	data, err := json.Marshal(decision)
 	if err != nil {
@@ -82,6 +129,29 @@ func sanitizeMatchedRules(rules []MatchedRule) []MatchedRule {
 		return nil
 	}
 	out := make([]MatchedRule, len(rules))
+	for i, rule := range rules {
+		out[i] = rule
+		if len(rule.Evidence) > maxEvidence {
+			out[i].Evidence = rule.Evidence[:maxEvidence]
+		}
+		if len(rule.Patterns) > 0 {
+			out[i].Patterns = make([]string, len(rule.Patterns))
+			for j, pattern := range rule.Patterns {
+				if len(pattern) > maxEvidence {
+					pattern = pattern[:maxEvidence]
+				}
+				out[i].Patterns[j] = pattern
+			}
+		}
+	}
+	return out
+}
+
+func sanitizeSuppressedRules(rules []SuppressedRule) []SuppressedRule {
+	if len(rules) == 0 {
+		return nil
+	}
+	out := make([]SuppressedRule, len(rules))
 	for i, rule := range rules {
 		out[i] = rule
 		if len(rule.Evidence) > maxEvidence {
@@ -0,0 +1,2 @@
+// Package modsec provides functionality for Klyr.
+package modsec
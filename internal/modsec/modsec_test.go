@@ -0,0 +1,133 @@
+package modsec
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/klyr/klyr/internal/config"
+	"github.com/klyr/klyr/internal/rules"
+	"gopkg.in/yaml.v3"
+)
+
+const sample = `# Example rules
+SecRuleEngine On
+
+SecRule ARGS|REQUEST_HEADERS:User-Agent "@rx (?i)union\s+select" \
+    "id:1001,phase:2,deny,status:403,t:none,t:lowercase,t:urlDecode,\
+    msg:'SQL injection, union based',tag:'attack-sqli',tag:'paranoia-level/2',severity:'CRITICAL'"
+
+SecRule REQUEST_COOKIES|!REQUEST_COOKIES:session "@pm nikto sqlmap" "id:1002,pass,severity:WARNING,tag:'scanner'"
+SecRule ARGS "@detectSQLi" "id:1003,block,severity:ERROR,t:removeWhitespace"
+SecRule REMOTE_ADDR "!@ipMatch 10.0.0.0/8,192.168.1.1" "id:1004,pass,severity:NOTICE,ctl:ruleEngine=Off"
+SecRule REQUEST_URI "@rx (?<=a)b" "id:1005,deny"
+SecRule ARGS "@rx x" "id:1006,chain,deny"
+  SecRule REQUEST_METHOD "@streq POST" "t:none"
+SecRule &ARGS "@gt 10" "id:1007,deny"
+SecRule XML:/* "@rx x" "id:1008,deny"
+SecRule ARGS "@within a b" "id:1009,deny"
+SecRule ARGS "@rx y" "phase:2,deny"
+SecRule ARGS:id "@rx ^\d+$" "id:1004,deny"
+`
+
+func TestTranslateSample(t *testing.T) {
+	directives, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	out, warnings := Translate(directives)
+
+	ids := make([]string, len(out))
+	for i, rule := range out {
+		ids[i] = rule.ID
+	}
+	if strings.Join(ids, ",") != "1001,1002,1003,1004" {
+		t.Fatalf("unexpected rules %v", ids)
+	}
+
+	first := out[0]
+	if first.Score != 5 || first.ParanoiaLevel != 2 || first.Action.Type != "block" || first.Action.StatusCode != 403 ||
+		first.Message != "SQL injection, union based" || strings.Join(first.Transforms, ",") != "lowercase" {
+		t.Fatalf("unexpected first rule %+v", first)
+	}
+	if c := first.Condition; c == nil || len(c.Any) != 2 || c.Any[0].Target != "ARGS" || c.Any[1].Target != "HEADERS:User-Agent" || c.Any[1].Value != `(?i)union\s+select` {
+		t.Fatalf("unexpected condition %+v", first.Condition)
+	}
+	if out[1].Score != 3 || out[1].Condition.Op != "contains" || len(out[1].Condition.Values) < 2 || out[1].Transforms[0] != "lowercase" {
+		t.Fatalf("unexpected pm rule %+v", out[1])
+	}
+	if out[2].Match.Type != "sqli" || out[2].Score != 4 {
+		t.Fatalf("unexpected detectSQLi rule %+v", out[2])
+	}
+	if c := out[3].Condition; c.Not == nil || c.Not.Values[1] != "192.168.1.1/32" {
+		t.Fatalf("unexpected ipMatch rule %+v", out[3].Condition)
+	}
+
+	want := []string{
+		"line 2: SecRuleEngine: directive not supported",
+		"line 8: SecRule: rule 1002: variable exclusion !REQUEST_COOKIES:session ignored",
+		"line 9: SecRule: rule 1003: transformation t:removeWhitespace ignored",
+		"line 10: SecRule: rule 1004: action ctl ignored",
+		"line 11: SecRule: rule 1005 skipped: regex is not RE2 compatible",
+		"line 12: SecRule: rule 1006 skipped: chained rules are not supported",
+		"line 13: SecRule: skipped: part of a chained rule",
+		"line 14: SecRule: rule 1007 skipped: counting variable &ARGS is not supported",
+		"line 15: SecRule: rule 1008 skipped: variable XML is not supported",
+		"line 16: SecRule: rule 1009 skipped: operator @within is not supported",
+		"line 17: SecRule: skipped: id is required",
+		"line 18: SecRule: rule 1004 skipped: duplicate id 1004",
+	}
+	if len(warnings) != len(want) {
+		t.Fatalf("expected %d warnings, got %d:\n%s", len(want), len(warnings), strings.Join(warnings, "\n"))
+	}
+	for i := range want {
+		if !strings.HasPrefix(warnings[i], want[i]) {
+			t.Errorf("warning %d = %q, want prefix %q", i, warnings[i], want[i])
+		}
+	}
+}
+
+func TestTranslateWarnsOnURIPatternSpanningQuery(t *testing.T) {
+	directives, err := Parse([]byte(`SecRule REQUEST_URI "@rx (?i)/admin\?debug=" "id:2001,deny"
+SecRule REQUEST_URI "@rx (?i)^/admin" "id:2002,deny"
+SecRule REQUEST_URI|ARGS "@contains ?debug=" "id:2003,deny"
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	out, warnings := Translate(directives)
+	if len(out) != 3 || len(warnings) != 2 || !strings.Contains(warnings[0], "rule 2001") || !strings.Contains(warnings[1], "rule 2003") {
+		t.Fatalf("expected warnings for rules 2001 and 2003, got %v", warnings)
+	}
+}
+
+func TestMarshalBuildsEngine(t *testing.T) {
+	directives, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	out, _ := Translate(directives)
+	data, err := Marshal(out)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var cfg config.Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("unmarshal: %v\n%s", err, data)
+	}
+	if len(cfg.Rules) != len(out) || strings.Contains(string(data), "patternsFile") {
+		t.Fatalf("unexpected yaml:\n%s", data)
+	}
+	engine, err := rules.BuildEngine(&cfg, "")
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	ctx := rules.EvalContext{RemoteAddr: "10.1.2.3", Header: map[string][]string{"User-Agent": {"x UNION  SELECT y"}}}
+	if result := engine.Evaluate(ctx); len(result.Matches) != 0 {
+		t.Fatalf("expected paranoia level 2 rule to be skipped, got %+v", result)
+	}
+	result := engine.EvaluateWith(ctx, rules.EvalOptions{ParanoiaLevel: 2})
+	if len(result.Matches) != 1 || result.Matches[0].RuleID != "1001" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
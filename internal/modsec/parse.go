@@ -0,0 +1,124 @@
+package modsec
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Directive is one configuration directive, with continuation lines joined.
+type Directive struct {
+	Line int
+	Name string
+	Args []string
+}
+
+func Load(path string) ([]Directive, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read modsec rules: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse splits a ModSecurity or Coraza rules file into directives. Lines
+// ending in a backslash continue on the next line and # starts a comment.
+func Parse(data []byte) ([]Directive, error) {
+	var directives []Directive
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		start := i + 1
+		line := strings.TrimSpace(lines[i])
+		for strings.HasSuffix(line, `\`) && i+1 < len(lines) {
+			i++
+			line = strings.TrimSuffix(line, `\`) + " " + strings.TrimSpace(lines[i])
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		args, err := splitArgs(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", start, err)
+		}
+		directives = append(directives, Directive{Line: start, Name: args[0], Args: args[1:]})
+	}
+	return directives, nil
+}
+
+// splitArgs splits on whitespace outside double quotes. Inside quotes only
+// \" is unescaped; other backslashes belong to the regex or value.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var b strings.Builder
+	inQuotes, inArg := false, false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inQuotes && c == '\\' && i+1 < len(line) && line[i+1] == '"':
+			b.WriteByte('"')
+			i++
+		case c == '"':
+			inQuotes = !inQuotes
+			inArg = true
+		case !inQuotes && (c == ' ' || c == '\t'):
+			if inArg {
+				args = append(args, b.String())
+				b.Reset()
+				inArg = false
+			}
+		default:
+			b.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inArg {
+		args = append(args, b.String())
+	}
+	return args, nil
+}
+
+type action struct {
+	name  string
+	value string
+}
+
+// splitActions parses an action list such as
+// "id:1,phase:2,t:none,msg:'a, b',tag:'x'".
+func splitActions(list string) []action {
+	var actions []action
+	var b strings.Builder
+	inQuotes := false
+	flush := func() {
+		item := strings.TrimSpace(b.String())
+		b.Reset()
+		if item == "" {
+			return
+		}
+		name, value, _ := strings.Cut(item, ":")
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		}
+		actions = append(actions, action{name: strings.ToLower(strings.TrimSpace(name)), value: value})
+	}
+	for i := 0; i < len(list); i++ {
+		c := list[i]
+		switch {
+		case c == '\\' && inQuotes && i+1 < len(list) && list[i+1] == '\'':
+			b.WriteByte('\'')
+			i++
+		case c == '\'':
+			inQuotes = !inQuotes
+			b.WriteByte(c)
+		case c == ',' && !inQuotes:
+			flush()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	flush()
+	return actions
+}
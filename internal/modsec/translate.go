@@ -0,0 +1,435 @@
+package modsec
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/klyr/klyr/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultScore is used for rules without a severity, which in practice are
+// deny rules.
+const defaultScore = 5
+
+// variables maps ModSecurity collections to condition targets.
+var variables = map[string][]string{
+	"ARGS":             {config.TargetArgs},
+	"ARGS_GET":         {config.TargetArgs},
+	"ARGS_POST":        {config.TargetArgs},
+	"ARGS_NAMES":       {config.TargetArgsNames},
+	"REQUEST_HEADERS":  {config.TargetHeaders},
+	"REQUEST_COOKIES":  {config.TargetCookies},
+	"REQUEST_URI":      {config.TargetPath, config.TargetQuery},
+	"REQUEST_URI_RAW":  {config.TargetPath, config.TargetQuery},
+	"REQUEST_FILENAME": {config.TargetPath},
+	"QUERY_STRING":     {config.TargetQuery},
+	"REQUEST_BODY":     {config.TargetBody},
+	"REQUEST_METHOD":   {config.TargetMethod},
+	"REMOTE_ADDR":      {config.TargetRemoteAddr},
+}
+
+// selectable collections accept a :name selector.
+var selectable = map[string]bool{"ARGS": true, "ARGS_GET": true, "ARGS_POST": true, "REQUEST_HEADERS": true, "REQUEST_COOKIES": true}
+
+// operators maps ModSecurity operators to condition operators.
+var operators = map[string]string{
+	"rx":         config.OpRegex,
+	"pm":         config.OpContains,
+	"contains":   config.OpContains,
+	"streq":      config.OpEquals,
+	"eq":         config.OpEquals,
+	"beginswith": config.OpStartsWith,
+	"endswith":   config.OpEndsWith,
+	"gt":         config.OpGreater,
+	"lt":         config.OpLess,
+	"ipmatch":    config.OpIPInCIDR,
+}
+
+// signals maps validation operators to normalization signals.
+var signals = map[string]string{
+	"validateurlencoding":  "invalid_encoding",
+	"validateutf8encoding": "invalid_utf8",
+}
+
+// transforms maps ModSecurity t: names to rule transforms. utf8toUnicode is
+// only used to expose fullwidth and other compatibility forms, which
+// unicode_nfkc folds directly.
+var transforms = map[string]string{
+	"lowercase":          "lowercase",
+	"htmlentitydecode":   "html_entity",
+	"normalisepath":      "normalize_path",
+	"normalizepath":      "normalize_path",
+	"base64decode":       "base64_decode",
+	"hexdecode":          "hex_decode",
+	"urldecodeuni":       "url_decode_uni",
+	"compresswhitespace": "compress_whitespace",
+	"removenulls":        "remove_nulls",
+	"replacecomments":    "replace_comments",
+	"cmdline":            "cmdline",
+	"jsdecode":           "js_decode",
+	"utf8tounicode":      "unicode_nfkc",
+}
+
+var severities = map[string]int{
+	"EMERGENCY": 5, "ALERT": 5, "CRITICAL": 5, "ERROR": 4, "WARNING": 3, "NOTICE": 2, "INFO": 1, "DEBUG": 1,
+	"0": 5, "1": 5, "2": 5, "3": 4, "4": 3, "5": 2, "6": 1, "7": 1,
+}
+
+// ignoredActions carry metadata or ModSecurity bookkeeping that has no
+// effect on a Klyr rule.
+var ignoredActions = map[string]bool{
+	"phase": true, "rev": true, "ver": true, "maturity": true, "accuracy": true,
+	"log": true, "nolog": true, "auditlog": true, "noauditlog": true, "logdata": true,
+	"capture": true, "setvar": true, "multimatch": true, "pass": true, "block": true,
+	"status": true,
+}
+
+// Translate converts the SecRule directives it supports into rules. Every
+// directive or part of one that is not translated is reported in the
+// returned warnings with its line number.
+func Translate(directives []Directive) ([]config.Rule, []string) {
+	var rules []config.Rule
+	var warnings []string
+	ids := map[string]bool{}
+	chained := false
+	for _, d := range directives {
+		warn := func(format string, args ...any) {
+			warnings = append(warnings, fmt.Sprintf("line %d: %s: ", d.Line, d.Name)+fmt.Sprintf(format, args...))
+		}
+		if d.Name != "SecRule" {
+			warn("directive not supported")
+			continue
+		}
+		if chained {
+			chained = hasChain(d)
+			warn("skipped: part of a chained rule")
+			continue
+		}
+		chained = hasChain(d)
+		rule, notes, err := translateRule(d)
+		if err == nil && ids[rule.ID] {
+			err = fmt.Errorf("duplicate id %s", rule.ID)
+		}
+		if err != nil {
+			if rule.ID != "" {
+				warn("rule %s skipped: %v", rule.ID, err)
+			} else {
+				warn("skipped: %v", err)
+			}
+			continue
+		}
+		for _, note := range notes {
+			warn("rule %s: %s", rule.ID, note)
+		}
+		ids[rule.ID] = true
+		rules = append(rules, rule)
+	}
+	return rules, warnings
+}
+
+func hasChain(d Directive) bool {
+	if len(d.Args) < 3 {
+		return false
+	}
+	for _, a := range splitActions(d.Args[2]) {
+		if a.name == "chain" {
+			return true
+		}
+	}
+	return false
+}
+
+func translateRule(d Directive) (config.Rule, []string, error) {
+	if len(d.Args) < 2 || len(d.Args) > 3 {
+		return config.Rule{}, nil, fmt.Errorf("expected variables, operator and actions")
+	}
+	var rule config.Rule
+	var notes []string
+	if len(d.Args) == 3 {
+		var err error
+		notes, err = applyActions(&rule, splitActions(d.Args[2]))
+		if err != nil {
+			return rule, nil, err
+		}
+	}
+	if rule.ID == "" {
+		return rule, nil, fmt.Errorf("id is required")
+	}
+
+	targets, phase, excluded, err := translateVariables(d.Args[0])
+	if err != nil {
+		return rule, nil, err
+	}
+	for _, name := range excluded {
+		notes = append(notes, fmt.Sprintf("variable exclusion %s ignored", name))
+	}
+
+	negated, name, arg := splitOperator(d.Args[1])
+	switch {
+	case name == "detectsqli" || name == "detectxss":
+		if negated {
+			return rule, nil, fmt.Errorf("negated @%s is not supported", name)
+		}
+		rule.Phase = phase
+		rule.Match = config.RuleMatch{Type: strings.TrimPrefix(name, "detect")}
+	case signals[name] != "":
+		if negated {
+			return rule, nil, fmt.Errorf("negated @%s is not supported", name)
+		}
+		rule.Phase = phase
+		rule.Match = config.RuleMatch{Type: "signal", Signals: []string{signals[name]}}
+	case operators[name] != "":
+		leaf, err := leafCondition(name, operators[name], arg)
+		if err != nil {
+			return rule, nil, err
+		}
+		if name == "pm" && !contains(rule.Transforms, "lowercase") {
+			// @pm is case-insensitive.
+			rule.Transforms = append(rule.Transforms, "lowercase")
+		}
+		if spansQuery(d.Args[0], name, arg) {
+			notes = append(notes, "REQUEST_URI is matched as separate PATH and QUERY targets; a pattern spanning ? never matches")
+		}
+		rule.Condition = combine(targets, leaf, negated)
+	default:
+		return rule, nil, fmt.Errorf("operator @%s is not supported", name)
+	}
+	return rule, notes, nil
+}
+
+func applyActions(rule *config.Rule, actions []action) ([]string, error) {
+	var notes []string
+	rule.Score = defaultScore
+	for _, a := range actions {
+		switch a.name {
+		case "id":
+			rule.ID = a.value
+		case "msg":
+			rule.Message = a.value
+		case "tag":
+			if level, ok := strings.CutPrefix(a.value, "paranoia-level/"); ok {
+				if n, err := strconv.Atoi(level); err == nil && n >= 1 && n <= config.MaxParanoiaLevel {
+					rule.ParanoiaLevel = n
+				}
+			}
+			rule.Tags = append(rule.Tags, a.value)
+		case "severity":
+			score, ok := severities[strings.ToUpper(a.value)]
+			if !ok {
+				notes = append(notes, fmt.Sprintf("unknown severity %q scored %d", a.value, defaultScore))
+				continue
+			}
+			rule.Score = score
+		case "t":
+			name := strings.ToLower(a.value)
+			switch {
+			case name == "none":
+				rule.Transforms = nil
+			case name == "urldecode":
+				// Rule inputs are always URL-decoded.
+			case transforms[name] != "":
+				if !contains(rule.Transforms, transforms[name]) {
+					rule.Transforms = append(rule.Transforms, transforms[name])
+				}
+			default:
+				notes = append(notes, fmt.Sprintf("transformation t:%s ignored", a.value))
+			}
+		case "deny", "drop":
+			rule.Action.Type = config.RuleActionBlock
+		case "allow":
+			rule.Action.Type = config.RuleActionAllow
+		case "redirect":
+			rule.Action = config.RuleAction{Type: config.RuleActionRedirect, Location: a.value}
+		case "chain":
+			return notes, fmt.Errorf("chained rules are not supported")
+		default:
+			if !ignoredActions[a.name] {
+				notes = append(notes, fmt.Sprintf("action %s ignored", a.name))
+			}
+		}
+	}
+	if rule.Action.Type == config.RuleActionBlock {
+		for _, a := range actions {
+			if a.name == "status" {
+				if code, err := strconv.Atoi(a.value); err == nil {
+					rule.Action.StatusCode = code
+				}
+			}
+		}
+	}
+	return notes, nil
+}
+
+// translateVariables returns the condition targets of a variable list and
+// the phase a match type rule should inspect. Exclusions such as
+// !ARGS:password are returned separately.
+// spansQuery reports whether a pattern on REQUEST_URI contains a literal ?,
+// which cannot match once the URI is split into path and query.
+func spansQuery(vars, op, arg string) bool {
+	uri := false
+	for _, item := range strings.Split(vars, "|") {
+		name, _, _ := strings.Cut(strings.TrimSpace(item), ":")
+		if n := strings.ToUpper(name); n == "REQUEST_URI" || n == "REQUEST_URI_RAW" {
+			uri = true
+		}
+	}
+	if !uri {
+		return false
+	}
+	if op == "rx" {
+		// An unescaped ? is a quantifier or a (?flags) group.
+		return strings.Contains(arg, `\?`) || strings.Contains(arg, "[?")
+	}
+	return strings.Contains(arg, "?")
+}
+
+func translateVariables(list string) ([]string, string, []string, error) {
+	var targets, excluded []string
+	phases := map[string]bool{}
+	for _, item := range strings.Split(list, "|") {
+		item = strings.TrimSpace(item)
+		if strings.HasPrefix(item, "!") {
+			excluded = append(excluded, item)
+			continue
+		}
+		if strings.HasPrefix(item, "&") {
+			return nil, "", nil, fmt.Errorf("counting variable %s is not supported", item)
+		}
+		name, selector, hasSelector := strings.Cut(item, ":")
+		name = strings.ToUpper(name)
+		mapped, ok := variables[name]
+		if !ok {
+			return nil, "", nil, fmt.Errorf("variable %s is not supported", name)
+		}
+		if hasSelector {
+			if !selectable[name] {
+				return nil, "", nil, fmt.Errorf("variable %s does not take a selector", name)
+			}
+			if strings.HasPrefix(selector, "/") {
+				return nil, "", nil, fmt.Errorf("regex selector %s is not supported", item)
+			}
+			mapped = []string{mapped[0] + ":" + selector}
+		}
+		for _, target := range mapped {
+			if !contains(targets, target) {
+				targets = append(targets, target)
+			}
+		}
+		phases[phaseOf(name)] = true
+	}
+	if len(targets) == 0 {
+		return nil, "", nil, fmt.Errorf("no supported variables")
+	}
+	phase := ""
+	if len(phases) == 1 {
+		for p := range phases {
+			phase = p
+		}
+	}
+	return targets, phase, excluded, nil
+}
+
+// phaseOf is the phase a detector rule checks for a collection; arguments
+// may come from the query or a form body, so they check every phase.
+func phaseOf(name string) string {
+	switch name {
+	case "ARGS_GET", "QUERY_STRING":
+		return "query"
+	case "REQUEST_HEADERS", "REQUEST_COOKIES":
+		return "headers"
+	case "REQUEST_BODY", "ARGS_POST":
+		return "body"
+	case "REQUEST_URI", "REQUEST_URI_RAW", "REQUEST_FILENAME", "REQUEST_METHOD":
+		return "request_line"
+	default:
+		return ""
+	}
+}
+
+func splitOperator(op string) (bool, string, string) {
+	negated := strings.HasPrefix(op, "!")
+	op = strings.TrimPrefix(op, "!")
+	if !strings.HasPrefix(op, "@") {
+		return negated, "rx", op
+	}
+	name, arg, _ := strings.Cut(op[1:], " ")
+	return negated, strings.ToLower(name), strings.TrimSpace(arg)
+}
+
+func leafCondition(name, op, arg string) (config.Condition, error) {
+	leaf := config.Condition{Op: op}
+	switch {
+	case op == config.OpRegex:
+		if _, err := regexp.Compile(arg); err != nil {
+			return leaf, fmt.Errorf("regex is not RE2 compatible: %v", err)
+		}
+		leaf.Value = arg
+	case name == "pm":
+		// @pm takes a space-separated list of phrases, any of which matches.
+		leaf.Values = strings.Fields(arg)
+	case op == config.OpIPInCIDR:
+		for _, item := range strings.Split(arg, ",") {
+			item = strings.TrimSpace(item)
+			if ip := net.ParseIP(item); ip != nil {
+				if ip.To4() != nil {
+					item += "/32"
+				} else {
+					item += "/128"
+				}
+			}
+			leaf.Values = append(leaf.Values, item)
+		}
+	default:
+		leaf.Value = arg
+	}
+	return leaf, nil
+}
+
+// combine tests leaf against every target. A negated operator matches when
+// any target does not match, as in ModSecurity.
+func combine(targets []string, leaf config.Condition, negated bool) *config.Condition {
+	conditions := make([]config.Condition, 0, len(targets))
+	for _, target := range targets {
+		c := leaf
+		c.Target = target
+		if negated {
+			conditions = append(conditions, config.Condition{Not: &c})
+		} else {
+			conditions = append(conditions, c)
+		}
+	}
+	if len(conditions) == 1 {
+		return &conditions[0]
+	}
+	return &config.Condition{Any: conditions}
+}
+
+// Marshal renders rules as the rules section of a Klyr config.
+func Marshal(rules []config.Rule) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(struct {
+		Rules []config.Rule `yaml:"rules"`
+	}{rules}); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func contains(items []string, item string) bool {
+	for _, candidate := range items {
+		if candidate == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package normalize
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Flags records evasion signals seen while decoding an input.
+type Flags uint8
+
+const (
+	FlagDoubleEncoded Flags = 1 << iota
+	FlagInvalidEncoding
+	FlagOverlongUTF8
+	FlagInvalidUTF8
+	FlagNullByte
+	FlagMixedSeparators
+)
+
+var flagNames = []struct {
+	flag Flags
+	name string
+}{
+	{FlagDoubleEncoded, "double_encoded"},
+	{FlagInvalidEncoding, "invalid_encoding"},
+	{FlagOverlongUTF8, "overlong_utf8"},
+	{FlagInvalidUTF8, "invalid_utf8"},
+	{FlagNullByte, "null_byte"},
+	{FlagMixedSeparators, "mixed_separators"},
+}
+
+// ParseFlag returns the flag with the given name.
+func ParseFlag(name string) (Flags, bool) {
+	for _, f := range flagNames {
+		if f.name == name {
+			return f.flag, true
+		}
+	}
+	return 0, false
+}
+
+func (f Flags) Has(flag Flags) bool {
+	return f&flag != 0
+}
+
+// Names lists the set flags in a fixed order.
+func (f Flags) Names() []string {
+	var out []string
+	for _, item := range flagNames {
+		if f.Has(item.flag) {
+			out = append(out, item.name)
+		}
+	}
+	return out
+}
+
+// inspect flags properties of URL-decoded input.
+func inspect(decoded string) Flags {
+	var flags Flags
+	if strings.IndexByte(decoded, 0) >= 0 {
+		flags |= FlagNullByte
+	}
+	if strings.IndexByte(decoded, '\\') >= 0 && strings.IndexByte(decoded, '/') >= 0 {
+		flags |= FlagMixedSeparators
+	}
+	if !utf8.ValidString(decoded) {
+		flags |= FlagInvalidUTF8
+		if hasOverlong(decoded) {
+			flags |= FlagOverlongUTF8
+		}
+	}
+	return flags
+}
+
+// hasOverlong reports UTF-8 sequences that encode a code point in more bytes
+// than needed, such as C0 AF for "/".
+func hasOverlong(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == 0xC0 || c == 0xC1 {
+			return true
+		}
+		if i+1 < len(s) {
+			next := s[i+1]
+			if c == 0xE0 && next >= 0x80 && next <= 0x9F {
+				return true
+			}
+			if c == 0xF0 && next >= 0x80 && next <= 0x8F {
+				return true
+			}
+		}
+	}
+	return false
+}
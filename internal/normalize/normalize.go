@@ -6,16 +6,33 @@ import (
 	"strings"
 )
 
+// Options selects the transforms Apply runs. They always run in the order of
+// the fields below, whatever order a rule lists them in. A step may not grow
+// its input past MaxOutputBytes (DefaultMaxOutput when zero); the input
+// itself is never cut, so padding cannot push a payload out of view.
 type Options struct {
 	MaxDecodeDepth int
-	Lowercase      bool
-	HTMLEntity     bool
-	NormalizePath  bool
+	MaxOutputBytes int
+
+	URLDecodeUni       bool
+	Base64Decode       bool
+	HexDecode          bool
+	JSDecode           bool
+	NormalizePath      bool
+	HTMLEntity         bool
+	UnicodeNFKC        bool
+	FoldLookalikes     bool
+	RemoveNulls        bool
+	ReplaceComments    bool
+	Cmdline            bool
+	CompressWhitespace bool
+	Lowercase          bool
 }
 
 type Result struct {
 	Raw        string
 	Normalized string
+	Flags      Flags
 }
 
 func Apply(input string, opts Options) Result {
@@ -25,31 +42,75 @@ func Apply(input string, opts Options) Result {
 	if depth <= 0 {
 		depth = 2
 	}
+	max := opts.MaxOutputBytes
+	if max <= 0 {
+		max = DefaultMaxOutput
+	}
 
 	decoded := res.Normalized
+	if opts.URLDecodeUni {
+		decoded = DecodeURLUnicode(decoded)
+	}
+	decodes := 0
 	for i := 0; i < depth; i++ {
 		next, ok := decodeOnce(decoded)
-		if !ok || next == decoded {
+		if !ok {
+			res.Flags |= FlagInvalidEncoding
+			break
+		}
+		if next == decoded {
 			break
 		}
 		decoded = next
+		decodes++
+	}
+	if decodes == depth {
+		// Still encoded after the last pass counts as layered encoding too.
+		if next, ok := decodeOnce(decoded); ok && next != decoded {
+			decodes++
+		}
 	}
+	if decodes > 1 {
+		res.Flags |= FlagDoubleEncoded
+	}
+	res.Flags |= inspect(decoded)
 
 	res.Normalized = decoded
 
-	if opts.NormalizePath {
-		res.Normalized = NormalizePath(res.Normalized)
-	}
-	if opts.HTMLEntity {
-		res.Normalized = html.UnescapeString(res.Normalized)
+	steps := []struct {
+		enabled bool
+		apply   func(string) string
+	}{
+		{opts.Base64Decode, DecodeBase64},
+		{opts.HexDecode, DecodeHex},
+		{opts.JSDecode, DecodeJS},
+		{opts.NormalizePath, NormalizePath},
+		{opts.HTMLEntity, html.UnescapeString},
+		{opts.UnicodeNFKC, NFKC},
+		{opts.FoldLookalikes, FoldLookalikes},
+		{opts.RemoveNulls, RemoveNulls},
+		{opts.ReplaceComments, ReplaceComments},
+		{opts.Cmdline, Cmdline},
+		{opts.CompressWhitespace, CompressWhitespace},
+		{opts.Lowercase, strings.ToLower},
 	}
-	if opts.Lowercase {
-		res.Normalized = strings.ToLower(res.Normalized)
+	for _, step := range steps {
+		if step.enabled {
+			res.Normalized = bounded(step.apply, res.Normalized, max)
+		}
 	}
 
 	return res
 }
 
+// bounded runs a transform step, cutting only output it added beyond max.
+func bounded(apply func(string) string, input string, max int) string {
+	if len(input) > max {
+		max = len(input)
+	}
+	return limit(apply(input), max)
+}
+
 func decodeOnce(input string) (string, bool) {
 	decoded, err := url.PathUnescape(input)
 	if err != nil {
@@ -1,6 +1,9 @@
 package normalize
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestApplyDecodeDepth(t *testing.T) {
 	res := Apply("%252e%252e%252f", Options{MaxDecodeDepth: 1})
@@ -45,3 +48,97 @@ func TestNormalizePath(t *testing.T) {
 		}
 	}
 }
+
+func TestTransforms(t *testing.T) {
+	cases := []struct {
+		name  string
+		fn    func(string) string
+		input string
+		want  string
+	}{
+		{"uni escape", DecodeURLUnicode, "%u003Cscript%u003e", "<script>"},
+		{"uni invalid", DecodeURLUnicode, "%u00zz%u12", "%u00zz%u12"},
+		{"base64 token", DecodeBase64, "q=PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==", "q=<script>alert(1)</script>"},
+		{"base64 url alphabet", DecodeBase64, "x=dW5pb24gc2VsZWN0IC0tIC0_", "x=union select -- -?"},
+		{"base64 short word", DecodeBase64, "name=admin", "name=admin"},
+		{"base64 binary kept", DecodeBase64, "id=AAECAwQFBgcI", "id=AAECAwQFBgcI"},
+		{"hex escapes", DecodeHex, `\x3cscript\x3e`, "<script>"},
+		{"hex prefixed", DecodeHex, "select 0x61646d696e", "select admin"},
+		{"hex bare", DecodeHex, "q=3c7363726970743e", "q=<script>"},
+		{"hex in word kept", DecodeHex, "user_deadbeefcafe", "user_deadbeefcafe"},
+		{"js escapes", DecodeJS, `alert\x28\u{31}\051\n`, "alert(1)\n"},
+		{"js invalid kept", DecodeJS, `\uZZZZ\`, `\uZZZZ\`},
+		{"nfkc fullwidth", NFKC, "＜ｓｃｒｉｐｔ＞", "<script>"},
+		{"nfkc ligature and circled", NFKC, "ﬁle ①", "file 1"},
+		{"nfkc composes", NFKC, "cafe\u0301", "café"},
+		{"lookalike fullwidth", FoldLookalikes, "＜ｓｃｒｉｐｔ＞", "<script>"},
+		{"lookalike math and spaces", FoldLookalikes, "𝐬𝐞𝐥𝐞𝐜𝐭　１", "select 1"},
+		{"lookalike small forms", FoldLookalikes, "﹤svg﹥ ﬁle", "<svg> file"},
+		{"compress whitespace", CompressWhitespace, "union \t\n  select", "union select"},
+		{"remove nulls", RemoveNulls, "sel\x00ect", "select"},
+		{"replace comments", ReplaceComments, "union/**/select/*x*/1/* open", "union select 1 "},
+		{"cmdline", Cmdline, `C^a"t /E"T'C/pa\sswd ,  ( id;ls`, "cat/etc/passwd( id ls"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.fn(tc.input); got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestApplyPipelineOrderAndBound(t *testing.T) {
+	opts := Options{URLDecodeUni: true, Base64Decode: true, ReplaceComments: true, CompressWhitespace: true, Lowercase: true}
+	// base64("UNION/**/  SELECT") behind a %u escape for the quote.
+	res := Apply("%u0027VU5JT04vKiovICBTRUxFQ1Q=", opts)
+	if res.Normalized != "'union select" {
+		t.Fatalf("unexpected pipeline output %q", res.Normalized)
+	}
+
+	// normalize_path runs before html_entity, as it always has.
+	res = Apply("a/./b&#47;..&#47;c", Options{NormalizePath: true, HTMLEntity: true})
+	if res.Normalized != "a/b/../c" {
+		t.Fatalf("unexpected path and entity order %q", res.Normalized)
+	}
+
+	padded := strings.Repeat("a", DefaultMaxOutput) + "<script>"
+	res = Apply(padded, Options{})
+	if res.Normalized != padded {
+		t.Fatalf("expected input past the output bound to be kept, got %d bytes", len(res.Normalized))
+	}
+
+	// Lowercasing Ⱥ takes two bytes to three; growth is cut on a rune boundary.
+	res = Apply(strings.Repeat("Ⱥ", 10), Options{Lowercase: true, MaxOutputBytes: 5})
+	if res.Normalized != strings.Repeat("ⱥ", 6) {
+		t.Fatalf("expected growth cut on a rune boundary, got %q", res.Normalized)
+	}
+}
+
+func TestApplyFlags(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"plain", "q=hello&page=2", nil},
+		{"single encoded", "q=%3Cscript%3E", nil},
+		{"double encoded", "%252e%252e%252f", []string{"double_encoded"}},
+		{"triple encoded", "%25252e", []string{"double_encoded"}},
+		{"invalid escape", "q=100%zz", []string{"invalid_encoding"}},
+		{"overlong slash", "..%c0%af..%c0%afetc", []string{"overlong_utf8", "invalid_utf8"}},
+		{"invalid utf8", "name=%ff%fe", []string{"invalid_utf8"}},
+		{"nul byte", "file=a.php%00.png", []string{"null_byte"}},
+		{"mixed separators", "..\\../etc/passwd", []string{"mixed_separators"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Apply(tc.input, Options{MaxDecodeDepth: 2}).Flags.Names()
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
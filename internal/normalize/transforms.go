@@ -0,0 +1,400 @@
+package normalize
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultMaxOutput bounds how far a transform step may grow its input.
+const DefaultMaxOutput = 1 << 20
+
+// minEncodedRun is the shortest base64 or bare hex run that is decoded, so
+// ordinary short words are left alone.
+const minEncodedRun = 8
+
+func limit(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
+
+// DecodeURLUnicode decodes IIS-style %uXXXX escapes.
+func DecodeURLUnicode(input string) string {
+	if !strings.Contains(input, "%u") && !strings.Contains(input, "%U") {
+		return input
+	}
+	var b strings.Builder
+	for i := 0; i < len(input); i++ {
+		if input[i] == '%' && i+5 < len(input) && (input[i+1] == 'u' || input[i+1] == 'U') {
+			if v, err := strconv.ParseUint(input[i+2:i+6], 16, 32); err == nil {
+				b.WriteRune(rune(v))
+				i += 5
+				continue
+			}
+		}
+		b.WriteByte(input[i])
+	}
+	return b.String()
+}
+
+// DecodeBase64 replaces base64 runs that decode to printable text.
+func DecodeBase64(input string) string {
+	var b strings.Builder
+	i := 0
+	for i < len(input) {
+		if !isBase64Char(input[i]) {
+			b.WriteByte(input[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(input) && isBase64Char(input[j]) {
+			j++
+		}
+		end := j
+		for end < len(input) && end-j < 2 && input[end] == '=' {
+			end++
+		}
+		run := input[i:end]
+		if decoded, ok := decodeBase64Run(run); ok {
+			b.WriteString(decoded)
+		} else {
+			b.WriteString(run)
+		}
+		i = end
+	}
+	return b.String()
+}
+
+func isBase64Char(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+		c == '+' || c == '/' || c == '-' || c == '_'
+}
+
+func decodeBase64Run(run string) (string, bool) {
+	trimmed := strings.TrimRight(run, "=")
+	if len(trimmed) < minEncodedRun {
+		return "", false
+	}
+	encoding := base64.RawStdEncoding
+	if strings.ContainsAny(trimmed, "-_") {
+		encoding = base64.RawURLEncoding
+	}
+	decoded, err := encoding.DecodeString(trimmed)
+	if err != nil || !printable(decoded) {
+		return "", false
+	}
+	return string(decoded), true
+}
+
+// DecodeHex decodes \xHH escapes, 0x-prefixed hex runs and bare hex runs
+// that decode to printable text.
+func DecodeHex(input string) string {
+	var b strings.Builder
+	i := 0
+	for i < len(input) {
+		switch {
+		case input[i] == '\\' && i+3 < len(input) && input[i+1] == 'x' && isHex(input[i+2]) && isHex(input[i+3]):
+			v, _ := strconv.ParseUint(input[i+2:i+4], 16, 8)
+			b.WriteByte(byte(v))
+			i += 4
+			continue
+		case input[i] == '0' && i+1 < len(input) && (input[i+1] == 'x' || input[i+1] == 'X'):
+			j := i + 2
+			for j < len(input) && isHex(input[j]) {
+				j++
+			}
+			if run := input[i+2 : j]; len(run) >= 2 && len(run)%2 == 0 {
+				decoded, _ := hex.DecodeString(run)
+				b.Write(decoded)
+				i = j
+				continue
+			}
+		case isHex(input[i]) && (i == 0 || !isWordChar(input[i-1])):
+			j := i
+			for j < len(input) && isHex(input[j]) {
+				j++
+			}
+			run := input[i:j]
+			if len(run) >= minEncodedRun && len(run)%2 == 0 && (j == len(input) || !isWordChar(input[j])) {
+				if decoded, err := hex.DecodeString(run); err == nil && printable(decoded) {
+					b.Write(decoded)
+					i = j
+					continue
+				}
+			}
+			b.WriteString(run)
+			i = j
+			continue
+		}
+		b.WriteByte(input[i])
+		i++
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func isWordChar(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func printable(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if r != '\t' && r != '\n' && r != '\r' && !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// DecodeJS decodes JavaScript string escapes: \xHH, \uHHHH, \u{H...},
+// octal \OOO and the single-character escapes.
+func DecodeJS(input string) string {
+	if !strings.Contains(input, `\`) {
+		return input
+	}
+	var b strings.Builder
+	for i := 0; i < len(input); i++ {
+		if input[i] != '\\' || i+1 == len(input) {
+			b.WriteByte(input[i])
+			continue
+		}
+		next := input[i+1]
+		switch {
+		case next == 'x' && i+3 < len(input) && isHex(input[i+2]) && isHex(input[i+3]):
+			v, _ := strconv.ParseUint(input[i+2:i+4], 16, 8)
+			b.WriteRune(rune(v))
+			i += 3
+		case next == 'u' && i+2 < len(input) && input[i+2] == '{':
+			end := strings.IndexByte(input[i+3:], '}')
+			if end < 1 || end > 6 {
+				b.WriteByte('\\')
+				continue
+			}
+			v, err := strconv.ParseUint(input[i+3:i+3+end], 16, 32)
+			if err != nil || v > unicode.MaxRune {
+				b.WriteByte('\\')
+				continue
+			}
+			b.WriteRune(rune(v))
+			i += 3 + end
+		case next == 'u' && i+5 < len(input):
+			v, err := strconv.ParseUint(input[i+2:i+6], 16, 32)
+			if err != nil {
+				b.WriteByte('\\')
+				continue
+			}
+			b.WriteRune(rune(v))
+			i += 5
+		case next >= '0' && next <= '7':
+			j := i + 1
+			for j < len(input) && j < i+4 && input[j] >= '0' && input[j] <= '7' {
+				j++
+			}
+			v, _ := strconv.ParseUint(input[i+1:j], 8, 16)
+			if v > 0xff {
+				j--
+				v >>= 3
+			}
+			b.WriteRune(rune(v))
+			i = j - 1
+		default:
+			b.WriteByte(jsEscape(next))
+			i++
+		}
+	}
+	return b.String()
+}
+
+func jsEscape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	case 'b':
+		return '\b'
+	case 'f':
+		return '\f'
+	case 'v':
+		return '\v'
+	default:
+		return c
+	}
+}
+
+// NFKC applies Unicode compatibility normalization (NFKC).
+func NFKC(input string) string {
+	for i := 0; i < len(input); i++ {
+		if input[i] >= utf8.RuneSelf {
+			return norm.NFKC.String(input)
+		}
+	}
+	return input
+}
+
+// FoldLookalikes applies the NFKC compatibility mappings used to disguise
+// ASCII: fullwidth forms, small form variants, Unicode spaces,
+// circled and mathematical letters, super- and subscript digits and the
+// Latin ligatures. It is not a complete NFKC implementation.
+func FoldLookalikes(input string) string {
+	ascii := true
+	for i := 0; i < len(input); i++ {
+		if input[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return input
+	}
+
+	var b strings.Builder
+	for _, r := range input {
+		if s, ok := foldRune(r); ok {
+			b.WriteString(s)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+var smallForms = map[rune]string{
+	0xFE50: ",", 0xFE51: ",", 0xFE52: ".", 0xFE54: ";", 0xFE55: ":", 0xFE56: "?",
+	0xFE57: "!", 0xFE59: "(", 0xFE5A: ")", 0xFE5B: "{", 0xFE5C: "}", 0xFE5F: "#",
+	0xFE60: "&", 0xFE61: "*", 0xFE62: "+", 0xFE63: "-", 0xFE64: "<", 0xFE65: ">",
+	0xFE66: "=", 0xFE68: `\`, 0xFE69: "$", 0xFE6A: "%", 0xFE6B: "@",
+}
+
+var ligatures = map[rune]string{
+	0xFB00: "ff", 0xFB01: "fi", 0xFB02: "fl", 0xFB03: "ffi", 0xFB04: "ffl", 0xFB05: "st", 0xFB06: "st",
+}
+
+var scriptDigits = map[rune]string{
+	0x2070: "0", 0x00B9: "1", 0x00B2: "2", 0x00B3: "3", 0x2074: "4", 0x2075: "5",
+	0x2076: "6", 0x2077: "7", 0x2078: "8", 0x2079: "9",
+}
+
+func foldRune(r rune) (string, bool) {
+	switch {
+	case r >= 0xFF01 && r <= 0xFF5E:
+		return string(r - 0xFF01 + '!'), true
+	case r == 0x3000 || r == 0x00A0 || r >= 0x2000 && r <= 0x200A || r == 0x202F || r == 0x205F:
+		return " ", true
+	case r >= 0x2080 && r <= 0x2089:
+		return string(r - 0x2080 + '0'), true
+	case r >= 0x24B6 && r <= 0x24CF:
+		return string(r - 0x24B6 + 'A'), true
+	case r >= 0x24D0 && r <= 0x24E9:
+		return string(r - 0x24D0 + 'a'), true
+	case r >= 0x1D400 && r <= 0x1D6A3:
+		// Mathematical alphanumeric letters repeat A-Z a-z in 52-rune styles.
+		offset := (r - 0x1D400) % 52
+		if offset < 26 {
+			return string('A' + offset), true
+		}
+		return string('a' + offset - 26), true
+	case r >= 0x1D7CE && r <= 0x1D7FF:
+		return string('0' + (r-0x1D7CE)%10), true
+	}
+	if s, ok := smallForms[r]; ok {
+		return s, true
+	}
+	if s, ok := ligatures[r]; ok {
+		return s, true
+	}
+	if s, ok := scriptDigits[r]; ok {
+		return s, true
+	}
+	return "", false
+}
+
+// CompressWhitespace replaces every run of whitespace with one space.
+func CompressWhitespace(input string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range input {
+		if unicode.IsSpace(r) {
+			if !space {
+				b.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func RemoveNulls(input string) string {
+	return strings.ReplaceAll(input, "\x00", "")
+}
+
+// ReplaceComments replaces each /* ... */ comment, including an unterminated
+// one at the end, with a single space.
+func ReplaceComments(input string) string {
+	if !strings.Contains(input, "/*") {
+		return input
+	}
+	var b strings.Builder
+	for {
+		start := strings.Index(input, "/*")
+		if start < 0 {
+			b.WriteString(input)
+			break
+		}
+		b.WriteString(input[:start])
+		b.WriteByte(' ')
+		end := strings.Index(input[start+2:], "*/")
+		if end < 0 {
+			break
+		}
+		input = input[start+2+end+2:]
+	}
+	return b.String()
+}
+
+// Cmdline undoes common shell obfuscation: it drops \ " ' and ^, turns , and ;
+// into spaces, compresses whitespace, drops spaces before / and ( and
+// lowercases the result.
+func Cmdline(input string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range input {
+		switch {
+		case r == '\\' || r == '"' || r == '\'' || r == '^':
+			continue
+		case r == ',' || r == ';' || unicode.IsSpace(r):
+			space = true
+			continue
+		case r == '/' || r == '(':
+			space = false
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -3,9 +3,12 @@ package observability
 import (
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
+	"github.com/klyr/klyr/internal/contract"
 	"github.com/klyr/klyr/internal/logging"
+	"github.com/klyr/klyr/internal/upstream"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -17,6 +20,13 @@ type Metrics struct {
 	contractViolationsTotal *prometheus.CounterVec
 	ratelimitHitsTotal      *prometheus.CounterVec
 	requestDuration         *prometheus.HistogramVec
+	configReloadsTotal      *prometheus.CounterVec
+	configGeneration        prometheus.Gauge
+	configLastReloadSuccess prometheus.Gauge
+	contractDriftTotal      *prometheus.CounterVec
+	budgetExceededTotal     *prometheus.CounterVec
+	upstreams               *upstreamCollector
+	drift                   *driftCollector
 }
 
 func NewMetrics(reg prometheus.Registerer) *Metrics {
@@ -49,6 +59,32 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 			},
 			[]string{"route", "policy"},
 		),
+		configReloadsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "klyr_config_reloads_total", Help: "Total configuration reload attempts"},
+			[]string{"result"},
+		),
+		configGeneration: prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "klyr_config_generation", Help: "Generation of the configuration currently serving traffic"},
+		),
+		configLastReloadSuccess: prometheus.NewGauge(
+			prometheus.GaugeOpts{Name: "klyr_config_last_reload_success", Help: "Whether the last configuration reload succeeded (1) or failed (0)"},
+		),
+		contractDriftTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "klyr_contract_drift_total", Help: "Total contract additions proposed or promoted"},
+			[]string{"route", "policy", "state"},
+		),
+		budgetExceededTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "klyr_inspection_budget_exceeded_total", Help: "Total requests whose rules exceeded the inspection budget"},
+			[]string{"route", "policy", "reason"},
+		),
+		drift: &driftCollector{
+			candidates: prometheus.NewDesc("klyr_contract_drift_candidates", "Contract drift candidates per state", []string{"route", "policy", "state"}, nil),
+		},
+		upstreams: &upstreamCollector{
+			healthy: prometheus.NewDesc("klyr_upstream_target_healthy", "Whether an upstream target is eligible for traffic (1) or skipped (0)", []string{"upstream", "target"}, nil),
+			ejected: prometheus.NewDesc("klyr_upstream_target_ejected", "Whether an upstream target is passively ejected (1) or not (0)", []string{"upstream", "target"}, nil),
+			active:  prometheus.NewDesc("klyr_upstream_target_active_requests", "In-flight requests per upstream target", []string{"upstream", "target"}, nil),
+		},
 	}
 
 	if reg == nil {
@@ -61,6 +97,13 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 		m.contractViolationsTotal,
 		m.ratelimitHitsTotal,
 		m.requestDuration,
+		m.configReloadsTotal,
+		m.configGeneration,
+		m.configLastReloadSuccess,
+		m.contractDriftTotal,
+		m.budgetExceededTotal,
+		m.upstreams,
+		m.drift,
 	)
 
 	return m
@@ -104,11 +147,125 @@ func (m *Metrics) Observe(decision logging.Decision, matches []logging.MatchedRu
 		m.contractViolationsTotal.WithLabelValues(route, policy, v.Type).Inc()
 	}
 
+	for _, d := range decision.ContractDrift {
+		m.contractDriftTotal.WithLabelValues(route, policy, d.State).Inc()
+	}
+
+	if decision.BudgetExceeded != "" {
+		m.budgetExceededTotal.WithLabelValues(route, policy, decision.BudgetExceeded).Inc()
+	}
+
 	if decision.RateLimited {
 		m.ratelimitHitsTotal.WithLabelValues(route, policy, ratelimitKey).Inc()
 	}
 }
 
+func (m *Metrics) SetConfigGeneration(generation uint64) {
+	if m == nil {
+		return
+	}
+	m.configGeneration.Set(float64(generation))
+}
+
+func (m *Metrics) ObserveReload(success bool, generation uint64) {
+	if m == nil {
+		return
+	}
+
+	result := "failure"
+	value := 0.0
+	if success {
+		result = "success"
+		value = 1
+	}
+	m.configReloadsTotal.WithLabelValues(result).Inc()
+	m.configLastReloadSuccess.Set(value)
+	m.configGeneration.Set(float64(generation))
+}
+
+// SetUpstreamSource registers the function used to read upstream target
+// health at scrape time.
+func (m *Metrics) SetUpstreamSource(source func() []upstream.TargetState) {
+	if m == nil {
+		return
+	}
+	m.upstreams.mu.Lock()
+	m.upstreams.source = source
+	m.upstreams.mu.Unlock()
+}
+
+type upstreamCollector struct {
+	healthy *prometheus.Desc
+	ejected *prometheus.Desc
+	active  *prometheus.Desc
+
+	mu     sync.RWMutex
+	source func() []upstream.TargetState
+}
+
+func (c *upstreamCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.healthy
+	ch <- c.ejected
+	ch <- c.active
+}
+
+func (c *upstreamCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	source := c.source
+	c.mu.RUnlock()
+	if source == nil {
+		return
+	}
+
+	for _, state := range source() {
+		ch <- prometheus.MustNewConstMetric(c.healthy, prometheus.GaugeValue, boolToFloat(state.Healthy), state.Upstream, state.Target)
+		ch <- prometheus.MustNewConstMetric(c.ejected, prometheus.GaugeValue, boolToFloat(state.Ejected), state.Upstream, state.Target)
+		ch <- prometheus.MustNewConstMetric(c.active, prometheus.GaugeValue, float64(state.Active), state.Upstream, state.Target)
+	}
+}
+
+// SetDriftSource registers the function used to read contract drift
+// candidates at scrape time.
+func (m *Metrics) SetDriftSource(source func() []contract.DriftCount) {
+	if m == nil {
+		return
+	}
+	m.drift.mu.Lock()
+	m.drift.source = source
+	m.drift.mu.Unlock()
+}
+
+type driftCollector struct {
+	candidates *prometheus.Desc
+
+	mu     sync.RWMutex
+	source func() []contract.DriftCount
+}
+
+func (c *driftCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.candidates
+}
+
+func (c *driftCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	source := c.source
+	c.mu.RUnlock()
+	if source == nil {
+		return
+	}
+
+	for _, count := range source() {
+		ch <- prometheus.MustNewConstMetric(c.candidates, prometheus.GaugeValue, float64(count.Candidates), count.RouteID, count.Policy, count.State)
+	}
+}
+
+func boolToFloat(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
 func intToString(code int) string {
 	if code == 0 {
 		return "0"
@@ -0,0 +1,2 @@
+// Package openapi converts between OpenAPI 3 documents and Klyr contracts.
+package openapi
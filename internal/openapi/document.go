@@ -0,0 +1,202 @@
+package openapi
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is the subset of OpenAPI 3 that maps onto a contract.
+type Document struct {
+	OpenAPI    string              `yaml:"openapi"`
+	Info       Info                `yaml:"info"`
+	Servers    []Server            `yaml:"servers,omitempty"`
+	Paths      map[string]PathItem `yaml:"paths"`
+	Components *Components         `yaml:"components,omitempty"`
+}
+
+type Info struct {
+	Title       string `yaml:"title"`
+	Version     string `yaml:"version"`
+	Description string `yaml:"description,omitempty"`
+}
+
+type Server struct {
+	URL string `yaml:"url"`
+}
+
+type PathItem struct {
+	Parameters []Parameter `yaml:"parameters,omitempty"`
+	Get        *Operation  `yaml:"get,omitempty"`
+	Put        *Operation  `yaml:"put,omitempty"`
+	Post       *Operation  `yaml:"post,omitempty"`
+	Delete     *Operation  `yaml:"delete,omitempty"`
+	Options    *Operation  `yaml:"options,omitempty"`
+	Head       *Operation  `yaml:"head,omitempty"`
+	Patch      *Operation  `yaml:"patch,omitempty"`
+}
+
+type Operation struct {
+	OperationID  string              `yaml:"operationId,omitempty"`
+	Parameters   []Parameter         `yaml:"parameters,omitempty"`
+	RequestBody  *RequestBody        `yaml:"requestBody,omitempty"`
+	Responses    map[string]Response `yaml:"responses,omitempty"`
+	MaxBodyBytes int64               `yaml:"x-klyr-max-body-bytes,omitempty"`
+}
+
+type Parameter struct {
+	Ref      string  `yaml:"$ref,omitempty"`
+	Name     string  `yaml:"name,omitempty"`
+	In       string  `yaml:"in,omitempty"`
+	Required bool    `yaml:"required,omitempty"`
+	Schema   *Schema `yaml:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Ref      string               `yaml:"$ref,omitempty"`
+	Required bool                 `yaml:"required,omitempty"`
+	Content  map[string]MediaType `yaml:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `yaml:"schema,omitempty"`
+}
+
+type Response struct {
+	Description string `yaml:"description"`
+}
+
+type Schema struct {
+	Ref                  string             `yaml:"$ref,omitempty"`
+	Type                 string             `yaml:"type,omitempty"`
+	Format               string             `yaml:"format,omitempty"`
+	Pattern              string             `yaml:"pattern,omitempty"`
+	Nullable             bool               `yaml:"nullable,omitempty"`
+	Enum                 []any              `yaml:"enum,omitempty"`
+	Minimum              *float64           `yaml:"minimum,omitempty"`
+	Maximum              *float64           `yaml:"maximum,omitempty"`
+	MaxLength            *int               `yaml:"maxLength,omitempty"`
+	Properties           map[string]*Schema `yaml:"properties,omitempty"`
+	Required             []string           `yaml:"required,omitempty"`
+	AdditionalProperties any                `yaml:"additionalProperties,omitempty"`
+	Items                *Schema            `yaml:"items,omitempty"`
+	MaxItems             *int               `yaml:"maxItems,omitempty"`
+	AllOf                []*Schema          `yaml:"allOf,omitempty"`
+	OneOf                []*Schema          `yaml:"oneOf,omitempty"`
+	AnyOf                []*Schema          `yaml:"anyOf,omitempty"`
+}
+
+type Components struct {
+	Schemas       map[string]*Schema      `yaml:"schemas,omitempty"`
+	Parameters    map[string]Parameter    `yaml:"parameters,omitempty"`
+	RequestBodies map[string]*RequestBody `yaml:"requestBodies,omitempty"`
+}
+
+// Load reads an OpenAPI 3 document in YAML or JSON form.
+func Load(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read openapi: %w", err)
+	}
+	return Parse(data)
+}
+
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse openapi: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version %q (need 3.x)", doc.OpenAPI)
+	}
+	return &doc, nil
+}
+
+func Marshal(doc *Document) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (p PathItem) operations() map[string]*Operation {
+	ops := map[string]*Operation{}
+	for method, op := range map[string]*Operation{
+		"GET": p.Get, "PUT": p.Put, "POST": p.Post, "DELETE": p.Delete,
+		"OPTIONS": p.Options, "HEAD": p.Head, "PATCH": p.Patch,
+	} {
+		if op != nil {
+			ops[method] = op
+		}
+	}
+	return ops
+}
+
+func (p *PathItem) setOperation(method string, op *Operation) {
+	switch method {
+	case "GET":
+		p.Get = op
+	case "PUT":
+		p.Put = op
+	case "POST":
+		p.Post = op
+	case "DELETE":
+		p.Delete = op
+	case "OPTIONS":
+		p.Options = op
+	case "HEAD":
+		p.Head = op
+	case "PATCH":
+		p.Patch = op
+	}
+}
+
+func (d *Document) resolveSchema(s *Schema, depth int) (*Schema, error) {
+	for s != nil && s.Ref != "" {
+		if depth > maxRefDepth {
+			return nil, fmt.Errorf("$ref %s nests too deeply", s.Ref)
+		}
+		name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+		if !ok || d.Components == nil || d.Components.Schemas[name] == nil {
+			return nil, fmt.Errorf("unresolved $ref %s", s.Ref)
+		}
+		s = d.Components.Schemas[name]
+		depth++
+	}
+	return s, nil
+}
+
+func (d *Document) resolveParameter(p Parameter) (Parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/")
+	if !ok || d.Components == nil {
+		return Parameter{}, fmt.Errorf("unresolved $ref %s", p.Ref)
+	}
+	resolved, ok := d.Components.Parameters[name]
+	if !ok {
+		return Parameter{}, fmt.Errorf("unresolved $ref %s", p.Ref)
+	}
+	return resolved, nil
+}
+
+func (d *Document) resolveRequestBody(b *RequestBody) (*RequestBody, error) {
+	if b == nil || b.Ref == "" {
+		return b, nil
+	}
+	name, ok := strings.CutPrefix(b.Ref, "#/components/requestBodies/")
+	if !ok || d.Components == nil || d.Components.RequestBodies[name] == nil {
+		return nil, fmt.Errorf("unresolved $ref %s", b.Ref)
+	}
+	return d.Components.RequestBodies[name], nil
+}
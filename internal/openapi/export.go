@@ -0,0 +1,266 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/klyr/klyr/internal/contract"
+)
+
+type ExportOptions struct {
+	Title   string
+	Version string
+}
+
+// Export renders a contract as an OpenAPI skeleton. Learned limits are kept so
+// that a reviewer sees what enforce mode will apply; responses are left as a
+// placeholder because klyr only learns requests.
+func Export(c *contract.Contract, opts ExportOptions) *Document {
+	title := opts.Title
+	if title == "" && c != nil {
+		title = fmt.Sprintf("%s (%s)", c.RouteID, c.Policy)
+	}
+	version := opts.Version
+	if version == "" {
+		version = "0.0.0"
+	}
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: title, Version: version, Description: "Generated by klyr from a learned contract."},
+		Paths:   map[string]PathItem{},
+	}
+	if c == nil {
+		return doc
+	}
+
+	endpoints := c.Endpoints
+	if len(endpoints) == 0 {
+		// Contracts learned before per-endpoint profiles only describe the
+		// route as a whole.
+		endpoints = map[string]*contract.Endpoint{"/": {
+			Template:         "/",
+			Methods:          c.Methods,
+			ContentTypes:     c.ContentTypes,
+			QueryParams:      c.QueryParams,
+			QueryParamValues: c.QueryParamValues,
+			MaxBodyBytes:     c.MaxBodyBytes,
+		}}
+	}
+
+	headers := headerParameters(c)
+	for template, ep := range endpoints {
+		item := PathItem{}
+		path, pathParams := exportPath(template)
+		item.Parameters = pathParams
+
+		methods := sortedKeys(ep.Methods)
+		for _, method := range methods {
+			op := &Operation{
+				Responses:    map[string]Response{"default": {Description: "Not learned"}},
+				MaxBodyBytes: ep.MaxBodyBytes,
+			}
+			op.Parameters = append(op.Parameters, queryParameters(ep)...)
+			op.Parameters = append(op.Parameters, headers...)
+			if hasBody(method) && len(ep.ContentTypes) > 0 {
+				body := &RequestBody{Content: map[string]MediaType{}}
+				for _, ct := range sortedKeys(ep.ContentTypes) {
+					media := MediaType{}
+					if ep.BodySchema != nil && (ct == "application/json" || strings.HasSuffix(ct, "+json")) {
+						media.Schema = exportSchema(ep.BodySchema)
+					}
+					body.Content[ct] = media
+				}
+				op.RequestBody = body
+			}
+			item.setOperation(method, op)
+		}
+		doc.Paths[path] = item
+	}
+	return doc
+}
+
+// exportPath names placeholder segments so that the OpenAPI path is valid even
+// when one template uses the same placeholder class more than once.
+func exportPath(template string) (string, []Parameter) {
+	var params []Parameter
+	seen := map[string]int{}
+	segments := strings.Split(template, "/")
+	for i, seg := range segments {
+		if len(seg) < 3 || seg[0] != '{' || seg[len(seg)-1] != '}' {
+			continue
+		}
+		class := seg[1 : len(seg)-1]
+		seen[class]++
+		name := class
+		if seen[class] > 1 {
+			name = fmt.Sprintf("%s%d", class, seen[class])
+		}
+		segments[i] = "{" + name + "}"
+
+		schema := &Schema{Type: "string"}
+		switch class {
+		case "id":
+			schema = &Schema{Type: "integer"}
+		case "uuid":
+			schema.Format = "uuid"
+		case "hex":
+			schema.Pattern = "^[0-9a-fA-F]+$"
+		case "slug":
+			schema.Pattern = "^[a-z0-9]+(-[a-z0-9]+)*$"
+		}
+		params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func queryParameters(ep *contract.Endpoint) []Parameter {
+	names := sortedKeys(ep.QueryParams)
+	params := make([]Parameter, 0, len(names))
+	for _, name := range names {
+		params = append(params, Parameter{Name: name, In: "query", Schema: profileSchema(ep.QueryParamValues[name])})
+	}
+	return params
+}
+
+// headerParameters lists only application headers; the standard ones every
+// client sends would drown out the interesting part of the contract.
+func headerParameters(c *contract.Contract) []Parameter {
+	var params []Parameter
+	for _, name := range sortedKeys(c.HeaderNames) {
+		if !strings.HasPrefix(http.CanonicalHeaderKey(name), "X-") || contains(standardHeaders, name) {
+			continue
+		}
+		params = append(params, Parameter{Name: name, In: "header", Schema: profileSchema(c.HeaderValues[name])})
+	}
+	return params
+}
+
+func profileSchema(p *contract.ValueProfile) *Schema {
+	s := &Schema{Type: "string"}
+	if p == nil {
+		return s
+	}
+	if p.MaxLength > 0 {
+		limit := p.MaxLength
+		s.MaxLength = &limit
+	}
+	switch p.Type {
+	case contract.ValueInteger:
+		s = &Schema{Type: "integer"}
+		minimum, maximum := float64(p.Min), float64(p.Max)
+		s.Minimum, s.Maximum = &minimum, &maximum
+	case contract.ValueBoolean:
+		s = &Schema{Type: "boolean"}
+	case contract.ValueUUID:
+		s.Format = "uuid"
+	case contract.ValueEmail:
+		s.Format = "email"
+	case contract.ValueEnum:
+		for _, v := range p.Enum {
+			s.Enum = append(s.Enum, v)
+		}
+	}
+	return s
+}
+
+func exportSchema(s *contract.Schema) *Schema {
+	if s == nil {
+		return &Schema{}
+	}
+	kinds := sortedCounts(s.Types)
+	nullable := false
+	if n := len(kinds); n > 1 && contains(kinds, "null") {
+		nullable = true
+		kinds = without(kinds, "null")
+	}
+	if len(kinds) > 1 {
+		out := &Schema{Nullable: nullable}
+		for _, kind := range kinds {
+			out.OneOf = append(out.OneOf, exportKind(s, kind))
+		}
+		return out
+	}
+	if len(kinds) == 0 {
+		return &Schema{}
+	}
+	out := exportKind(s, kinds[0])
+	out.Nullable = nullable
+	return out
+}
+
+func exportKind(s *contract.Schema, kind string) *Schema {
+	out := &Schema{Type: kind}
+	switch kind {
+	case "null":
+		return &Schema{Nullable: true}
+	case "object":
+		if s.Open {
+			out.AdditionalProperties = true
+			return out
+		}
+		out.AdditionalProperties = false
+		out.Required = s.Required()
+		for _, name := range sortedKeys(s.Properties) {
+			if out.Properties == nil {
+				out.Properties = map[string]*Schema{}
+			}
+			out.Properties[name] = exportSchema(s.Properties[name])
+		}
+	case "array":
+		if s.MaxItems > 0 && s.MaxItems < unboundedLength {
+			limit := s.MaxItems
+			out.MaxItems = &limit
+		}
+		if s.Items != nil {
+			out.Items = exportSchema(s.Items)
+		} else {
+			out.Items = &Schema{}
+		}
+	case "string":
+		if s.MaxLength > 0 && s.MaxLength < unboundedLength {
+			limit := s.MaxLength
+			out.MaxLength = &limit
+		}
+	}
+	return out
+}
+
+func hasBody(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodDelete:
+		return false
+	}
+	return true
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedCounts(m map[string]int) []string {
+	var keys []string
+	for k, n := range m {
+		if n > 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func without(values []string, drop string) []string {
+	var out []string
+	for _, v := range values {
+		if v != drop {
+			out = append(out, v)
+		}
+	}
+	return out
+}
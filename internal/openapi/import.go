@@ -0,0 +1,478 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klyr/klyr/internal/contract"
+)
+
+const (
+	maxRefDepth    = 32
+	maxSchemaDepth = 16
+	// unboundedLength stands in for a missing maxLength/maxItems so that
+	// strict enforcement does not invent a limit the spec does not state.
+	unboundedLength = 1 << 30
+)
+
+// standardHeaders are allowed on every imported contract because clients and
+// proxies send them whether or not the spec lists them.
+var standardHeaders = []string{
+	"Accept", "Accept-Encoding", "Accept-Language", "Authorization", "Cache-Control",
+	"Connection", "Content-Length", "Content-Type", "Cookie", "Origin", "Referer",
+	"User-Agent", "X-Forwarded-For", "X-Forwarded-Proto", "X-Request-Id",
+}
+
+type ImportOptions struct {
+	RouteID string
+	Policy  string
+	// PathPrefixes restricts the import to spec paths under one of the
+	// prefixes, after BasePath is applied. Empty imports every path.
+	PathPrefixes []string
+	// BasePath is prepended to every spec path. When empty the path of the
+	// first server URL is used.
+	BasePath     string
+	MaxBodyBytes int64
+}
+
+// Import builds an enforce-ready contract from an OpenAPI document. Constructs
+// that cannot be represented are skipped and described in the returned
+// warnings.
+func Import(doc *Document, opts ImportOptions) (*contract.Contract, []string, error) {
+	if doc == nil {
+		return nil, nil, fmt.Errorf("openapi document is nil")
+	}
+
+	basePath := opts.BasePath
+	if basePath == "" && len(doc.Servers) > 0 {
+		if parsed, err := url.Parse(doc.Servers[0].URL); err == nil {
+			basePath = parsed.Path
+		}
+	}
+	basePath = strings.TrimSuffix(basePath, "/")
+
+	c := contract.New(opts.RouteID, opts.Policy)
+	c.GeneratedAt = time.Now().UTC()
+	for _, name := range standardHeaders {
+		c.HeaderNames[name] = true
+	}
+
+	var warnings []string
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, specPath := range paths {
+		fullPath := basePath + specPath
+		if !underPrefix(fullPath, opts.PathPrefixes) {
+			continue
+		}
+		item := doc.Paths[specPath]
+		ep, pathWarnings, err := doc.importPath(c, fullPath, item, opts)
+		warnings = append(warnings, pathWarnings...)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: skipped: %v", specPath, err))
+			continue
+		}
+		if existing, ok := c.Endpoints[ep.Template]; ok {
+			warnings = append(warnings, fmt.Sprintf("%s: shares template %s with another path; merged", specPath, ep.Template))
+			mergeEndpoint(existing, ep)
+			continue
+		}
+		c.Endpoints[ep.Template] = ep
+	}
+
+	for _, ep := range c.Endpoints {
+		for method := range ep.Methods {
+			c.Methods[method] = true
+		}
+		for ct := range ep.ContentTypes {
+			c.ContentTypes[ct] = true
+		}
+		for name := range ep.QueryParams {
+			c.QueryParams[name] = true
+		}
+		if ep.MaxBodyBytes > c.MaxBodyBytes {
+			c.MaxBodyBytes = ep.MaxBodyBytes
+		}
+	}
+	if c.MaxBodyBytes == 0 {
+		c.MaxBodyBytes = opts.MaxBodyBytes
+	}
+	// Declared limits stand in for observations so that merging an imported
+	// contract with a learned one keeps them.
+	c.ObservedMax = c.MaxBodyBytes
+	for _, ep := range c.Endpoints {
+		ep.ObservedMax = ep.MaxBodyBytes
+	}
+
+	return c, warnings, nil
+}
+
+func (d *Document) importPath(c *contract.Contract, fullPath string, item PathItem, opts ImportOptions) (*contract.Endpoint, []string, error) {
+	var warnings []string
+	ops := item.operations()
+	if len(ops) == 0 {
+		return nil, nil, fmt.Errorf("no operations")
+	}
+
+	pathParams := map[string]Parameter{}
+	ep := &contract.Endpoint{
+		Methods:          map[string]bool{},
+		ContentTypes:     map[string]bool{},
+		QueryParams:      map[string]bool{},
+		QueryParamValues: map[string]*contract.ValueProfile{},
+	}
+
+	methods := make([]string, 0, len(ops))
+	for method := range ops {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	for _, method := range methods {
+		op := ops[method]
+		ep.Methods[method] = true
+
+		params := append(append([]Parameter(nil), item.Parameters...), op.Parameters...)
+		for _, raw := range params {
+			param, err := d.resolveParameter(raw)
+			if err != nil {
+				return nil, warnings, err
+			}
+			schema, err := d.resolveSchema(param.Schema, 0)
+			if err != nil {
+				return nil, warnings, err
+			}
+			switch param.In {
+			case "path":
+				pathParams[param.Name] = Parameter{Name: param.Name, In: "path", Schema: schema}
+			case "query":
+				ep.QueryParams[param.Name] = true
+				ep.QueryParamValues[param.Name] = paramProfile(schema)
+			case "header":
+				canon := http.CanonicalHeaderKey(param.Name)
+				c.HeaderNames[canon] = true
+				c.HeaderValues[canon] = paramProfile(schema)
+			default:
+				warnings = append(warnings, fmt.Sprintf("%s %s: %s parameter %q not enforced", method, fullPath, param.In, param.Name))
+			}
+		}
+
+		maxBody := op.MaxBodyBytes
+		if maxBody == 0 {
+			maxBody = opts.MaxBodyBytes
+		}
+		if maxBody > ep.MaxBodyBytes {
+			ep.MaxBodyBytes = maxBody
+		}
+
+		body, err := d.resolveRequestBody(op.RequestBody)
+		if err != nil {
+			return nil, warnings, err
+		}
+		if body == nil {
+			continue
+		}
+		cts := make([]string, 0, len(body.Content))
+		for ct := range body.Content {
+			cts = append(cts, ct)
+		}
+		sort.Strings(cts)
+		for _, ct := range cts {
+			normalized := strings.ToLower(strings.TrimSpace(strings.Split(ct, ";")[0]))
+			ep.ContentTypes[normalized] = true
+			if normalized != "application/json" && !strings.HasSuffix(normalized, "+json") {
+				continue
+			}
+			schema, err := d.bodySchema(body.Content[ct].Schema, 0)
+			if err != nil {
+				return nil, warnings, err
+			}
+			if ep.BodySchema == nil {
+				ep.BodySchema = schema
+			} else {
+				// Different operations on one path share a body profile.
+				merged := &contract.Schema{Types: map[string]int{}}
+				mergeSchema(merged, ep.BodySchema)
+				mergeSchema(merged, schema)
+				merged.RequiredFields = intersect(ep.BodySchema.RequiredFields, schema.RequiredFields)
+				ep.BodySchema = merged
+			}
+		}
+	}
+
+	ep.Template = templatePath(fullPath, pathParams)
+	return ep, warnings, nil
+}
+
+// templatePath rewrites {name} segments into the placeholder classes used by
+// learned contracts when the parameter type allows it, so that imported and
+// learned contracts template the same way.
+func templatePath(path string, params map[string]Parameter) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if len(seg) < 3 || seg[0] != '{' || seg[len(seg)-1] != '}' {
+			continue
+		}
+		name := seg[1 : len(seg)-1]
+		schema := params[name].Schema
+		switch {
+		case schema != nil && schema.Type == "integer":
+			segments[i] = "{id}"
+		case schema != nil && schema.Format == "uuid":
+			segments[i] = "{uuid}"
+		case name == "id" || name == "uuid" || name == "hex" || name == "slug":
+			segments[i] = "{param}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func paramProfile(schema *Schema) *contract.ValueProfile {
+	p := &contract.ValueProfile{Type: contract.ValueString}
+	if schema == nil {
+		return p
+	}
+	if schema.Type == "array" && schema.Items != nil {
+		schema = schema.Items
+	}
+	if schema.MaxLength != nil {
+		p.MaxLength = *schema.MaxLength
+		p.ObservedMaxLength = *schema.MaxLength
+	}
+
+	switch {
+	case len(schema.Enum) > 0:
+		p.Type = contract.ValueEnum
+		for _, v := range schema.Enum {
+			p.Enum = append(p.Enum, fmt.Sprint(v))
+		}
+		sort.Strings(p.Enum)
+	case schema.Type == "integer":
+		p.Type = contract.ValueInteger
+		p.Min, p.Max = math.MinInt64, math.MaxInt64
+		if schema.Minimum != nil {
+			p.Min = int64(math.Ceil(*schema.Minimum))
+		}
+		if schema.Maximum != nil {
+			p.Max = int64(math.Floor(*schema.Maximum))
+		}
+	case schema.Type == "boolean":
+		p.Type = contract.ValueBoolean
+	case schema.Format == "uuid":
+		p.Type = contract.ValueUUID
+	case schema.Format == "email":
+		p.Type = contract.ValueEmail
+	}
+	return p
+}
+
+func (d *Document) bodySchema(raw *Schema, depth int) (*contract.Schema, error) {
+	s, err := d.resolveSchema(raw, 0)
+	if err != nil {
+		return nil, err
+	}
+	out := &contract.Schema{Types: map[string]int{}}
+	if s == nil || depth >= maxSchemaDepth {
+		return anySchema(), nil
+	}
+
+	if len(s.AllOf) > 0 {
+		for _, part := range s.AllOf {
+			converted, err := d.bodySchema(part, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			required := union(out.RequiredFields, converted.RequiredFields)
+			mergeSchema(out, converted)
+			out.RequiredFields = required
+		}
+		return out, nil
+	}
+	if alternatives := append(append([]*Schema(nil), s.OneOf...), s.AnyOf...); len(alternatives) > 0 {
+		for i, part := range alternatives {
+			converted, err := d.bodySchema(part, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			required := converted.RequiredFields
+			if i > 0 {
+				required = intersect(out.RequiredFields, converted.RequiredFields)
+			}
+			mergeSchema(out, converted)
+			out.RequiredFields = required
+		}
+		return out, nil
+	}
+
+	kind := s.Type
+	if kind == "" && len(s.Properties) > 0 {
+		kind = "object"
+	}
+	if s.Nullable {
+		out.Types["null"] = 1
+	}
+
+	switch kind {
+	case "":
+		return anySchema(), nil
+	case "object":
+		out.Types["object"] = 1
+		// An unset additionalProperties is treated as closed, matching how
+		// learned contracts flag unknown fields.
+		if len(s.Properties) == 0 || s.AdditionalProperties == true || isSchemaValue(s.AdditionalProperties) {
+			out.Open = true
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, err := d.bodySchema(s.Properties[name], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if out.Properties == nil {
+				out.Properties = map[string]*contract.Schema{}
+			}
+			out.Properties[name] = prop
+		}
+		out.RequiredFields = append([]string(nil), s.Required...)
+		sort.Strings(out.RequiredFields)
+	case "array":
+		out.Types["array"] = 1
+		out.MaxItems = unboundedLength
+		if s.MaxItems != nil {
+			out.MaxItems = *s.MaxItems
+		}
+		items, err := d.bodySchema(s.Items, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		out.Items = items
+	case "string":
+		out.Types["string"] = 1
+		out.MaxLength = unboundedLength
+		if s.MaxLength != nil {
+			out.MaxLength = *s.MaxLength
+		}
+	case "integer", "number", "boolean":
+		out.Types[kind] = 1
+	default:
+		return nil, fmt.Errorf("unsupported schema type %q", kind)
+	}
+	return out, nil
+}
+
+func anySchema() *contract.Schema {
+	return &contract.Schema{
+		Types:     map[string]int{"object": 1, "array": 1, "string": 1, "integer": 1, "number": 1, "boolean": 1, "null": 1},
+		Open:      true,
+		MaxItems:  unboundedLength,
+		MaxLength: unboundedLength,
+	}
+}
+
+func isSchemaValue(v any) bool {
+	_, ok := v.(map[string]any)
+	return ok
+}
+
+// mergeSchema unions src into dst without the count semantics used by
+// learning, since imported schemas carry declared required fields.
+func mergeSchema(dst, src *contract.Schema) {
+	for kind := range src.Types {
+		dst.Types[kind] = 1
+	}
+	dst.Open = dst.Open || src.Open
+	dst.MaxItems = max(dst.MaxItems, src.MaxItems)
+	dst.MaxLength = max(dst.MaxLength, src.MaxLength)
+	for name, prop := range src.Properties {
+		if dst.Properties == nil {
+			dst.Properties = map[string]*contract.Schema{}
+		}
+		if existing, ok := dst.Properties[name]; ok {
+			mergeSchema(existing, prop)
+		} else {
+			dst.Properties[name] = prop
+		}
+	}
+	if src.Items != nil {
+		if dst.Items == nil {
+			dst.Items = &contract.Schema{Types: map[string]int{}}
+		}
+		mergeSchema(dst.Items, src.Items)
+	}
+}
+
+func mergeEndpoint(dst, src *contract.Endpoint) {
+	for k := range src.Methods {
+		dst.Methods[k] = true
+	}
+	for k := range src.ContentTypes {
+		dst.ContentTypes[k] = true
+	}
+	for k, v := range src.QueryParamValues {
+		dst.QueryParams[k] = true
+		dst.QueryParamValues[k] = v
+	}
+	dst.MaxBodyBytes = max(dst.MaxBodyBytes, src.MaxBodyBytes)
+	if dst.BodySchema == nil {
+		dst.BodySchema = src.BodySchema
+	} else if src.BodySchema != nil {
+		required := intersect(dst.BodySchema.RequiredFields, src.BodySchema.RequiredFields)
+		mergeSchema(dst.BodySchema, src.BodySchema)
+		dst.BodySchema.RequiredFields = required
+	}
+}
+
+func underPrefix(path string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func union(a, b []string) []string {
+	out := append([]string(nil), a...)
+	for _, v := range b {
+		if !contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func intersect(a, b []string) []string {
+	var out []string
+	for _, v := range a {
+		if contains(b, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func contains(values []string, v string) bool {
+	for _, item := range values {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
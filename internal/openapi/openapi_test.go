@@ -0,0 +1,191 @@
+package openapi
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klyr/klyr/internal/contract"
+)
+
+const petSpec = `
+openapi: 3.0.3
+info:
+  title: pets
+  version: "1"
+servers:
+  - url: https://api.example.com/api
+paths:
+  /pets:
+    get:
+      parameters:
+        - name: limit
+          in: query
+          schema: {type: integer, minimum: 1, maximum: 100}
+        - name: status
+          in: query
+          schema: {type: string, enum: [available, sold]}
+    post:
+      x-klyr-max-body-bytes: 2048
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        schema: {type: integer}
+    get:
+      parameters:
+        - $ref: '#/components/parameters/TraceID'
+  /other:
+    get: {}
+components:
+  parameters:
+    TraceID:
+      name: X-Trace-Id
+      in: header
+      schema: {type: string, format: uuid}
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string, maxLength: 32}
+        tags:
+          type: array
+          items: {type: string}
+`
+
+func TestImport(t *testing.T) {
+	doc, err := Parse([]byte(petSpec))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	c, warnings, err := Import(doc, ImportOptions{RouteID: "route-0", Policy: "api", PathPrefixes: []string{"/api/pets"}, MaxBodyBytes: 1024})
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+	if len(c.Endpoints) != 2 {
+		t.Fatalf("expected 2 endpoints, got %v", c.Endpoints)
+	}
+	if c.Endpoints["/api/pets/{id}"] == nil {
+		t.Fatalf("expected integer path param to become {id}")
+	}
+	if c.MaxBodyBytes != 2048 {
+		t.Fatalf("expected max body 2048, got %d", c.MaxBodyBytes)
+	}
+
+	cases := []struct {
+		method, target, body string
+		violations           []string
+	}{
+		{"GET", "/api/pets?limit=10&status=sold", "", nil},
+		{"GET", "/api/pets?limit=500", "", []string{"query_param_out_of_range"}},
+		{"GET", "/api/pets?status=lost", "", []string{"query_param_type_mismatch"}},
+		{"GET", "/api/pets/42", "", nil},
+		{"DELETE", "/api/pets/42", "", []string{"method_unexpected"}},
+		{"GET", "/api/other", "", []string{"path_unexpected"}},
+		{"POST", "/api/pets", `{"name":"rex","tags":["a"]}`, nil},
+		{"POST", "/api/pets", `{"tags":["a"],"owner":1}`, []string{"body_field_unexpected", "body_field_missing"}},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+		if tc.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		var got []string
+		for _, v := range contract.Evaluate(c, req, []byte(tc.body), int64(len(tc.body)), contract.EnforcementStrict) {
+			got = append(got, v.Type)
+		}
+		if strings.Join(got, ",") != strings.Join(tc.violations, ",") {
+			t.Fatalf("%s %s: expected %v, got %v", tc.method, tc.target, tc.violations, got)
+		}
+	}
+}
+
+func TestImportUnresolvedRef(t *testing.T) {
+	doc, err := Parse([]byte(`
+openapi: 3.0.0
+info: {title: t, version: "1"}
+paths:
+  /a:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/Missing'}
+  /b:
+    get: {}
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	c, warnings, err := Import(doc, ImportOptions{})
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "Missing") {
+		t.Fatalf("expected unresolved ref warning, got %v", warnings)
+	}
+	if len(c.Endpoints) != 1 || c.Endpoints["/b"] == nil {
+		t.Fatalf("expected only /b to be imported, got %v", c.Endpoints)
+	}
+}
+
+func TestParseRejectsSwagger2(t *testing.T) {
+	if _, err := Parse([]byte("swagger: \"2.0\"\npaths: {}\n")); err == nil {
+		t.Fatalf("expected error for swagger 2 document")
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	doc, err := Parse([]byte(petSpec))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	c, _, err := Import(doc, ImportOptions{BasePath: "/"})
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	exported := Export(c, ExportOptions{Title: "pets"})
+	data, err := Marshal(exported)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	reparsed, err := Parse(data)
+	if err != nil {
+		t.Fatalf("reparse: %v\n%s", err, data)
+	}
+
+	item, ok := reparsed.Paths["/pets/{id}"]
+	if !ok || item.Get == nil {
+		t.Fatalf("expected GET /pets/{id}, got %s", data)
+	}
+	if len(item.Parameters) != 1 || item.Parameters[0].Schema.Type != "integer" {
+		t.Fatalf("expected integer path parameter, got %+v", item.Parameters)
+	}
+	post := reparsed.Paths["/pets"].Post
+	if post == nil || post.RequestBody == nil {
+		t.Fatalf("expected POST /pets with body, got %s", data)
+	}
+	schema := post.RequestBody.Content["application/json"].Schema
+	if schema == nil || schema.Properties["name"] == nil || len(schema.Required) != 1 {
+		t.Fatalf("expected body schema with required name, got %+v", schema)
+	}
+
+	again, _, err := Import(reparsed, ImportOptions{})
+	if err != nil {
+		t.Fatalf("re-import: %v", err)
+	}
+	if len(again.Endpoints) != len(c.Endpoints) {
+		t.Fatalf("expected %d endpoints after round trip, got %d", len(c.Endpoints), len(again.Endpoints))
+	}
+}
@@ -1,27 +1,56 @@
 package policy
 
-import "github.com/klyr/klyr/internal/rules"
+import (
+	"sort"
+	"time"
+
+	"github.com/klyr/klyr/internal/rules"
+)
 
 type Action string
 
 const (
-	ActionAllow  Action = "allow"
-	ActionBlock  Action = "block"
-	ActionShadow Action = "shadow"
+	ActionAllow    Action = "allow"
+	ActionBlock    Action = "block"
+	ActionShadow   Action = "shadow"
+	ActionRedirect Action = "redirect"
 )
 
-func EvaluateRules(engine *rules.Engine, ctx rules.EvalContext) rules.Result {
+// Thresholds are the anomaly score limits of a policy: one for the total and
+// one per category (rule tag).
+type Thresholds struct {
+	Anomaly    int
+	Categories map[string]int
+}
+
+// Verdict is the outcome of the rule stage. RuleID names the rule whose
+// action decided it and is empty when the anomaly score did.
+type Verdict struct {
+	Action     Action
+	Block      bool
+	RuleID     string
+	Category   string
+	StatusCode int
+	Location   string
+	Delay      time.Duration
+	Tags       []string
+}
+
+func EvaluateRules(engine *rules.Engine, ctx rules.EvalContext, opts rules.EvalOptions) rules.Result {
 	if engine == nil {
 		return rules.Result{}
 	}
-	return engine.Evaluate(ctx)
+	return engine.EvaluateWith(ctx, opts)
 }
 
 func DecideAction(mode string, score, threshold int) (Action, bool) {
 	if score < threshold {
 		return ActionAllow, false
 	}
+	return overThreshold(mode)
+}
 
+func overThreshold(mode string) (Action, bool) {
 	switch mode {
 	case "shadow":
 		return ActionShadow, false
@@ -33,3 +62,82 @@ func DecideAction(mode string, score, threshold int) (Action, bool) {
 		return ActionAllow, false
 	}
 }
+
+// Decide applies rule actions ahead of the anomaly score, in the order
+// allow, block, redirect, then the score thresholds. Block, redirect and the
+// longest matched tarpit delay only take effect in enforce mode; shadow mode
+// reports a block or redirect as shadow. When no total threshold is crossed,
+// the first category over its own threshold, by name, decides.
+func Decide(mode string, result rules.Result, thresholds Thresholds) Verdict {
+	var verdict Verdict
+	var block, redirect *rules.Match
+	for i := range result.Matches {
+		m := &result.Matches[i]
+		switch m.Action.Type {
+		case rules.ActionAllow:
+			return Verdict{Action: ActionAllow, RuleID: m.RuleID}
+		case rules.ActionBlock:
+			if block == nil {
+				block = m
+			}
+		case rules.ActionRedirect:
+			if redirect == nil {
+				redirect = m
+			}
+		case rules.ActionTarpit:
+			if mode == "enforce" && m.Action.Delay > verdict.Delay {
+				verdict.Delay = m.Action.Delay
+			}
+		case rules.ActionTag:
+			verdict.Tags = append(verdict.Tags, m.Tags...)
+		}
+	}
+
+	immediate := block
+	if immediate == nil {
+		immediate = redirect
+	}
+	if immediate == nil {
+		verdict.Action, verdict.Block = DecideAction(mode, result.Score, thresholds.Anomaly)
+		if verdict.Action != ActionAllow {
+			return verdict
+		}
+		if category := exceededCategory(result.Categories, thresholds.Categories); category != "" {
+			verdict.Category = category
+			verdict.Action, verdict.Block = overThreshold(mode)
+		}
+		return verdict
+	}
+
+	verdict.RuleID = immediate.RuleID
+	switch mode {
+	case "enforce":
+		verdict.Block = true
+		verdict.StatusCode = immediate.Action.StatusCode
+		if immediate.Action.Type == rules.ActionRedirect {
+			verdict.Action = ActionRedirect
+			verdict.Location = immediate.Action.Location
+		} else {
+			verdict.Action = ActionBlock
+		}
+	case "shadow":
+		verdict.Action = ActionShadow
+	default:
+		verdict.Action = ActionAllow
+	}
+	return verdict
+}
+
+func exceededCategory(scores, thresholds map[string]int) string {
+	var out []string
+	for category, threshold := range thresholds {
+		if threshold > 0 && scores[category] >= threshold {
+			out = append(out, category)
+		}
+	}
+	if len(out) == 0 {
+		return ""
+	}
+	sort.Strings(out)
+	return out[0]
+}
@@ -1,6 +1,12 @@
 package policy
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/klyr/klyr/internal/rules"
+)
 
 func TestDecideAction(t *testing.T) {
 	cases := []struct {
@@ -25,3 +31,65 @@ func TestDecideAction(t *testing.T) {
 		}
 	}
 }
+
+func TestDecideRuleActions(t *testing.T) {
+	match := func(id string, action rules.Action, score int) rules.Match {
+		return rules.Match{RuleID: id, Score: score, Tags: []string{id}, Action: action}
+	}
+	block := match("bad", rules.Action{Type: rules.ActionBlock, StatusCode: 451}, 0)
+	redirect := match("old", rules.Action{Type: rules.ActionRedirect, Location: "/new"}, 0)
+	slow := match("slow", rules.Action{Type: rules.ActionTarpit, Delay: 2 * time.Second}, 1)
+	tag := match("tagged", rules.Action{Type: rules.ActionTag}, 0)
+
+	cases := []struct {
+		name      string
+		mode      string
+		result    rules.Result
+		threshold int
+		want      Verdict
+	}{
+		{"allow wins", "enforce", rules.Result{Matches: []rules.Match{match("health", rules.Action{Type: rules.ActionAllow}, 0)}}, 1,
+			Verdict{Action: ActionAllow, RuleID: "health"}},
+		{"block below threshold", "enforce", rules.Result{Matches: []rules.Match{block}}, 10,
+			Verdict{Action: ActionBlock, Block: true, RuleID: "bad", StatusCode: 451}},
+		{"block over redirect", "enforce", rules.Result{Matches: []rules.Match{redirect, block}}, 10,
+			Verdict{Action: ActionBlock, Block: true, RuleID: "bad", StatusCode: 451}},
+		{"redirect", "enforce", rules.Result{Matches: []rules.Match{redirect}}, 10,
+			Verdict{Action: ActionRedirect, Block: true, RuleID: "old", Location: "/new"}},
+		{"shadow block", "shadow", rules.Result{Matches: []rules.Match{block}}, 10,
+			Verdict{Action: ActionShadow, RuleID: "bad"}},
+		{"tarpit then score", "enforce", rules.Result{Score: 1, Matches: []rules.Match{slow, tag}}, 1,
+			Verdict{Action: ActionBlock, Block: true, Delay: 2 * time.Second, Tags: []string{"tagged"}}},
+		{"tarpit ignored in shadow", "shadow", rules.Result{Score: 1, Matches: []rules.Match{slow}}, 5,
+			Verdict{Action: ActionAllow}},
+	}
+
+	for _, tt := range cases {
+		if got := Decide(tt.mode, tt.result, Thresholds{Anomaly: tt.threshold}); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s: expected %+v got %+v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestDecideCategoryThresholds(t *testing.T) {
+	thresholds := Thresholds{Anomaly: 20, Categories: map[string]int{"sqli": 5, "xss": 5}}
+	cases := []struct {
+		name       string
+		categories map[string]int
+		score      int
+		want       Verdict
+	}{
+		{"below all", map[string]int{"sqli": 4, "xss": 4}, 8, Verdict{Action: ActionAllow}},
+		{"one category", map[string]int{"sqli": 2, "xss": 6}, 8, Verdict{Action: ActionBlock, Block: true, Category: "xss"}},
+		{"first by name", map[string]int{"sqli": 5, "xss": 9}, 14, Verdict{Action: ActionBlock, Block: true, Category: "sqli"}},
+		{"untracked category", map[string]int{"protocol": 19}, 19, Verdict{Action: ActionAllow}},
+		{"total", map[string]int{"protocol": 20}, 20, Verdict{Action: ActionBlock, Block: true}},
+	}
+
+	for _, tt := range cases {
+		result := rules.Result{Score: tt.score, Categories: tt.categories}
+		if got := Decide("enforce", result, thresholds); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s: expected %+v got %+v", tt.name, tt.want, got)
+		}
+	}
+}
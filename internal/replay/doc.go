@@ -0,0 +1,2 @@
+// Package replay provides functionality for Klyr.
+package replay
@@ -0,0 +1,144 @@
+package replay
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/klyr/klyr/internal/explain"
+	"github.com/klyr/klyr/internal/gateway"
+	"github.com/klyr/klyr/internal/logging"
+	"github.com/klyr/klyr/internal/policy"
+)
+
+// Change is a recorded request whose outcome differs under the candidate
+// config.
+type Change struct {
+	RequestID   string
+	RouteID     string
+	Method      string
+	Path        string
+	Before      string
+	After       string
+	BeforeCause string
+	AfterCause  string
+}
+
+// Report summarizes a replay. Decisions without recorded request data and
+// rate-limited decisions, whose outcome replay cannot reproduce, are
+// counted but not replayed.
+type Report struct {
+	Total        int
+	Replayed     int
+	NoRequest    int
+	RateLimited  int
+	Truncated    int
+	NoRoute      int
+	NewlyBlocked []Change
+	NewlyAllowed []Change
+}
+
+// Run re-evaluates every replayable decision against gw and collects the
+// requests that are newly blocked or newly allowed. Redirects count as
+// blocked; shadow does not.
+func Run(gw *gateway.Gateway, decisions []logging.Decision) Report {
+	report := Report{Total: len(decisions)}
+	for _, d := range decisions {
+		switch {
+		case d.Request == nil:
+			report.NoRequest++
+			continue
+		case d.RateLimited:
+			report.RateLimited++
+			continue
+		}
+		req, err := Request(d)
+		if err != nil {
+			report.NoRequest++
+			continue
+		}
+		ev, err := gw.Evaluate(req)
+		if err != nil {
+			report.NoRoute++
+			continue
+		}
+		report.Replayed++
+		if d.Request.BodyTruncated {
+			report.Truncated++
+		}
+
+		before, after := blocked(d), blocked(ev.Decision)
+		if before == after {
+			continue
+		}
+		change := Change{
+			RequestID:   d.RequestID,
+			RouteID:     d.RouteID,
+			Method:      d.Method,
+			Path:        d.Path,
+			Before:      d.Action,
+			After:       ev.Decision.Action,
+			BeforeCause: Cause(d),
+			AfterCause:  Cause(ev.Decision),
+		}
+		if after {
+			change.RouteID = ev.Decision.RouteID
+			report.NewlyBlocked = append(report.NewlyBlocked, change)
+		} else {
+			report.NewlyAllowed = append(report.NewlyAllowed, change)
+		}
+	}
+	return report
+}
+
+// Request rebuilds the request recorded in d.
+func Request(d logging.Decision) (*http.Request, error) {
+	if d.Request == nil {
+		return nil, fmt.Errorf("decision %s has no recorded request", d.RequestID)
+	}
+	host := d.Host
+	if host == "" {
+		// HTTP/1.0 clients may send no Host; req.Host is reset below.
+		host = "localhost"
+	}
+	target := "http://" + host + (&url.URL{Path: d.Path}).EscapedPath()
+	if d.Query != "" {
+		target += "?" + d.Query
+	}
+	req, err := explain.NewRequest(d.Method, target, http.Header(d.Request.Headers), string(d.Request.Body))
+	if err != nil {
+		return nil, fmt.Errorf("rebuild request %s: %w", d.RequestID, err)
+	}
+	if d.Request.Headers["Content-Type"] == nil {
+		// Replay what was sent, not NewRequest's default for a bare body.
+		req.Header.Del("Content-Type")
+	}
+	req.Host = d.Host
+	req.RemoteAddr = net.JoinHostPort(d.ClientIP, "0")
+	return req, nil
+}
+
+// Cause names what decided d: the deciding rule, the contract, a category or
+// the total anomaly score, or the size limits.
+func Cause(d logging.Decision) string {
+	limited := d.StatusCode == http.StatusRequestEntityTooLarge || d.StatusCode == http.StatusRequestHeaderFieldsTooLarge
+	switch {
+	case d.ActionRule != "":
+		return "rule:" + d.ActionRule
+	case limited && len(d.MatchedRules) == 0:
+		return "limits"
+	case len(d.ContractViolations) > 0 && d.Action == string(policy.ActionBlock):
+		return "contract:" + d.ContractViolations[0].Type
+	case d.Category != "":
+		return "category:" + d.Category
+	case d.Action == string(policy.ActionAllow):
+		return "allowed"
+	default:
+		return "anomaly_score"
+	}
+}
+
+func blocked(d logging.Decision) bool {
+	return d.Action == string(policy.ActionBlock) || d.Action == string(policy.ActionRedirect)
+}
@@ -0,0 +1,153 @@
+package replay
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klyr/klyr/internal/config"
+	"github.com/klyr/klyr/internal/contract"
+	"github.com/klyr/klyr/internal/gateway"
+	"github.com/klyr/klyr/internal/logging"
+	"github.com/klyr/klyr/internal/report"
+)
+
+func replayConfig(upstreamURL string, threshold int, rules ...config.Rule) *config.Config {
+	return &config.Config{
+		Upstreams: []config.Upstream{{Name: "backend", URL: upstreamURL}},
+		Routes:    []config.Route{{Match: config.RouteMatch{PathPrefix: "/"}, Upstream: "backend", Policy: "default"}},
+		Policies: map[string]config.Policy{
+			"default": {Mode: config.ModeEnforce, AnomalyThreshold: threshold, Limits: config.Limits{MaxBodyBytes: 1024, MaxHeaderBytes: 4096, Timeout: time.Second}},
+		},
+		Logging: config.LoggingConfig{Replay: config.ReplayLogging{Enabled: true, MaxBodyBytes: 16}},
+		Rules:   rules,
+	}
+}
+
+func recordTraffic(t *testing.T) []logging.Decision {
+	t.Helper()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	cfg := replayConfig(backend.URL, 5,
+		config.Rule{ID: "quote", Score: 5, Tags: []string{"sqli"}, Condition: &config.Condition{Target: "ARGS", Op: "contains", Value: "'"}},
+	)
+	policyCfg := cfg.Policies["default"]
+	policyCfg.Mode = config.ModeShadow
+	cfg.Policies["default"] = policyCfg
+	gw, err := gateway.New(cfg)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	defer gw.Close()
+	var logs bytes.Buffer
+	gw.SetDecisionLogger(logging.NewDecisionLogger(&logs))
+
+	send := func(method, target, agent, body string) {
+		var req *http.Request
+		if body == "" {
+			req = httptest.NewRequest(method, target, nil)
+		} else {
+			req = httptest.NewRequest(method, target, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		req.Header.Set("User-Agent", agent)
+		req.Header.Set("Authorization", "Bearer secret")
+		req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+		gw.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	send(http.MethodGet, "http://shop.test/search?q=O'Brien", "curl", "")
+	send(http.MethodGet, "http://shop.test/search?q=shoes", "sqlmap/1.7", "")
+	send(http.MethodPost, "http://shop.test/login", "curl", "user=admin&comment=a very long comment")
+
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	if err := os.WriteFile(path, logs.Bytes(), 0o600); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+	reader := report.Reader{}
+	decisions, err := reader.Read(path)
+	if err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	return decisions
+}
+
+func TestRecordedRequest(t *testing.T) {
+	decisions := recordTraffic(t)
+	if len(decisions) != 3 {
+		t.Fatalf("expected 3 decisions, got %d", len(decisions))
+	}
+	rec := decisions[2].Request
+	if rec == nil {
+		t.Fatal("expected recorded request data")
+	}
+	if got := rec.Headers["Authorization"]; len(got) != 1 || got[0] != "<redacted>" {
+		t.Fatalf("expected redacted authorization, got %v", got)
+	}
+	if got := rec.Headers["Cookie"]; len(got) != 1 || got[0] != "session=<redacted>" {
+		t.Fatalf("expected cookie names only, got %v", got)
+	}
+	if string(rec.Body) != "user=admin&comme" || !rec.BodyTruncated {
+		t.Fatalf("expected body capped at 16 bytes, got %q truncated=%v", rec.Body, rec.BodyTruncated)
+	}
+}
+
+func TestRunReportsChanges(t *testing.T) {
+	decisions := recordTraffic(t)
+	decisions = append(decisions, logging.Decision{RequestID: "old", Method: http.MethodGet, Path: "/"})
+	decisions[2].Action, decisions[2].ActionRule = "block", "legacy"
+
+	candidate := replayConfig("http://127.0.0.1:1", 5,
+		config.Rule{ID: "scanner", Condition: &config.Condition{Target: "HEADERS:User-Agent", Op: "contains", Value: "sqlmap"}, Action: config.RuleAction{Type: config.RuleActionBlock}},
+	)
+	policyCfg := candidate.Policies["default"]
+	policyCfg.Mode = config.ModeShadow
+	candidate.Policies["default"] = policyCfg
+	gw, err := gateway.NewOffline(candidate)
+	if err != nil {
+		t.Fatalf("NewOffline error: %v", err)
+	}
+	if result := Run(gw, decisions); len(result.NewlyBlocked) != 0 {
+		t.Fatalf("expected shadow candidate to block nothing, got %+v", result.NewlyBlocked)
+	}
+
+	policyCfg.Mode = config.ModeEnforce
+	policyCfg.Contract.Path = filepath.Join(t.TempDir(), "contract.json")
+	candidate.Policies["default"] = policyCfg
+	enforced := contract.New("route-0", "default")
+	enforced.Methods["GET"] = true
+	enforced.Methods["POST"] = true
+	if err := contract.Save(policyCfg.Contract.Path, enforced); err != nil {
+		t.Fatalf("save contract: %v", err)
+	}
+	candidate.Rules = append(candidate.Rules, config.Rule{ID: "long-arg", Score: 5, Condition: &config.Condition{Target: "ARGS", Op: "lengthGt", Value: "6"}})
+	gw, err = gateway.NewOffline(candidate)
+	if err != nil {
+		t.Fatalf("NewOffline error: %v", err)
+	}
+	result := Run(gw, decisions)
+	if result.Total != 4 || result.Replayed != 3 || result.NoRequest != 1 || result.Truncated != 1 {
+		t.Fatalf("unexpected counts %+v", result)
+	}
+	if len(result.NewlyAllowed) != 1 || result.NewlyAllowed[0].BeforeCause != "rule:legacy" || result.NewlyAllowed[0].After != "allow" {
+		t.Fatalf("unexpected newly allowed %+v", result.NewlyAllowed)
+	}
+	if len(result.NewlyBlocked) != 2 || result.NewlyBlocked[0].AfterCause != "anomaly_score" || result.NewlyBlocked[1].AfterCause != "rule:scanner" {
+		t.Fatalf("unexpected newly blocked %+v", result.NewlyBlocked)
+	}
+
+	var out bytes.Buffer
+	if err := WriteText(&out, result); err != nil {
+		t.Fatalf("WriteText error: %v", err)
+	}
+	for _, want := range []string{"replayed 3 of 4 decisions (skipped 1 without request data)", "newly blocked: 2", "route route-0  rule:scanner  1", "newly allowed: 1"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in report:\n%s", want, out.String())
+		}
+	}
+}
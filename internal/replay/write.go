@@ -0,0 +1,79 @@
+package replay
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// maxExamples is the number of request IDs listed per group.
+const maxExamples = 3
+
+type group struct {
+	route    string
+	cause    string
+	requests []string
+}
+
+// WriteText writes the report with the changed requests grouped by route and
+// by the cause of the new outcome, or of the old one for newly allowed
+// requests.
+func WriteText(w io.Writer, report Report) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "replayed %d of %d decisions", report.Replayed, report.Total)
+	var skipped []string
+	if report.NoRequest > 0 {
+		skipped = append(skipped, fmt.Sprintf("%d without request data", report.NoRequest))
+	}
+	if report.RateLimited > 0 {
+		skipped = append(skipped, fmt.Sprintf("%d rate limited", report.RateLimited))
+	}
+	if report.NoRoute > 0 {
+		skipped = append(skipped, fmt.Sprintf("%d without a route in the candidate", report.NoRoute))
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(bw, " (skipped %s)", strings.Join(skipped, ", "))
+	}
+	fmt.Fprintln(bw)
+	if report.Truncated > 0 {
+		fmt.Fprintf(bw, "%d replayed with a truncated body\n", report.Truncated)
+	}
+
+	writeGroups(bw, "newly blocked", report.NewlyBlocked, func(c Change) string { return c.AfterCause })
+	writeGroups(bw, "newly allowed", report.NewlyAllowed, func(c Change) string { return c.BeforeCause })
+	return bw.Flush()
+}
+
+func writeGroups(w io.Writer, title string, changes []Change, cause func(Change) string) {
+	fmt.Fprintf(w, "\n%s: %d\n", title, len(changes))
+	index := map[[2]string]int{}
+	var groups []group
+	for _, c := range changes {
+		key := [2]string{c.RouteID, cause(c)}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, group{route: key[0], cause: key[1]})
+		}
+		groups[i].requests = append(groups[i].requests, c.RequestID)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if len(groups[i].requests) != len(groups[j].requests) {
+			return len(groups[i].requests) > len(groups[j].requests)
+		}
+		if groups[i].route != groups[j].route {
+			return groups[i].route < groups[j].route
+		}
+		return groups[i].cause < groups[j].cause
+	})
+	for _, g := range groups {
+		examples := g.requests
+		if len(examples) > maxExamples {
+			examples = examples[:maxExamples]
+		}
+		fmt.Fprintf(w, "  route %s  %s  %d  (%s)\n", g.route, g.cause, len(g.requests), strings.Join(examples, ", "))
+	}
+}
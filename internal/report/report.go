@@ -39,6 +39,9 @@ type LatencySummary struct {
 	P99 float64 `json:"p99"`
 }
 
+// maxLine bounds one decision log line.
+const maxLine = 16 << 20
+
 type Reader struct {
 	Since time.Time
 }
@@ -52,6 +55,8 @@ func (r *Reader) Read(path string) ([]logging.Decision, error) {
 
 	var decisions []logging.Decision
 	scanner := bufio.NewScanner(file)
+	// Decisions logged with logging.replay carry the request body.
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" {
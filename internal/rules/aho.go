@@ -2,8 +2,12 @@ package rules
 
 import "errors"
 
+// AhoMatcher matches one rule's patterns on its own. The engine scans aho
+// rules through a combined automaton instead; this matcher remains for rules
+// evaluated outside an engine.
 type AhoMatcher struct {
-	nodes []ahoNode
+	nodes    []ahoNode
+	patterns []string
 }
 
 type ahoNode struct {
@@ -48,18 +52,17 @@ func NewAhoMatcher(patterns []string) (*AhoMatcher, error) {
 
 		for b, next := range nodes[state].next {
 			fail := nodes[state].fail
-			for fail != 0 {
+			nodes[next].fail = 0
+			for {
 				if target, ok := nodes[fail].next[b]; ok {
-					fail = target
+					nodes[next].fail = target
+					break
+				}
+				if fail == 0 {
 					break
 				}
 				fail = nodes[fail].fail
 			}
-			if target, ok := nodes[fail].next[b]; ok {
-				nodes[next].fail = target
-			} else {
-				nodes[next].fail = 0
-			}
 			nodes[next].out = append(nodes[next].out, nodes[nodes[next].fail].out...)
 			queue = append(queue, next)
 		}
@@ -69,25 +72,24 @@ func NewAhoMatcher(patterns []string) (*AhoMatcher, error) {
 		return nil, errors.New("no non-empty patterns")
 	}
 
-	return &AhoMatcher{nodes: nodes}, nil
+	return &AhoMatcher{nodes: nodes, patterns: patterns}, nil
 }
 
 func (m *AhoMatcher) Match(input string) (bool, string) {
 	state := 0
 	for i := 0; i < len(input); i++ {
 		b := input[i]
-		for state != 0 {
+		for {
 			if next, ok := m.nodes[state].next[b]; ok {
 				state = next
 				break
 			}
+			if state == 0 {
+				break
+			}
 			state = m.nodes[state].fail
 		}
 
-		if next, ok := m.nodes[state].next[b]; ok {
-			state = next
-		}
-
 		if len(m.nodes[state].out) > 0 {
 			pattern := m.nodes[state].out[0]
 			return true, snippet(pattern)
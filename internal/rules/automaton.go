@@ -0,0 +1,122 @@
+package rules
+
+import "errors"
+
+// maxMatchedPatterns bounds how many distinct patterns are reported per rule.
+const maxMatchedPatterns = 8
+
+// automaton is an Aho-Corasick automaton with the failure transitions folded
+// into a dense state x byte-class table, so scanning is one table lookup per
+// input byte. Bytes that appear in no pattern share class 0.
+type automaton struct {
+	classes  [256]uint8
+	width    int
+	delta    []int32
+	output   []int32 // pattern ending at the state, or -1
+	dict     []int32 // nearest suffix state with an output, or -1
+	patterns []string
+}
+
+func newAutomaton(patterns []string) (*automaton, error) {
+	a := &automaton{}
+	next := 1
+	for _, p := range patterns {
+		for i := 0; i < len(p); i++ {
+			if a.classes[p[i]] == 0 {
+				if next > 255 {
+					return nil, errors.New("too many distinct pattern bytes")
+				}
+				a.classes[p[i]] = uint8(next)
+				next++
+			}
+		}
+	}
+	a.width = next
+
+	// Build the trie with -1 for missing edges.
+	a.delta = make([]int32, a.width)
+	a.output = []int32{-1}
+	for i := range a.delta {
+		a.delta[i] = -1
+	}
+	index := map[string]int32{}
+	for _, p := range patterns {
+		if p == "" {
+			continue
+		}
+		if _, dup := index[p]; dup {
+			continue
+		}
+		state := int32(0)
+		for i := 0; i < len(p); i++ {
+			slot := int(state)*a.width + int(a.classes[p[i]])
+			if a.delta[slot] < 0 {
+				a.delta[slot] = int32(len(a.output))
+				a.output = append(a.output, -1)
+				for j := 0; j < a.width; j++ {
+					a.delta = append(a.delta, -1)
+				}
+			}
+			state = a.delta[slot]
+		}
+		id := int32(len(a.patterns))
+		index[p] = id
+		a.patterns = append(a.patterns, p)
+		a.output[state] = id
+	}
+	if len(a.patterns) == 0 {
+		return nil, errors.New("no non-empty patterns")
+	}
+
+	// Breadth-first pass: fill missing edges from the failure state and link
+	// each state to its nearest suffix with an output.
+	states := len(a.output)
+	fail := make([]int32, states)
+	a.dict = make([]int32, states)
+	a.dict[0] = -1
+	queue := make([]int32, 0, states)
+	for c := 0; c < a.width; c++ {
+		if s := a.delta[c]; s > 0 {
+			fail[s] = 0
+			a.dict[s] = -1
+			queue = append(queue, s)
+		} else {
+			a.delta[c] = 0
+		}
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		base := int(state) * a.width
+		for c := 0; c < a.width; c++ {
+			s := a.delta[base+c]
+			fallback := a.delta[int(fail[state])*a.width+c]
+			if s < 0 {
+				a.delta[base+c] = fallback
+				continue
+			}
+			fail[s] = fallback
+			if a.output[fallback] >= 0 {
+				a.dict[s] = fallback
+			} else {
+				a.dict[s] = a.dict[fallback]
+			}
+			queue = append(queue, s)
+		}
+	}
+	return a, nil
+}
+
+// scan calls hit for every pattern occurrence in input, in input order.
+// Returning false from hit stops the scan.
+func (a *automaton) scan(input string, hit func(pattern int32) bool) {
+	state := int32(0)
+	for i := 0; i < len(input); i++ {
+		state = a.delta[int(state)*a.width+int(a.classes[input[i]])]
+		for s := state; s >= 0; s = a.dict[s] {
+			if id := a.output[s]; id >= 0 && !hit(id) {
+				return
+			}
+		}
+	}
+}
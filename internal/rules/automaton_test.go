@@ -0,0 +1,164 @@
+package rules
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAutomatonReportsOverlappingPatterns(t *testing.T) {
+	a, err := newAutomaton([]string{"he", "she", "his", "hers", "she"})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	var got []string
+	a.scan("ushers", func(id int32) bool {
+		got = append(got, a.patterns[id])
+		return true
+	})
+	expected := []string{"she", "he", "hers"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestAutomatonAgreesWithAhoMatcher(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	alphabet := "ab c'=1-"
+	randomString := func(n int) string {
+		var b strings.Builder
+		for i := 0; i < n; i++ {
+			b.WriteByte(alphabet[rng.Intn(len(alphabet))])
+		}
+		return b.String()
+	}
+
+	for round := 0; round < 2000; round++ {
+		patterns := make([]string, 1+rng.Intn(6))
+		for i := range patterns {
+			patterns[i] = randomString(1 + rng.Intn(4))
+		}
+		input := randomString(rng.Intn(40))
+
+		single, err := NewAhoMatcher(patterns)
+		if err != nil {
+			t.Fatalf("aho: %v", err)
+		}
+		combined, err := newAutomaton(patterns)
+		if err != nil {
+			t.Fatalf("automaton: %v", err)
+		}
+
+		want, _ := single.Match(input)
+		got := false
+		combined.scan(input, func(id int32) bool {
+			if !strings.Contains(input, combined.patterns[id]) {
+				t.Fatalf("reported %q not in %q", combined.patterns[id], input)
+			}
+			got = true
+			return false
+		})
+		if got != want {
+			t.Fatalf("patterns %q input %q: automaton %v, aho %v", patterns, input, got, want)
+		}
+	}
+}
+
+func TestEngineCombinesAhoRules(t *testing.T) {
+	sqli, _ := NewAhoMatcher([]string{"union select", "or 1=1", "sleep("})
+	traversal, _ := NewAhoMatcher([]string{"../", "/etc/passwd"})
+	shared, _ := NewAhoMatcher([]string{"or 1=1"})
+	caseSensitive, _ := NewAhoMatcher([]string{"UNION SELECT"})
+
+	engine := NewEngine([]Rule{
+		{ID: "sqli", Phase: PhaseQuery, Score: 3, Transforms: []Transform{TransformLowercase}, Matcher: sqli},
+		{ID: "traversal", Phase: PhaseQuery, Score: 2, Transforms: []Transform{TransformLowercase}, Matcher: traversal},
+		{ID: "shared", Phase: PhaseQuery, Score: 1, Transforms: []Transform{TransformLowercase}, Matcher: shared},
+		{ID: "raw", Phase: PhaseQuery, Score: 1, Matcher: caseSensitive},
+	})
+	if len(engine.groups) != 2 {
+		t.Fatalf("expected 2 groups (lowercase and raw), got %d", len(engine.groups))
+	}
+
+	result := engine.Evaluate(EvalContext{Query: Field{Raw: "q=1 OR 1=1 UNION SELECT sleep(5) -- or 1=1"}})
+	if result.Score != 5 {
+		t.Fatalf("expected score 5, got %d: %+v", result.Score, result.Matches)
+	}
+	if len(result.Matches) != 3 {
+		t.Fatalf("expected sqli, shared and raw matches, got %+v", result.Matches)
+	}
+	if got := result.Matches[0].Patterns; !reflect.DeepEqual(got, []string{"or 1=1", "union select", "sleep("}) {
+		t.Fatalf("expected every sqli pattern once in order, got %v", got)
+	}
+	if result.Matches[0].Evidence != "or 1=1" {
+		t.Fatalf("expected first pattern as evidence, got %q", result.Matches[0].Evidence)
+	}
+	if result.Matches[1].RuleID != "shared" || result.Matches[2].RuleID != "raw" {
+		t.Fatalf("expected matches in rule order, got %+v", result.Matches)
+	}
+}
+
+func TestEngineBoundsMatchedPatterns(t *testing.T) {
+	patterns := make([]string, 20)
+	for i := range patterns {
+		patterns[i] = fmt.Sprintf("p%02d", i)
+	}
+	matcher, _ := NewAhoMatcher(patterns)
+	engine := NewEngine([]Rule{{ID: "many", Phase: PhaseBody, Score: 1, Matcher: matcher}})
+
+	result := engine.Evaluate(EvalContext{Body: Field{Raw: strings.Join(patterns, " ")}})
+	if len(result.Matches) != 1 || len(result.Matches[0].Patterns) != maxMatchedPatterns {
+		t.Fatalf("expected %d patterns, got %+v", maxMatchedPatterns, result.Matches)
+	}
+}
+
+func benchmarkRules(b *testing.B) ([]Rule, EvalContext) {
+	rng := rand.New(rand.NewSource(7))
+	words := []string{"select", "union", "script", "alert", "passwd", "onerror", "eval", "sleep", "benchmark", "exec"}
+	rules := make([]Rule, 0, 8)
+	for r := 0; r < 8; r++ {
+		patterns := make([]string, 0, 200)
+		for i := 0; i < 200; i++ {
+			patterns = append(patterns, fmt.Sprintf("%s%d%s", words[rng.Intn(len(words))], r*1000+i, words[rng.Intn(len(words))]))
+		}
+		matcher, err := NewAhoMatcher(patterns)
+		if err != nil {
+			b.Fatalf("aho: %v", err)
+		}
+		rules = append(rules, Rule{ID: fmt.Sprintf("rule-%d", r), Phase: PhaseBody, Score: 1, Transforms: []Transform{TransformLowercase}, Matcher: matcher})
+	}
+
+	var body strings.Builder
+	for body.Len() < 16<<10 {
+		body.WriteString(words[rng.Intn(len(words))])
+		body.WriteString(" lorem ipsum dolor sit amet ")
+	}
+	return rules, EvalContext{Body: Field{Raw: body.String()}}
+}
+
+// BenchmarkAhoPerRule is the previous evaluation path: every rule normalizes
+// and scans the input with its own map-based matcher.
+func BenchmarkAhoPerRule(b *testing.B) {
+	rules, ctx := benchmarkRules(b)
+	b.SetBytes(int64(len(ctx.Body.Raw)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, rule := range rules {
+			normalized, _ := applyTransforms(ctx.Body.Raw, rule.Transforms)
+			rule.Matcher.Match(normalized)
+		}
+	}
+}
+
+func BenchmarkAhoCombined(b *testing.B) {
+	rules, ctx := benchmarkRules(b)
+	engine := NewEngine(rules)
+	b.SetBytes(int64(len(ctx.Body.Raw)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.Evaluate(ctx)
+	}
+}
@@ -0,0 +1,104 @@
+package rules
+
+import (
+	"time"
+
+	"github.com/klyr/klyr/internal/config"
+)
+
+// BudgetRuleID is the rule ID of the match recorded when a request exceeds
+// its inspection budget and the policy fails closed or adds a penalty.
+const BudgetRuleID = "inspection-budget"
+
+// Reasons a budget is exceeded.
+const (
+	BudgetBytes = "bytes"
+	BudgetTime  = "time"
+)
+
+// Budget bounds the inspection of one request. It is checked before each
+// rule, so a single rule is never interrupted. Zero limits are unlimited.
+type Budget struct {
+	MaxBytes int64
+	MaxTime  time.Duration
+	OnExceed string
+	Penalty  int
+}
+
+func NewBudget(raw config.InspectionBudget) Budget {
+	return Budget{MaxBytes: raw.MaxBytes, MaxTime: raw.MaxTime, OnExceed: raw.OnExceed, Penalty: raw.Penalty}
+}
+
+type budgetTracker struct {
+	budget  Budget
+	start   time.Time
+	scanned int64
+	charged map[Phase]bool
+}
+
+func newBudgetTracker(budget Budget) *budgetTracker {
+	t := &budgetTracker{budget: budget}
+	if budget.MaxTime > 0 {
+		t.start = time.Now()
+	}
+	return t
+}
+
+// charge accounts for scanning n more bytes and returns the reason the
+// budget would be exceeded, or "".
+func (t *budgetTracker) charge(n int) string {
+	if t.budget.MaxTime > 0 && time.Since(t.start) > t.budget.MaxTime {
+		return BudgetTime
+	}
+	if t.budget.MaxBytes > 0 && t.scanned+int64(n) > t.budget.MaxBytes {
+		return BudgetBytes
+	}
+	t.scanned += int64(n)
+	return ""
+}
+
+// exceeded records reason on result and applies the budget's OnExceed.
+func (t *budgetTracker) exceeded(result *Result, reason string) {
+	result.BudgetExceeded = reason
+	match := Match{RuleID: BudgetRuleID, Phase: PhaseRequest, Evidence: "budget:" + reason}
+	switch t.budget.OnExceed {
+	case config.BudgetFailClosed:
+		match.Action = Action{Type: ActionBlock}
+	case config.BudgetScore:
+		match.Score = t.budget.Penalty
+		result.Score += match.Score
+	default:
+		return
+	}
+	result.Matches = append(result.Matches, match)
+}
+
+// requestPhases are the inputs a PhaseRequest rule may read.
+var requestPhases = []Phase{PhaseRequestLine, PhaseHeaders, PhaseQuery, PhaseBody}
+
+// chargePhase accounts for the phase inputs a rule on phase scans, charging
+// each input at most once per request however many rules read it, and
+// returns the reason the budget would be exceeded, or "".
+func (t *budgetTracker) chargePhase(ctx EvalContext, phase Phase) string {
+	phases := []Phase{phase}
+	if phase == PhaseRequest {
+		phases = requestPhases
+	}
+	n := 0
+	for _, p := range phases {
+		if !t.charged[p] {
+			input, _ := selectPhaseInput(ctx, p)
+			n += len(input)
+		}
+	}
+	if reason := t.charge(n); reason != "" {
+		return reason
+	}
+	if t.charged == nil {
+		t.charged = map[Phase]bool{}
+	}
+	for _, p := range phases {
+		t.charged[p] = true
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klyr/klyr/internal/config"
+)
+
+func budgetEngine(t *testing.T) *Engine {
+	t.Helper()
+	query, err := NewRegexMatcher("(?i)select")
+	if err != nil {
+		t.Fatalf("regex compile: %v", err)
+	}
+	body, err := NewRegexMatcher("(?i)union")
+	if err != nil {
+		t.Fatalf("regex compile: %v", err)
+	}
+	return NewEngine([]Rule{
+		{ID: "query", Phase: PhaseQuery, Score: 2, Matcher: query},
+		{ID: "body", Phase: PhaseBody, Score: 3, Matcher: body},
+	})
+}
+
+func TestEngineInspectionBudget(t *testing.T) {
+	ctx := EvalContext{Query: Field{Raw: "q=select"}, Body: Field{Raw: strings.Repeat("x", 100) + " union"}}
+	cases := []struct {
+		name      string
+		budget    Budget
+		wantScore int
+		wantRules []string
+		exceeded  string
+	}{
+		{"unlimited", Budget{}, 5, []string{"query", "body"}, ""},
+		{"fail open", Budget{MaxBytes: 64}, 2, []string{"query"}, BudgetBytes},
+		{"fail closed", Budget{MaxBytes: 64, OnExceed: config.BudgetFailClosed}, 2, []string{"query", BudgetRuleID}, BudgetBytes},
+		{"penalty", Budget{MaxBytes: 64, OnExceed: config.BudgetScore, Penalty: 4}, 6, []string{"query", BudgetRuleID}, BudgetBytes},
+		{"time", Budget{MaxTime: time.Nanosecond}, 0, nil, BudgetTime},
+	}
+
+	engine := budgetEngine(t)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := engine.EvaluateWith(ctx, EvalOptions{Budget: tc.budget})
+			if result.Score != tc.wantScore || result.BudgetExceeded != tc.exceeded || len(result.Matches) != len(tc.wantRules) {
+				t.Fatalf("unexpected result %+v", result)
+			}
+			for i, id := range tc.wantRules {
+				if result.Matches[i].RuleID != id {
+					t.Fatalf("expected match %d to be %s, got %+v", i, id, result.Matches)
+				}
+			}
+		})
+	}
+
+	result := engine.EvaluateWith(ctx, EvalOptions{Budget: Budget{MaxBytes: 64, OnExceed: config.BudgetFailClosed}})
+	if last := result.Matches[len(result.Matches)-1]; last.Action.Type != ActionBlock || last.Evidence != "budget:bytes" {
+		t.Fatalf("expected a blocking budget match, got %+v", last)
+	}
+}
+
+func TestEngineBudgetChargesEachPhaseOnce(t *testing.T) {
+	var rules []Rule
+	for _, id := range []string{"get-1", "get-2", "get-3", "get-4"} {
+		matcher, err := NewConditionMatcher(config.Condition{Target: "METHOD", Op: "equals", Value: "GET"}, nil)
+		if err != nil {
+			t.Fatalf("condition: %v", err)
+		}
+		rules = append(rules, Rule{ID: id, Phase: PhaseRequest, Score: 1, Matcher: matcher})
+	}
+	engine := NewEngine(rules)
+	ctx := EvalContext{
+		RequestLine: Field{Raw: "GET /search?q=shoes HTTP/1.1"},
+		Headers:     Field{Raw: "Host: shop.test\r\nUser-Agent: curl/8.0\r\n"},
+		Query:       Field{Raw: "q=shoes"},
+		Method:      "GET",
+	}
+	size := int64(len(ctx.RequestLine.Raw) + len(ctx.Headers.Raw) + len(ctx.Query.Raw))
+
+	result := engine.EvaluateWith(ctx, EvalOptions{Budget: Budget{MaxBytes: size, OnExceed: config.BudgetFailClosed}})
+	if result.BudgetExceeded != "" || result.Score != 4 || len(result.Matches) != 4 {
+		t.Fatalf("expected every rule to fit a budget of one request, got %+v", result)
+	}
+	result = engine.EvaluateWith(ctx, EvalOptions{Budget: Budget{MaxBytes: size - 1}})
+	if result.BudgetExceeded != BudgetBytes || len(result.Matches) != 0 {
+		t.Fatalf("expected a budget below the request size to be exceeded, got %+v", result)
+	}
+}
@@ -24,7 +24,7 @@ func BuildEngine(cfg *config.Config, baseDir string) (*Engine, error) {
 		rules = append(rules, compiled)
 	}
 
-	return &Engine{Rules: rules}, nil
+	return NewEngine(rules), nil
 }
 
 func compileRule(raw config.Rule, baseDir string) (Rule, error) {
@@ -37,13 +37,32 @@ func compileRule(raw config.Rule, baseDir string) (Rule, error) {
 	}
 
 	var matcher Matcher
-	switch matchType {
-	case MatchRegex:
+	switch {
+	case raw.Condition != nil:
+		if phase == "" {
+			phase = PhaseRequest
+		}
+		matcher, err = NewConditionMatcher(*raw.Condition, transforms)
+	case matchType == MatchRegex:
 		if raw.Match.Pattern == "" {
 			return Rule{}, fmt.Errorf("regex pattern is required")
 		}
 		matcher, err = NewRegexMatcher(raw.Match.Pattern)
-	case MatchAho:
+	case matchType == MatchSignal:
+		if phase == "" {
+			phase = PhaseRequest
+		}
+		matcher, err = NewSignalMatcher(phase, raw.Match.Signals)
+	case matchType == MatchSQLi || matchType == MatchXSS:
+		if phase == "" {
+			phase = PhaseRequest
+		}
+		if matchType == MatchSQLi {
+			matcher = NewSQLiMatcher(phase)
+		} else {
+			matcher = NewXSSMatcher(phase)
+		}
+	case matchType == MatchAho:
 		if raw.Match.PatternsFile == "" {
 			return Rule{}, fmt.Errorf("patternsFile is required")
 		}
@@ -67,6 +86,13 @@ func compileRule(raw config.Rule, baseDir string) (Rule, error) {
 		Tags:       append([]string(nil), raw.Tags...),
 		Transforms: transforms,
 		Matcher:    matcher,
+		Action: Action{
+			Type:       ActionType(raw.Action.Type),
+			StatusCode: raw.Action.StatusCode,
+			Location:   raw.Action.Location,
+			Delay:      raw.Action.Delay,
+		},
+		ParanoiaLevel: raw.ParanoiaLevel,
 	}, nil
 }
 
@@ -75,7 +101,10 @@ func mapTransforms(raw []string) ([]Transform, error) {
 	for _, item := range raw {
 		transform := Transform(strings.TrimSpace(item))
 		switch transform {
-		case TransformLowercase, TransformHTMLEntity, TransformPathNormalize:
+		case TransformLowercase, TransformHTMLEntity, TransformPathNormalize,
+			TransformBase64Decode, TransformHexDecode, TransformURLDecodeUni,
+			TransformUnicodeNFKC, TransformFoldLookalikes, TransformCompressWhitespace, TransformRemoveNulls,
+			TransformReplaceComments, TransformCmdline, TransformJSDecode:
 			out = append(out, transform)
 		default:
 			return nil, fmt.Errorf("unknown transform %q", item)
@@ -87,7 +116,7 @@ func mapTransforms(raw []string) ([]Transform, error) {
 func applyPatternTransforms(patterns []string, transforms []Transform) []string {
 	lower := false
 	for _, t := range transforms {
-		if t == TransformLowercase {
+		if t == TransformLowercase || t == TransformCmdline {
 			lower = true
 			break
 		}
@@ -0,0 +1,424 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/klyr/klyr/internal/config"
+)
+
+// ConditionMatcher evaluates a compiled condition tree against the structured
+// fields of an EvalContext.
+type ConditionMatcher struct {
+	root *condition
+}
+
+type condition struct {
+	all []*condition
+	any []*condition
+	not *condition
+
+	target   string
+	selector string
+	op       string
+	values   []string
+	res      []*regexp.Regexp
+	numbers  []float64
+	lengths  []int
+	networks []*net.IPNet
+}
+
+// targetValue is one value selected by a target, labelled for evidence.
+type targetValue struct {
+	label string
+	value string
+}
+
+// NewConditionMatcher compiles cond. Comparison values are lowercased when
+// transforms include lowercase so they line up with the transformed input.
+func NewConditionMatcher(cond config.Condition, transforms []Transform) (*ConditionMatcher, error) {
+	lower := false
+	for _, t := range transforms {
+		if t == TransformLowercase || t == TransformCmdline {
+			lower = true
+		}
+	}
+	root, err := compileCondition(cond, lower, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &ConditionMatcher{root: root}, nil
+}
+
+func compileCondition(raw config.Condition, lower bool, depth int) (*condition, error) {
+	if depth >= config.MaxConditionDepth {
+		return nil, fmt.Errorf("condition nests deeper than %d levels", config.MaxConditionDepth)
+	}
+
+	c := &condition{}
+	switch {
+	case len(raw.All) > 0:
+		for _, child := range raw.All {
+			compiled, err := compileCondition(child, lower, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			c.all = append(c.all, compiled)
+		}
+		return c, nil
+	case len(raw.Any) > 0:
+		for _, child := range raw.Any {
+			compiled, err := compileCondition(child, lower, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			c.any = append(c.any, compiled)
+		}
+		return c, nil
+	case raw.Not != nil:
+		compiled, err := compileCondition(*raw.Not, lower, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		c.not = compiled
+		return c, nil
+	case raw.Target == "":
+		return nil, fmt.Errorf("condition must set all, any, not or target")
+	}
+
+	if err := config.ValidateTarget(raw.Target); err != nil {
+		return nil, err
+	}
+	c.target, c.selector, _ = strings.Cut(raw.Target, ":")
+
+	c.op = raw.Op
+	c.values = append([]string(nil), raw.Values...)
+	if raw.Value != "" {
+		c.values = append([]string{raw.Value}, c.values...)
+	}
+	if c.op != config.OpExists && len(c.values) == 0 {
+		return nil, fmt.Errorf("op %s needs a value", c.op)
+	}
+
+	for _, value := range c.values {
+		switch c.op {
+		case config.OpRegex:
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, err
+			}
+			c.res = append(c.res, re)
+		case config.OpEquals, config.OpContains, config.OpStartsWith, config.OpEndsWith:
+		case config.OpGreater, config.OpLess:
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("op %s: %w", c.op, err)
+			}
+			c.numbers = append(c.numbers, n)
+		case config.OpLengthGt, config.OpLengthLt:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("op %s: %w", c.op, err)
+			}
+			c.lengths = append(c.lengths, n)
+		case config.OpIPInCIDR:
+			_, network, err := net.ParseCIDR(value)
+			if err != nil {
+				return nil, err
+			}
+			c.networks = append(c.networks, network)
+		default:
+			return nil, fmt.Errorf("unknown op %q", c.op)
+		}
+	}
+	if lower && c.op != config.OpRegex {
+		for i, value := range c.values {
+			c.values[i] = strings.ToLower(value)
+		}
+	}
+	return c, nil
+}
+
+// Match never matches: conditions need the structured context.
+func (m *ConditionMatcher) Match(string) (bool, string) {
+	return false, ""
+}
+
+func (m *ConditionMatcher) MatchContext(ctx EvalContext, transforms []Transform) (bool, string) {
+	return m.root.eval(ctx, transforms)
+}
+
+func (c *condition) eval(ctx EvalContext, transforms []Transform) (bool, string) {
+	switch {
+	case c.all != nil:
+		evidence := ""
+		for _, child := range c.all {
+			matched, childEvidence := child.eval(ctx, transforms)
+			if !matched {
+				return false, ""
+			}
+			if evidence == "" {
+				evidence = childEvidence
+			}
+		}
+		return true, evidence
+	case c.any != nil:
+		for _, child := range c.any {
+			if matched, evidence := child.eval(ctx, transforms); matched {
+				return true, evidence
+			}
+		}
+		return false, ""
+	case c.not != nil:
+		matched, _ := c.not.eval(ctx, transforms)
+		return !matched, ""
+	}
+
+	values := c.selectValues(ctx)
+	if c.op == config.OpExists {
+		if len(values) == 0 {
+			return false, ""
+		}
+		return true, c.evidence(values[0])
+	}
+	for _, tv := range values {
+		value := tv.value
+		if len(transforms) > 0 {
+			normalized, err := applyTransforms(value, transforms)
+			if err != nil {
+				continue
+			}
+			value = normalized
+		}
+		if c.test(value) {
+			return true, c.evidence(targetValue{label: tv.label, value: value})
+		}
+	}
+	return false, ""
+}
+
+func (c *condition) needsBody() bool {
+	for _, child := range append(append([]*condition(nil), c.all...), c.any...) {
+		if child.needsBody() {
+			return true
+		}
+	}
+	if c.not != nil {
+		return c.not.needsBody()
+	}
+	switch c.target {
+	case config.TargetBody, config.TargetBodyJSON, config.TargetArgs, config.TargetArgsNames:
+		return true
+	}
+	return false
+}
+
+func (c *condition) test(value string) bool {
+	switch c.op {
+	case config.OpRegex:
+		for _, re := range c.res {
+			if re.MatchString(value) {
+				return true
+			}
+		}
+	case config.OpEquals:
+		for _, want := range c.values {
+			if value == want {
+				return true
+			}
+		}
+	case config.OpContains:
+		for _, want := range c.values {
+			if strings.Contains(value, want) {
+				return true
+			}
+		}
+	case config.OpStartsWith:
+		for _, want := range c.values {
+			if strings.HasPrefix(value, want) {
+				return true
+			}
+		}
+	case config.OpEndsWith:
+		for _, want := range c.values {
+			if strings.HasSuffix(value, want) {
+				return true
+			}
+		}
+	case config.OpGreater, config.OpLess:
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return false
+		}
+		for _, want := range c.numbers {
+			if (c.op == config.OpGreater && n > want) || (c.op == config.OpLess && n < want) {
+				return true
+			}
+		}
+	case config.OpLengthGt:
+		for _, want := range c.lengths {
+			if len(value) > want {
+				return true
+			}
+		}
+	case config.OpLengthLt:
+		for _, want := range c.lengths {
+			if len(value) < want {
+				return true
+			}
+		}
+	case config.OpIPInCIDR:
+		ip := net.ParseIP(strings.TrimSpace(value))
+		if ip == nil {
+			return false
+		}
+		for _, network := range c.networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *condition) selectValues(ctx EvalContext) []targetValue {
+	switch c.target {
+	case config.TargetArgs:
+		return namedValues(c.target, c.selector, ctx.Args)
+	case config.TargetArgsNames:
+		names := sortedKeys(ctx.Args)
+		out := make([]targetValue, len(names))
+		for i, name := range names {
+			out[i] = targetValue{label: c.target, value: name}
+		}
+		return out
+	case config.TargetHeaders:
+		headers := make(map[string][]string, len(ctx.Header))
+		for name, values := range ctx.Header {
+			headers[strings.ToLower(name)] = values
+		}
+		return namedValues(c.target, strings.ToLower(c.selector), headers)
+	case config.TargetCookies:
+		return namedValues(c.target, c.selector, ctx.Cookies)
+	case config.TargetPath:
+		return []targetValue{{label: c.target, value: ctx.Path}}
+	case config.TargetMethod:
+		return []targetValue{{label: c.target, value: ctx.Method}}
+	case config.TargetQuery:
+		return []targetValue{{label: c.target, value: ctx.Query.Raw}}
+	case config.TargetBody:
+		if ctx.Body.Raw == "" {
+			return nil
+		}
+		return []targetValue{{label: c.target, value: ctx.Body.Raw}}
+	case config.TargetRemoteAddr:
+		return []targetValue{{label: c.target, value: ctx.RemoteAddr}}
+	case config.TargetBodyJSON:
+		value, ok := lookupJSON(ctx.JSON, c.selector)
+		if !ok {
+			return nil
+		}
+		label := c.target + ":" + c.selector
+		if items, isArray := value.([]any); isArray {
+			out := make([]targetValue, 0, len(items))
+			for _, item := range items {
+				out = append(out, targetValue{label: label, value: jsonString(item)})
+			}
+			return out
+		}
+		return []targetValue{{label: label, value: jsonString(value)}}
+	}
+	return nil
+}
+
+func namedValues(target, selector string, values map[string][]string) []targetValue {
+	if selector != "" {
+		out := make([]targetValue, 0, len(values[selector]))
+		for _, value := range values[selector] {
+			out = append(out, targetValue{label: target + ":" + selector, value: value})
+		}
+		return out
+	}
+	var out []targetValue
+	for _, name := range sortedKeys(values) {
+		for _, value := range values[name] {
+			out = append(out, targetValue{label: target + ":" + name, value: value})
+		}
+	}
+	return out
+}
+
+func sortedKeys(values map[string][]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// evidence labels the matched value, redacting cookies and credential headers.
+func (c *condition) evidence(tv targetValue) string {
+	value := tv.value
+	switch c.target {
+	case config.TargetCookies:
+		value = "<redacted>"
+	case config.TargetHeaders:
+		switch strings.ToLower(strings.TrimPrefix(tv.label, c.target+":")) {
+		case "authorization", "cookie", "set-cookie":
+			value = "<redacted>"
+		}
+	}
+	return snippet(tv.label + "=" + value)
+}
+
+func lookupJSON(doc any, pointer string) (any, bool) {
+	if doc == nil {
+		return nil, false
+	}
+	current := doc
+	for _, token := range strings.Split(pointer, "/")[1:] {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch node := current.(type) {
+		case map[string]any:
+			next, ok := node[token]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			current = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func jsonString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+}
@@ -0,0 +1,134 @@
+package rules
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/klyr/klyr/internal/config"
+)
+
+func conditionContext() EvalContext {
+	return EvalContext{
+		Method:     "POST",
+		Path:       "/api/login",
+		RemoteAddr: "10.1.2.3",
+		Args:       url.Values{"username": {"admin"}, "page": {"12"}},
+		Header: http.Header{
+			"User-Agent":    {"sqlmap/1.7"},
+			"Authorization": {"Bearer secret"},
+		},
+		Cookies: url.Values{"session": {"abc"}},
+		JSON: map[string]any{
+			"user": map[string]any{"name": "Robert'); DROP TABLE"},
+			"tags": []any{"a", "b"},
+		},
+	}
+}
+
+func TestConditionOperators(t *testing.T) {
+	cases := []struct {
+		name string
+		cond config.Condition
+		want bool
+	}{
+		{"equals arg", config.Condition{Target: "ARGS:username", Op: "equals", Value: "admin"}, true},
+		{"equals miss", config.Condition{Target: "ARGS:username", Op: "equals", Value: "root"}, false},
+		{"any arg", config.Condition{Target: "ARGS", Op: "equals", Value: "12"}, true},
+		{"arg names", config.Condition{Target: "ARGS_NAMES", Op: "equals", Values: []string{"debug", "page"}}, true},
+		{"header prefix", config.Condition{Target: "HEADERS:user-agent", Op: "startsWith", Value: "sqlmap"}, true},
+		{"header suffix", config.Condition{Target: "HEADERS:User-Agent", Op: "endsWith", Value: "1.7"}, true},
+		{"cookie exists", config.Condition{Target: "COOKIES:session", Op: "exists"}, true},
+		{"cookie missing", config.Condition{Target: "COOKIES:other", Op: "exists"}, false},
+		{"path contains", config.Condition{Target: "PATH", Op: "contains", Value: "login"}, true},
+		{"method", config.Condition{Target: "METHOD", Op: "equals", Values: []string{"PUT", "POST"}}, true},
+		{"gt", config.Condition{Target: "ARGS:page", Op: "gt", Value: "10"}, true},
+		{"lt", config.Condition{Target: "ARGS:page", Op: "lt", Value: "10"}, false},
+		{"gt non number", config.Condition{Target: "ARGS:username", Op: "gt", Value: "0"}, false},
+		{"length", config.Condition{Target: "BODY_JSON:/user/name", Op: "lengthGt", Value: "10"}, true},
+		{"json regex", config.Condition{Target: "BODY_JSON:/user/name", Op: "regex", Value: `(?i)drop\s+table`}, true},
+		{"json array item", config.Condition{Target: "BODY_JSON:/tags", Op: "equals", Value: "b"}, true},
+		{"json missing", config.Condition{Target: "BODY_JSON:/user/id", Op: "exists"}, false},
+		{"cidr", config.Condition{Target: "REMOTE_ADDR", Op: "ipInCIDR", Values: []string{"192.168.0.0/16", "10.0.0.0/8"}}, true},
+		{"cidr miss", config.Condition{Target: "REMOTE_ADDR", Op: "ipInCIDR", Value: "172.16.0.0/12"}, false},
+	}
+
+	ctx := conditionContext()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := NewConditionMatcher(tc.cond, nil)
+			if err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			if got, _ := m.MatchContext(ctx, nil); got != tc.want {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestConditionCompound(t *testing.T) {
+	cond := config.Condition{All: []config.Condition{
+		{Target: "PATH", Op: "startsWith", Value: "/api/"},
+		{Any: []config.Condition{
+			{Target: "ARGS:username", Op: "equals", Value: "root"},
+			{Target: "HEADERS:User-Agent", Op: "contains", Value: "sqlmap"},
+		}},
+		{Not: &config.Condition{Target: "REMOTE_ADDR", Op: "ipInCIDR", Value: "127.0.0.0/8"}},
+	}}
+	m, err := NewConditionMatcher(cond, nil)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	ctx := conditionContext()
+	matched, evidence := m.MatchContext(ctx, nil)
+	if !matched {
+		t.Fatalf("expected compound condition to match")
+	}
+	if evidence != "PATH=/api/login" {
+		t.Fatalf("unexpected evidence %q", evidence)
+	}
+
+	ctx.RemoteAddr = "127.0.0.1"
+	if matched, _ := m.MatchContext(ctx, nil); matched {
+		t.Fatalf("expected not to exclude loopback clients")
+	}
+}
+
+func TestConditionTransformsAndRedaction(t *testing.T) {
+	m, err := NewConditionMatcher(config.Condition{Target: "HEADERS", Op: "contains", Value: "BEARER"}, []Transform{TransformLowercase})
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	matched, evidence := m.MatchContext(conditionContext(), []Transform{TransformLowercase})
+	if !matched {
+		t.Fatalf("expected lowercase transform to apply to both sides")
+	}
+	if strings.Contains(evidence, "secret") || evidence != "HEADERS:authorization=<redacted>" {
+		t.Fatalf("expected redacted evidence, got %q", evidence)
+	}
+}
+
+func TestEngineEvaluatesConditionRules(t *testing.T) {
+	cfg := &config.Config{Rules: []config.Rule{
+		{ID: "admin-login", Score: 4, Condition: &config.Condition{Target: "ARGS:username", Op: "equals", Value: "admin"}},
+		{ID: "json-body", Score: 1, Condition: &config.Condition{Target: "BODY_JSON:/user/name", Op: "exists"}},
+	}}
+	engine, err := BuildEngine(cfg, "")
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if !engine.NeedsBody() {
+		t.Fatalf("expected BODY_JSON condition to need the body")
+	}
+
+	result := engine.Evaluate(conditionContext())
+	if result.Score != 5 || len(result.Matches) != 2 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if result.Matches[0].Phase != PhaseRequest || result.Matches[0].Evidence != "ARGS:username=admin" {
+		t.Fatalf("unexpected match %+v", result.Matches[0])
+	}
+}
@@ -1,5 +1,10 @@
 package rules
 
+import (
+	"net/http"
+	"net/url"
+)
+
 type Field struct {
 	Raw        string
 	Normalized string
@@ -10,4 +15,14 @@ type EvalContext struct {
 	Headers     Field
 	Query       Field
 	Body        Field
+
+	// Structured request data read by condition rules. Args holds query and
+	// urlencoded form parameters; JSON is the decoded body or nil.
+	Method     string
+	Path       string
+	RemoteAddr string
+	Args       url.Values
+	Header     http.Header
+	Cookies    url.Values
+	JSON       any
 }
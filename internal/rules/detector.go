@@ -0,0 +1,135 @@
+package rules
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/klyr/klyr/internal/config"
+	"github.com/klyr/klyr/internal/sqli"
+	"github.com/klyr/klyr/internal/xss"
+)
+
+// maxJSONValues bounds the JSON strings a detector inspects per request.
+const maxJSONValues = 256
+
+// DetectorMatcher runs a detector over each value of its phase, such as one
+// query argument, header or JSON string, instead of the raw phase input, so
+// the detector sees values the way the application does. PhaseRequest
+// checks every phase.
+type DetectorMatcher struct {
+	name   string
+	phase  Phase
+	detect func(string) (bool, string)
+}
+
+// NewSQLiMatcher matches values whose SQL token fingerprint is a known
+// injection. The evidence is the fingerprint.
+func NewSQLiMatcher(phase Phase) *DetectorMatcher {
+	return &DetectorMatcher{name: string(MatchSQLi), phase: phase, detect: sqli.Detect}
+}
+
+// NewXSSMatcher matches values that would run script when reflected into
+// HTML. The evidence is the offending tag, attribute or URL scheme.
+func NewXSSMatcher(phase Phase) *DetectorMatcher {
+	return &DetectorMatcher{name: string(MatchXSS), phase: phase, detect: xss.Detect}
+}
+
+func (m *DetectorMatcher) Match(input string) (bool, string) {
+	if ok, token := m.detect(input); ok {
+		return true, snippet(m.name + ":" + token)
+	}
+	return false, ""
+}
+
+func (m *DetectorMatcher) MatchContext(ctx EvalContext, transforms []Transform) (bool, string) {
+	for _, tv := range phaseValues(ctx, m.phase) {
+		value := tv.value
+		if len(transforms) > 0 {
+			normalized, err := applyTransforms(value, transforms)
+			if err != nil {
+				continue
+			}
+			value = normalized
+		}
+		if ok, token := m.detect(value); ok {
+			return true, snippet(m.name + ":" + token + " " + tv.label)
+		}
+	}
+	return false, ""
+}
+
+func phaseValues(ctx EvalContext, phase Phase) []targetValue {
+	switch phase {
+	case PhaseRequestLine:
+		path := ctx.Path
+		if path == "" {
+			path = ctx.RequestLine.Raw
+		}
+		return []targetValue{{label: config.TargetPath, value: path}}
+	case PhaseQuery:
+		args, _ := url.ParseQuery(ctx.Query.Raw)
+		return namedValues(config.TargetArgs, "", args)
+	case PhaseHeaders:
+		header := map[string][]string{}
+		if ctx.Header != nil {
+			for name, values := range ctx.Header {
+				header[strings.ToLower(name)] = values
+			}
+		} else {
+			for _, line := range strings.Split(ctx.Headers.Raw, "\n") {
+				if name, value, ok := strings.Cut(line, ":"); ok {
+					name = strings.ToLower(strings.TrimSpace(name))
+					header[name] = append(header[name], strings.TrimSpace(value))
+				}
+			}
+		}
+		return append(namedValues(config.TargetHeaders, "", header), namedValues(config.TargetCookies, "", ctx.Cookies)...)
+	case PhaseBody:
+		if ctx.JSON != nil {
+			return jsonValues(ctx.JSON, "", nil)
+		}
+		if ctx.Body.Raw == "" {
+			return nil
+		}
+		if form, err := url.ParseQuery(ctx.Body.Raw); err == nil && strings.Contains(ctx.Body.Raw, "=") {
+			return namedValues(config.TargetArgs, "", form)
+		}
+		return []targetValue{{label: config.TargetBody, value: ctx.Body.Raw}}
+	case PhaseRequest:
+		var out []targetValue
+		for _, p := range []Phase{PhaseRequestLine, PhaseHeaders, PhaseQuery, PhaseBody} {
+			out = append(out, phaseValues(ctx, p)...)
+		}
+		return out
+	}
+	return nil
+}
+
+// jsonValues collects the strings in a JSON document, labelled with their
+// JSON pointer.
+func jsonValues(value any, pointer string, out []targetValue) []targetValue {
+	if len(out) >= maxJSONValues {
+		return out
+	}
+	switch v := value.(type) {
+	case string:
+		out = append(out, targetValue{label: config.TargetBodyJSON + ":" + pointer, value: v})
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			escaped := strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+			out = jsonValues(v[key], pointer+"/"+escaped, out)
+		}
+	case []any:
+		for i, item := range v {
+			out = jsonValues(item, pointer+"/"+strconv.Itoa(i), out)
+		}
+	}
+	return out
+}
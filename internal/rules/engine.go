@@ -2,6 +2,9 @@ package rules
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/klyr/klyr/internal/normalize"
 )
@@ -11,40 +14,305 @@ const defaultDecodeDepth = 2
 // Engine evaluates rules against an evaluation context.
 type Engine struct {
 	Rules []Rule
+
+	once    sync.Once
+	groups  []*ahoGroup
+	groupOf []int
+	order   []int // allow rules first, so a match can skip the rest
 }
 
-func (e *Engine) Evaluate(ctx EvalContext) Result {
-	result := Result{}
+// ahoGroup combines the patterns of every aho rule sharing a phase and
+// transform set so the normalized input is scanned once for all of them.
+type ahoGroup struct {
+	phase      Phase
+	transforms []Transform
+	automaton  *automaton
+	owners     [][]int // pattern id -> indexes into Engine.Rules
+}
 
-	for _, rule := range e.Rules {
-		input, ok := selectPhaseInput(ctx, rule.Phase)
-		if !ok {
+// NewEngine returns an engine for rules with the combined aho automata
+// already built.
+func NewEngine(rules []Rule) *Engine {
+	e := &Engine{Rules: rules}
+	e.once.Do(e.combine)
+	return e
+}
+
+func (e *Engine) combine() {
+	e.order = make([]int, 0, len(e.Rules))
+	for i, rule := range e.Rules {
+		if rule.Action.Type == ActionAllow {
+			e.order = append(e.order, i)
+		}
+	}
+	for i, rule := range e.Rules {
+		if rule.Action.Type != ActionAllow {
+			e.order = append(e.order, i)
+		}
+	}
+
+	e.groupOf = make([]int, len(e.Rules))
+	byKey := map[string]int{}
+	patterns := map[int][]string{}
+	ruleIdx := map[int][]int{}
+	for i, rule := range e.Rules {
+		e.groupOf[i] = -1
+		aho, ok := rule.Matcher.(*AhoMatcher)
+		if !ok || len(aho.patterns) == 0 {
 			continue
 		}
+		key := groupKey(rule.Phase, rule.Transforms)
+		g, ok := byKey[key]
+		if !ok {
+			g = len(e.groups)
+			byKey[key] = g
+			e.groups = append(e.groups, &ahoGroup{phase: rule.Phase, transforms: rule.Transforms})
+		}
+		e.groupOf[i] = g
+		patterns[g] = append(patterns[g], aho.patterns...)
+		ruleIdx[g] = append(ruleIdx[g], i)
+	}
 
-		normalized, err := applyTransforms(input, rule.Transforms)
+	for g, group := range e.groups {
+		a, err := newAutomaton(patterns[g])
 		if err != nil {
+			// Fall back to the per-rule matchers.
+			for _, i := range ruleIdx[g] {
+				e.groupOf[i] = -1
+			}
 			continue
 		}
+		group.automaton = a
+		group.owners = make([][]int, len(a.patterns))
+		ids := make(map[string]int, len(a.patterns))
+		for id, p := range a.patterns {
+			ids[p] = id
+		}
+		for _, i := range ruleIdx[g] {
+			for _, p := range e.Rules[i].Matcher.(*AhoMatcher).patterns {
+				id, ok := ids[p]
+				if !ok {
+					continue
+				}
+				if owners := group.owners[id]; len(owners) == 0 || owners[len(owners)-1] != i {
+					group.owners[id] = append(owners, i)
+				}
+			}
+		}
+	}
+}
+
+func groupKey(phase Phase, transforms []Transform) string {
+	names := make([]string, len(transforms))
+	for i, t := range transforms {
+		names[i] = string(t)
+	}
+	sort.Strings(names)
+	return string(phase) + "|" + strings.Join(names, ",")
+}
+
+func (e *Engine) Evaluate(ctx EvalContext) Result {
+	return e.EvaluateWith(ctx, EvalOptions{})
+}
+
+// EvaluateWith evaluates every rule and applies the first exclusion in opts
+// that selects it. Matches an exclusion removes or rescores are reported in
+// Result.Suppressed. A matching allow rule ends evaluation with a zero score.
+// Evaluation stops at the first rule that would exceed opts.Budget. When
+// opts.Trace is set, the outcome of every rule is recorded in it.
+func (e *Engine) EvaluateWith(ctx EvalContext, opts EvalOptions) Result {
+	e.once.Do(e.combine)
+	result := Result{}
+	budget := newBudgetTracker(opts.Budget)
 
-		matched, evidence := rule.Matcher.Match(normalized)
+	var hits [][]string
+	var excluded map[int]EvalContext
+	scanned := make([]bool, len(e.groups))
+	paranoia := max(opts.ParanoiaLevel, 1)
+	for pos, i := range e.order {
+		rule := e.Rules[i]
+		if rule.ParanoiaLevel > paranoia {
+			opts.Trace.record(rule, CheckParanoia, "")
+			continue
+		}
+		var matched bool
+		var evidence string
+		var patterns []string
+		if g := e.groupOf[i]; g >= 0 {
+			if !scanned[g] {
+				if reason := budget.chargePhase(ctx, rule.Phase); reason != "" {
+					budget.exceeded(&result, reason)
+					e.traceRest(opts.Trace, pos)
+					return result
+				}
+				scanned[g] = true
+				hits = e.scanGroup(ctx, e.groups[g], hits)
+			}
+			if hits != nil && len(hits[i]) > 0 {
+				matched = true
+				patterns = hits[i]
+				evidence = snippet(patterns[0])
+			}
+		} else {
+			if reason := budget.chargePhase(ctx, rule.Phase); reason != "" {
+				budget.exceeded(&result, reason)
+				e.traceRest(opts.Trace, pos)
+				return result
+			}
+			matched, evidence = matchRule(ctx, rule)
+		}
 		if !matched {
+			opts.Trace.record(rule, CheckNoMatch, "")
 			continue
 		}
 
-		result.Score += rule.Score
-		result.Matches = append(result.Matches, Match{
+		score := rule.Score
+		if x := opts.exclusionFor(rule, ctx.Path); x >= 0 {
+			exclusion := &opts.Exclusions[x]
+			suppression := Suppression{RuleID: rule.ID, ExclusionID: exclusion.ID, Score: rule.Score, Evidence: evidence}
+			if exclusion.disables() {
+				suppression.Effect = EffectDisabled
+				result.Suppressed = append(result.Suppressed, suppression)
+				opts.Trace.record(rule, CheckSuppressed, evidence)
+				continue
+			}
+			if exclusion.hasTargets() {
+				if excluded == nil {
+					excluded = map[int]EvalContext{}
+				}
+				trimmed, ok := excluded[x]
+				if !ok {
+					trimmed = exclusion.without(ctx)
+					excluded[x] = trimmed
+				}
+				if matched, evidence = matchRule(trimmed, rule); !matched {
+					suppression.Effect = EffectTargetExcluded
+					result.Suppressed = append(result.Suppressed, suppression)
+					opts.Trace.record(rule, CheckSuppressed, suppression.Evidence)
+					continue
+				}
+				patterns = nil
+			}
+			if exclusion.Score != nil {
+				score = *exclusion.Score
+				suppression.Effect = EffectScoreAdjusted
+				result.Suppressed = append(result.Suppressed, suppression)
+			}
+		}
+
+		if !rule.Action.Scores() {
+			score = 0
+		}
+		match := Match{
 			RuleID:   rule.ID,
 			Phase:    rule.Phase,
-			Score:    rule.Score,
+			Score:    score,
 			Tags:     append([]string(nil), rule.Tags...),
 			Evidence: evidence,
-		})
+			Patterns: patterns,
+			Action:   rule.Action,
+		}
+		opts.Trace.record(rule, CheckMatched, evidence)
+		if rule.Action.Type == ActionAllow {
+			e.traceRest(opts.Trace, pos+1)
+			return Result{Matches: []Match{match}, Suppressed: result.Suppressed}
+		}
+		result.Score += score
+		if score != 0 {
+			for _, tag := range rule.Tags {
+				if result.Categories == nil {
+					result.Categories = map[string]int{}
+				}
+				result.Categories[tag] += score
+			}
+		}
+		result.Matches = append(result.Matches, match)
 	}
 
 	return result
 }
 
+// traceRest records the rules from position pos of the evaluation order on
+// as not reached.
+func (e *Engine) traceRest(trace *Trace, pos int) {
+	if trace == nil {
+		return
+	}
+	for _, i := range e.order[pos:] {
+		trace.record(e.Rules[i], CheckNotReached, "")
+	}
+}
+
+// matchRule evaluates one rule on its own, outside the combined automata.
+func matchRule(ctx EvalContext, rule Rule) (bool, string) {
+	if cm, ok := rule.Matcher.(ContextMatcher); ok {
+		return cm.MatchContext(ctx, rule.Transforms)
+	}
+
+	input, ok := selectPhaseInput(ctx, rule.Phase)
+	if !ok {
+		return false, ""
+	}
+	normalized, err := applyTransforms(input, rule.Transforms)
+	if err != nil {
+		return false, ""
+	}
+	return rule.Matcher.Match(normalized)
+}
+
+// NeedsBody reports whether any rule inspects the request body, directly,
+// through a condition on BODY, BODY_JSON or ARGS, or as a signal or detector
+// rule checking every phase.
+func (e *Engine) NeedsBody() bool {
+	for _, rule := range e.Rules {
+		if rule.Phase == PhaseBody {
+			return true
+		}
+		if cm, ok := rule.Matcher.(*ConditionMatcher); ok && cm.root.needsBody() {
+			return true
+		}
+		if sm, ok := rule.Matcher.(*SignalMatcher); ok && sm.phase == PhaseRequest {
+			return true
+		}
+		if dm, ok := rule.Matcher.(*DetectorMatcher); ok && dm.phase == PhaseRequest {
+			return true
+		}
+	}
+	return false
+}
+
+// scanGroup runs the group's automaton once and records, per rule, the
+// distinct patterns found in order of first occurrence.
+func (e *Engine) scanGroup(ctx EvalContext, group *ahoGroup, hits [][]string) [][]string {
+	input, ok := selectPhaseInput(ctx, group.phase)
+	if !ok {
+		return hits
+	}
+	normalized, err := applyTransforms(input, group.transforms)
+	if err != nil {
+		return hits
+	}
+	if hits == nil {
+		hits = make([][]string, len(e.Rules))
+	}
+
+	seen := map[int32]bool{}
+	group.automaton.scan(normalized, func(id int32) bool {
+		if seen[id] {
+			return true
+		}
+		seen[id] = true
+		pattern := group.automaton.patterns[id]
+		for _, i := range group.owners[id] {
+			if len(hits[i]) < maxMatchedPatterns {
+				hits[i] = append(hits[i], pattern)
+			}
+		}
+		return true
+	})
+	return hits
+}
+
 func selectPhaseInput(ctx EvalContext, phase Phase) (string, bool) {
 	switch phase {
 	case PhaseRequestLine:
@@ -61,6 +329,11 @@ func selectPhaseInput(ctx EvalContext, phase Phase) (string, bool) {
 }
 
 func applyTransforms(input string, transforms []Transform) (string, error) {
+	res, err := normalizeInput(input, transforms)
+	return res.Normalized, err
+}
+
+func normalizeInput(input string, transforms []Transform) (normalize.Result, error) {
 	opts := normalize.Options{MaxDecodeDepth: defaultDecodeDepth}
 	for _, transform := range transforms {
 		switch transform {
@@ -70,11 +343,30 @@ func applyTransforms(input string, transforms []Transform) (string, error) {
 			opts.HTMLEntity = true
 		case TransformPathNormalize:
 			opts.NormalizePath = true
+		case TransformBase64Decode:
+			opts.Base64Decode = true
+		case TransformHexDecode:
+			opts.HexDecode = true
+		case TransformURLDecodeUni:
+			opts.URLDecodeUni = true
+		case TransformUnicodeNFKC:
+			opts.UnicodeNFKC = true
+		case TransformFoldLookalikes:
+			opts.FoldLookalikes = true
+		case TransformCompressWhitespace:
+			opts.CompressWhitespace = true
+		case TransformRemoveNulls:
+			opts.RemoveNulls = true
+		case TransformReplaceComments:
+			opts.ReplaceComments = true
+		case TransformCmdline:
+			opts.Cmdline = true
+		case TransformJSDecode:
+			opts.JSDecode = true
 		default:
-			return "", fmt.Errorf("unknown transform %q", transform)
+			return normalize.Result{}, fmt.Errorf("unknown transform %q", transform)
 		}
 	}
 
-	res := normalize.Apply(input, opts)
-	return res.Normalized, nil
+	return normalize.Apply(input, opts), nil
 }
@@ -1,6 +1,11 @@
 package rules
 
-import "testing"
+import (
+	"net/http"
+	"testing"
+
+	"github.com/klyr/klyr/internal/config"
+)
 
 func TestEngineRegexMatch(t *testing.T) {
 	matcher, err := NewRegexMatcher("(?i)<script>")
@@ -64,3 +69,139 @@ func TestEngineAhoMatch(t *testing.T) {
 		t.Fatalf("expected 1 match, got %d", len(result.Matches))
 	}
 }
+
+func TestEngineAllowRuleShortCircuits(t *testing.T) {
+	attack, err := NewRegexMatcher("select")
+	if err != nil {
+		t.Fatalf("regex compile: %v", err)
+	}
+	health, err := NewRegexMatcher("^GET /healthz$")
+	if err != nil {
+		t.Fatalf("regex compile: %v", err)
+	}
+	logged, err := NewRegexMatcher("q=")
+	if err != nil {
+		t.Fatalf("regex compile: %v", err)
+	}
+
+	engine := NewEngine([]Rule{
+		{ID: "sqli", Phase: PhaseQuery, Score: 5, Matcher: attack},
+		{ID: "audit", Phase: PhaseQuery, Score: 3, Matcher: logged, Action: Action{Type: ActionLog}},
+		{ID: "health", Phase: PhaseRequestLine, Matcher: health, Action: Action{Type: ActionAllow}},
+	})
+
+	result := engine.Evaluate(EvalContext{RequestLine: Field{Raw: "GET /search"}, Query: Field{Raw: "q=select"}})
+	if result.Score != 5 || len(result.Matches) != 2 || result.Matches[1].Score != 0 {
+		t.Fatalf("expected log rule to match without scoring, got %+v", result)
+	}
+
+	result = engine.Evaluate(EvalContext{RequestLine: Field{Raw: "GET /healthz"}, Query: Field{Raw: "q=select"}})
+	if result.Score != 0 || len(result.Matches) != 1 || result.Matches[0].RuleID != "health" {
+		t.Fatalf("expected allow rule to end evaluation, got %+v", result)
+	}
+}
+
+func TestEngineParanoiaLevelsAndCategories(t *testing.T) {
+	quote, err := NewRegexMatcher("'")
+	if err != nil {
+		t.Fatalf("regex compile: %v", err)
+	}
+	script, err := NewRegexMatcher("<script")
+	if err != nil {
+		t.Fatalf("regex compile: %v", err)
+	}
+
+	engine := NewEngine([]Rule{
+		{ID: "sqli-quote", Phase: PhaseQuery, Score: 2, Tags: []string{"sqli"}, Matcher: quote, ParanoiaLevel: 3},
+		{ID: "xss-script", Phase: PhaseQuery, Score: 5, Tags: []string{"xss", "injection"}, Matcher: script},
+	})
+	ctx := EvalContext{Query: Field{Raw: "q=<script>'"}}
+
+	result := engine.EvaluateWith(ctx, EvalOptions{})
+	if result.Score != 5 || len(result.Matches) != 1 {
+		t.Fatalf("expected paranoia level 3 rule to stay dormant at level 1, got %+v", result)
+	}
+	if result.Categories["xss"] != 5 || result.Categories["injection"] != 5 || result.Categories["sqli"] != 0 {
+		t.Fatalf("unexpected categories %+v", result.Categories)
+	}
+
+	result = engine.EvaluateWith(ctx, EvalOptions{ParanoiaLevel: 3})
+	if result.Score != 7 || result.Categories["sqli"] != 2 {
+		t.Fatalf("expected paranoia level 3 rule to run, got %+v", result)
+	}
+}
+
+func TestEngineSignalRules(t *testing.T) {
+	double, err := NewSignalMatcher(PhaseQuery, []string{"double_encoded", "null_byte"})
+	if err != nil {
+		t.Fatalf("signal matcher: %v", err)
+	}
+	anywhere, err := NewSignalMatcher(PhaseRequest, []string{"overlong_utf8"})
+	if err != nil {
+		t.Fatalf("signal matcher: %v", err)
+	}
+	if _, err := NewSignalMatcher(PhaseQuery, []string{"bogus"}); err == nil {
+		t.Fatalf("expected unknown signal to fail")
+	}
+
+	engine := NewEngine([]Rule{
+		{ID: "double", Phase: PhaseQuery, Score: 3, Matcher: double},
+		{ID: "overlong", Phase: PhaseRequest, Score: 4, Matcher: anywhere},
+	})
+
+	result := engine.Evaluate(EvalContext{Query: Field{Raw: "f=%252e%252e%252f"}})
+	if result.Score != 3 || result.Matches[0].Evidence != "signal:double_encoded" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	result = engine.Evaluate(EvalContext{RequestLine: Field{Raw: "GET /..%c0%af..%c0%afetc"}, Query: Field{Raw: "q=1"}})
+	if result.Score != 4 || result.Matches[0].RuleID != "overlong" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
+
+func TestEngineSQLiRules(t *testing.T) {
+	engine := NewEngine([]Rule{
+		{ID: "sqli-query", Phase: PhaseQuery, Score: 5, Matcher: NewSQLiMatcher(PhaseQuery)},
+		{ID: "sqli-any", Phase: PhaseRequest, Score: 1, Matcher: NewSQLiMatcher(PhaseRequest)},
+	})
+
+	result := engine.Evaluate(EvalContext{Query: Field{Raw: "q=shoes&id=1%27%20or%20%271%27%3D%271"}})
+	if result.Score != 6 || result.Matches[0].Evidence != "sqli:s&sos ARGS:id" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	result = engine.Evaluate(EvalContext{JSON: map[string]any{"user": map[string]any{"name": "x' union select password from users--"}}})
+	if result.Score != 1 || result.Matches[0].Evidence != "sqli:sUEnk BODY_JSON:/user/name" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	result = engine.Evaluate(EvalContext{Query: Field{Raw: "q=rock+and+roll&name=O%27Brien"}, Body: Field{Raw: "comment=it's 5 o'clock"}})
+	if result.Score != 0 {
+		t.Fatalf("expected no match, got %+v", result)
+	}
+}
+
+func TestEngineXSSRules(t *testing.T) {
+	engine, err := BuildEngine(&config.Config{Rules: []config.Rule{
+		{ID: "xss", Score: 5, Tags: []string{"xss"}, Match: config.RuleMatch{Type: "xss"}},
+	}}, "")
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	result := engine.Evaluate(EvalContext{Query: Field{Raw: "q=%3Csvg%2Fonload%3Dalert(1)%3E"}})
+	if result.Score != 5 || result.Matches[0].Evidence != "xss:onload ARGS:q" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	result = engine.Evaluate(EvalContext{Header: http.Header{"Referer": {"javascript:alert(1)"}}})
+	if result.Score != 5 || result.Matches[0].Evidence != "xss:javascript: HEADERS:referer" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	result = engine.Evaluate(EvalContext{Query: Field{Raw: "q=the+script+was+great&note=%3Cb%3Ehi%3C%2Fb%3E"}})
+	if result.Score != 0 {
+		t.Fatalf("expected no match, got %+v", result)
+	}
+}
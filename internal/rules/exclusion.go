@@ -0,0 +1,206 @@
+package rules
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/klyr/klyr/internal/config"
+)
+
+// Suppression effects recorded when an exclusion changes a match.
+const (
+	EffectDisabled       = "disabled"
+	EffectTargetExcluded = "target_excluded"
+	EffectScoreAdjusted  = "score_adjusted"
+)
+
+// Exclusion tunes the rules it selects for one route or policy. The first
+// exclusion that applies to a rule wins.
+type Exclusion struct {
+	ID         string
+	Rules      map[string]bool
+	Tags       map[string]bool
+	PathPrefix string
+	Args       []string
+	Headers    []string
+	Cookies    []string
+	Score      *int
+}
+
+// EvalOptions carries per-request evaluation settings such as the
+// exclusions of the matched route and the paranoia level and inspection
+// budget of its policy, and an optional Trace to fill.
+type EvalOptions struct {
+	Exclusions    []Exclusion
+	ParanoiaLevel int
+	Budget        Budget
+	Trace         *Trace
+}
+
+// Suppression records a match an exclusion removed or rescored.
+type Suppression struct {
+	RuleID      string
+	ExclusionID string
+	Effect      string
+	Score       int
+	Evidence    string
+}
+
+func NewExclusions(raw []config.Exclusion) []Exclusion {
+	out := make([]Exclusion, 0, len(raw))
+	for _, item := range raw {
+		x := Exclusion{
+			ID:         item.ID,
+			Rules:      map[string]bool{},
+			Tags:       map[string]bool{},
+			PathPrefix: item.PathPrefix,
+		}
+		for _, id := range item.Rules {
+			x.Rules[id] = true
+		}
+		for _, tag := range item.Tags {
+			x.Tags[tag] = true
+		}
+		for _, target := range item.Targets {
+			name, selector, _ := strings.Cut(target, ":")
+			switch name {
+			case config.TargetArgs:
+				x.Args = append(x.Args, selector)
+			case config.TargetHeaders:
+				x.Headers = append(x.Headers, http.CanonicalHeaderKey(selector))
+			case config.TargetCookies:
+				x.Cookies = append(x.Cookies, selector)
+			}
+		}
+		if item.Score != nil {
+			score := *item.Score
+			x.Score = &score
+		}
+		out = append(out, x)
+	}
+	return out
+}
+
+func (x *Exclusion) applies(rule Rule, path string) bool {
+	if x.PathPrefix != "" && !strings.HasPrefix(path, x.PathPrefix) {
+		return false
+	}
+	if x.Rules[rule.ID] {
+		return true
+	}
+	for _, tag := range rule.Tags {
+		if x.Tags[tag] {
+			return true
+		}
+	}
+	return false
+}
+
+func (x *Exclusion) hasTargets() bool {
+	return len(x.Args) > 0 || len(x.Headers) > 0 || len(x.Cookies) > 0
+}
+
+func (x *Exclusion) disables() bool {
+	return !x.hasTargets() && x.Score == nil
+}
+
+func (o EvalOptions) exclusionFor(rule Rule, path string) int {
+	for i := range o.Exclusions {
+		if o.Exclusions[i].applies(rule, path) {
+			return i
+		}
+	}
+	return -1
+}
+
+// without returns ctx with the excluded arguments, headers and cookies
+// removed from both the structured fields and the raw phase inputs.
+func (x *Exclusion) without(ctx EvalContext) EvalContext {
+	if len(x.Args) > 0 {
+		ctx.Query.Raw = withoutPairs(ctx.Query.Raw, x.Args)
+		ctx.Args = withoutKeys(ctx.Args, x.Args)
+		if ctx.JSON == nil {
+			ctx.Body.Raw = withoutPairs(ctx.Body.Raw, x.Args)
+		} else if obj, ok := ctx.JSON.(map[string]any); ok {
+			trimmed := make(map[string]any, len(obj))
+			for key, value := range obj {
+				trimmed[key] = value
+			}
+			for _, name := range x.Args {
+				if value, ok := obj[name]; ok {
+					ctx.Body.Raw = strings.TrimSpace(strings.Replace(ctx.Body.Raw, name+"="+jsonString(value), "", 1))
+					delete(trimmed, name)
+				}
+			}
+			ctx.JSON = trimmed
+		}
+	}
+	if len(x.Headers) > 0 {
+		ctx.Headers.Raw = withoutHeaderLines(ctx.Headers.Raw, x.Headers)
+		header := make(http.Header, len(ctx.Header))
+		for name, values := range ctx.Header {
+			header[name] = values
+		}
+		for _, name := range x.Headers {
+			header.Del(name)
+		}
+		ctx.Header = header
+	}
+	if len(x.Cookies) > 0 {
+		ctx.Cookies = withoutKeys(ctx.Cookies, x.Cookies)
+	}
+	return ctx
+}
+
+func withoutPairs(raw string, names []string) string {
+	if raw == "" {
+		return raw
+	}
+	pairs := strings.Split(raw, "&")
+	kept := pairs[:0]
+	for _, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		if decoded, err := url.QueryUnescape(key); err == nil {
+			key = decoded
+		}
+		if !contains(names, key) {
+			kept = append(kept, pair)
+		}
+	}
+	return strings.Join(kept, "&")
+}
+
+func withoutHeaderLines(raw string, names []string) string {
+	lines := strings.SplitAfter(raw, "\n")
+	var b strings.Builder
+	for _, line := range lines {
+		name, _, _ := strings.Cut(line, ":")
+		if !contains(names, http.CanonicalHeaderKey(name)) {
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
+
+func withoutKeys(values url.Values, names []string) url.Values {
+	if len(values) == 0 {
+		return values
+	}
+	out := make(url.Values, len(values))
+	for key, value := range values {
+		if !contains(names, key) {
+			out[key] = value
+		}
+	}
+	return out
+}
+
+func contains(items []string, item string) bool {
+	for _, candidate := range items {
+		if candidate == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package rules
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/klyr/klyr/internal/config"
+)
+
+func exclusionEngine(t *testing.T) *Engine {
+	t.Helper()
+	query, err := NewAhoMatcher([]string{"union select"})
+	if err != nil {
+		t.Fatalf("aho build: %v", err)
+	}
+	agent, err := NewRegexMatcher("(?i)curl")
+	if err != nil {
+		t.Fatalf("regex compile: %v", err)
+	}
+	return NewEngine([]Rule{
+		{ID: "sqli-1", Phase: PhaseQuery, Score: 5, Tags: []string{"sqli"}, Transforms: []Transform{TransformLowercase}, Matcher: query},
+		{ID: "ua-1", Phase: PhaseHeaders, Score: 2, Tags: []string{"scanner"}, Matcher: agent},
+	})
+}
+
+func exclusionContext() EvalContext {
+	return EvalContext{
+		Path:    "/search",
+		Query:   Field{Raw: "q=UNION%20SELECT&page=1"},
+		Args:    url.Values{"q": {"UNION SELECT"}, "page": {"1"}},
+		Headers: Field{Raw: "User-Agent: curl/8.0\nAccept: */*\n"},
+		Header:  http.Header{"User-Agent": {"curl/8.0"}, "Accept": {"*/*"}},
+	}
+}
+
+func TestEngineAppliesExclusions(t *testing.T) {
+	score := func(n int) *int { return &n }
+	cases := []struct {
+		name       string
+		exclusion  config.Exclusion
+		wantScore  int
+		wantEffect string
+	}{
+		{"disable by id", config.Exclusion{ID: "x", Rules: []string{"sqli-1"}}, 2, EffectDisabled},
+		{"disable by tag", config.Exclusion{ID: "x", Tags: []string{"scanner"}}, 5, EffectDisabled},
+		{"exclude arg", config.Exclusion{ID: "x", Rules: []string{"sqli-1"}, Targets: []string{"ARGS:q"}}, 2, EffectTargetExcluded},
+		{"exclude header", config.Exclusion{ID: "x", Tags: []string{"scanner"}, Targets: []string{"HEADERS:user-agent"}}, 5, EffectTargetExcluded},
+		{"lower score", config.Exclusion{ID: "x", Rules: []string{"sqli-1"}, Score: score(1)}, 3, EffectScoreAdjusted},
+		{"other path", config.Exclusion{ID: "x", Rules: []string{"sqli-1"}, PathPrefix: "/admin"}, 7, ""},
+		{"other arg", config.Exclusion{ID: "x", Rules: []string{"sqli-1"}, Targets: []string{"ARGS:page"}}, 7, ""},
+	}
+
+	engine := exclusionEngine(t)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := EvalOptions{Exclusions: NewExclusions([]config.Exclusion{tc.exclusion})}
+			result := engine.EvaluateWith(exclusionContext(), opts)
+			if result.Score != tc.wantScore {
+				t.Fatalf("expected score %d, got %d (%+v)", tc.wantScore, result.Score, result)
+			}
+			if tc.wantEffect == "" {
+				if len(result.Suppressed) != 0 {
+					t.Fatalf("expected no suppression, got %+v", result.Suppressed)
+				}
+				return
+			}
+			if len(result.Suppressed) != 1 || result.Suppressed[0].Effect != tc.wantEffect || result.Suppressed[0].ExclusionID != "x" {
+				t.Fatalf("expected %s suppression, got %+v", tc.wantEffect, result.Suppressed)
+			}
+		})
+	}
+}
+
+func TestExclusionRemovesConditionTargets(t *testing.T) {
+	engine, err := BuildEngine(&config.Config{Rules: []config.Rule{
+		{ID: "long-arg", Score: 3, Condition: &config.Condition{Target: "ARGS", Op: "lengthGt", Value: "8"}},
+	}}, "")
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	opts := EvalOptions{Exclusions: NewExclusions([]config.Exclusion{
+		{ID: "search-q", Rules: []string{"long-arg"}, PathPrefix: "/search", Targets: []string{"ARGS:q"}},
+	})}
+	ctx := exclusionContext()
+	if result := engine.EvaluateWith(ctx, opts); result.Score != 0 || len(result.Suppressed) != 1 {
+		t.Fatalf("expected q to be excluded, got %+v", result)
+	}
+
+	ctx.Args = url.Values{"q": {"short"}, "comment": {"a long comment"}}
+	if result := engine.EvaluateWith(ctx, opts); result.Score != 3 || result.Matches[0].Evidence != "ARGS:comment=a long comment" {
+		t.Fatalf("expected other args to stay inspected, got %+v", result)
+	}
+}
@@ -0,0 +1,135 @@
+package rules
+
+import (
+	"fmt"
+	"regexp/syntax"
+
+	"github.com/klyr/klyr/internal/config"
+)
+
+// Limits above which a regex is reported by Lint.
+const (
+	maxProgramSize   = 2000
+	maxCountedRepeat = 100
+	maxAlternatives  = 64
+)
+
+// LintIssue is a rule pattern that is likely to be slow on large inputs.
+type LintIssue struct {
+	RuleID  string
+	Pattern string
+	Message string
+}
+
+// Lint checks the regex patterns of every rule, including regex conditions.
+// RE2 matching is linear, but large programs and nested or counted
+// repetition still multiply the cost per scanned byte.
+func Lint(cfg *config.Config) []LintIssue {
+	var issues []LintIssue
+	for _, rule := range cfg.Rules {
+		var patterns []string
+		if rule.Match.Type == string(MatchRegex) {
+			patterns = append(patterns, rule.Match.Pattern)
+		}
+		if rule.Condition != nil {
+			patterns = conditionPatterns(*rule.Condition, patterns)
+		}
+		for _, pattern := range patterns {
+			for _, message := range LintPattern(pattern) {
+				issues = append(issues, LintIssue{RuleID: rule.ID, Pattern: pattern, Message: message})
+			}
+		}
+	}
+	return issues
+}
+
+func conditionPatterns(cond config.Condition, out []string) []string {
+	for _, child := range append(append([]config.Condition(nil), cond.All...), cond.Any...) {
+		out = conditionPatterns(child, out)
+	}
+	if cond.Not != nil {
+		out = conditionPatterns(*cond.Not, out)
+	}
+	if cond.Op == config.OpRegex {
+		if cond.Value != "" {
+			out = append(out, cond.Value)
+		}
+		out = append(out, cond.Values...)
+	}
+	return out
+}
+
+// LintPattern returns the problems found in one regex.
+func LintPattern(pattern string) []string {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return []string{fmt.Sprintf("does not compile: %v", err)}
+	}
+
+	var out []string
+	seen := map[string]bool{}
+	add := func(message string) {
+		if !seen[message] {
+			seen[message] = true
+			out = append(out, message)
+		}
+	}
+	if leadingWildcard(re) {
+		add("leading .* is implied for an unanchored match and only adds work")
+	}
+	lintNode(re, false, add)
+
+	if prog, err := syntax.Compile(re.Simplify()); err == nil && len(prog.Inst) > maxProgramSize {
+		add(fmt.Sprintf("compiled program has %d instructions (limit %d)", len(prog.Inst), maxProgramSize))
+	}
+	return out
+}
+
+func lintNode(re *syntax.Regexp, repeated bool, add func(string)) {
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus, syntax.OpRepeat:
+		if re.Op == syntax.OpRepeat && (re.Max > maxCountedRepeat || re.Min > maxCountedRepeat) {
+			add(fmt.Sprintf("counted repetition %s expands to a large program", repeatString(re)))
+		}
+		if repeated && repeats(re) {
+			add("nested repetition multiplies the states tracked per byte")
+		}
+		for _, sub := range re.Sub {
+			lintNode(sub, repeated || repeats(re), add)
+		}
+		return
+	case syntax.OpAlternate:
+		if len(re.Sub) > maxAlternatives {
+			add(fmt.Sprintf("%d alternatives; use an aho rule with a patterns file", len(re.Sub)))
+		}
+	}
+	for _, sub := range re.Sub {
+		lintNode(sub, repeated, add)
+	}
+}
+
+// repeats reports whether re can match its operand more than once.
+func repeats(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpStar || re.Op == syntax.OpPlus || re.Op == syntax.OpRepeat && (re.Max == -1 || re.Max > 1)
+}
+
+func repeatString(re *syntax.Regexp) string {
+	if re.Max == -1 {
+		return fmt.Sprintf("{%d,}", re.Min)
+	}
+	if re.Min == re.Max {
+		return fmt.Sprintf("{%d}", re.Min)
+	}
+	return fmt.Sprintf("{%d,%d}", re.Min, re.Max)
+}
+
+func leadingWildcard(re *syntax.Regexp) bool {
+	for re.Op == syntax.OpConcat || re.Op == syntax.OpCapture {
+		if len(re.Sub) == 0 {
+			return false
+		}
+		re = re.Sub[0]
+	}
+	return re.Op == syntax.OpStar && len(re.Sub) == 1 &&
+		(re.Sub[0].Op == syntax.OpAnyCharNotNL || re.Sub[0].Op == syntax.OpAnyChar)
+}
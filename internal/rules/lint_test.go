@@ -0,0 +1,47 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/klyr/klyr/internal/config"
+)
+
+func TestLintPattern(t *testing.T) {
+	cases := map[string]string{
+		`(?i)union\s+select`:                     "",
+		`x{2,5}`:                                 "",
+		`(a+)+b`:                                 "nested repetition",
+		`(?:\d+\s*)*$`:                           "nested repetition",
+		`.*select`:                               "leading .*",
+		`a{500}`:                                 "counted repetition {500}",
+		`\w{1,1000}x`:                            "compiled program has",
+		`(`:                                      "does not compile",
+		"(" + strings.Repeat("a|b1|", 40) + "c)": "alternatives",
+	}
+	for pattern, want := range cases {
+		issues := LintPattern(pattern)
+		if want == "" {
+			if len(issues) != 0 {
+				t.Errorf("LintPattern(%q) = %q, want none", pattern, issues)
+			}
+			continue
+		}
+		if len(issues) == 0 || !strings.Contains(strings.Join(issues, "; "), want) {
+			t.Errorf("LintPattern(%q) = %q, want %q", pattern, issues, want)
+		}
+	}
+}
+
+func TestLintChecksConditions(t *testing.T) {
+	issues := Lint(&config.Config{Rules: []config.Rule{
+		{ID: "plain", Match: config.RuleMatch{Type: "regex", Pattern: "admin"}},
+		{ID: "nested", Condition: &config.Condition{Any: []config.Condition{
+			{Target: "PATH", Op: "regex", Value: "^/api"},
+			{Not: &config.Condition{Target: "ARGS", Op: "regex", Values: []string{"(x+)+y"}}},
+		}}},
+	}})
+	if len(issues) != 1 || issues[0].RuleID != "nested" || issues[0].Pattern != "(x+)+y" {
+		t.Fatalf("unexpected issues %+v", issues)
+	}
+}
@@ -0,0 +1,56 @@
+package rules
+
+import (
+	"fmt"
+
+	"github.com/klyr/klyr/internal/normalize"
+)
+
+// SignalMatcher matches when normalizing a phase input raises one of its
+// evasion flags, such as double encoding or overlong UTF-8. PhaseRequest
+// checks every phase.
+type SignalMatcher struct {
+	phase Phase
+	flags normalize.Flags
+}
+
+func NewSignalMatcher(phase Phase, signals []string) (*SignalMatcher, error) {
+	if len(signals) == 0 {
+		return nil, fmt.Errorf("signals are required")
+	}
+	m := &SignalMatcher{phase: phase}
+	for _, name := range signals {
+		flag, ok := normalize.ParseFlag(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown signal %q", name)
+		}
+		m.flags |= flag
+	}
+	return m, nil
+}
+
+// Match never matches: signals come from normalizing the context.
+func (m *SignalMatcher) Match(string) (bool, string) {
+	return false, ""
+}
+
+func (m *SignalMatcher) MatchContext(ctx EvalContext, transforms []Transform) (bool, string) {
+	phases := []Phase{m.phase}
+	if m.phase == PhaseRequest {
+		phases = []Phase{PhaseRequestLine, PhaseHeaders, PhaseQuery, PhaseBody}
+	}
+	for _, phase := range phases {
+		input, ok := selectPhaseInput(ctx, phase)
+		if !ok || input == "" {
+			continue
+		}
+		res, err := normalizeInput(input, transforms)
+		if err != nil {
+			continue
+		}
+		if hit := res.Flags & m.flags; hit != 0 {
+			return true, "signal:" + hit.Names()[0]
+		}
+	}
+	return false, ""
+}
@@ -0,0 +1,72 @@
+package rules
+
+// Outcomes of a rule recorded in a Trace.
+const (
+	CheckMatched    = "matched"
+	CheckNoMatch    = "no_match"
+	CheckParanoia   = "paranoia_skipped"
+	CheckSuppressed = "suppressed"
+	// CheckNotReached marks rules left unchecked because an allow rule or
+	// the inspection budget ended evaluation.
+	CheckNotReached = "not_reached"
+)
+
+// Trace records how every rule fared in one evaluation, in evaluation
+// order. It is filled when passed in EvalOptions and is meant for
+// explaining a decision, not for the request path.
+type Trace struct {
+	Checks []Check
+}
+
+type Check struct {
+	RuleID   string
+	Phase    Phase
+	Outcome  string
+	Evidence string
+}
+
+func (t *Trace) record(rule Rule, outcome, evidence string) {
+	if t == nil {
+		return
+	}
+	t.Checks = append(t.Checks, Check{RuleID: rule.ID, Phase: rule.Phase, Outcome: outcome, Evidence: evidence})
+}
+
+// NormalizedInput is a phase input after one rule's transforms.
+type NormalizedInput struct {
+	Phase      Phase
+	Transforms []Transform
+	Raw        string
+	Normalized string
+}
+
+// Inputs returns each phase input once per distinct transform set used by
+// the rules that scan it, in rule order. Condition, signal and detector
+// rules select their own values and are not included.
+func (e *Engine) Inputs(ctx EvalContext) []NormalizedInput {
+	if e == nil {
+		return nil
+	}
+	var out []NormalizedInput
+	seen := map[string]bool{}
+	for _, rule := range e.Rules {
+		if _, ok := rule.Matcher.(ContextMatcher); ok {
+			continue
+		}
+		key := groupKey(rule.Phase, rule.Transforms)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		raw, ok := selectPhaseInput(ctx, rule.Phase)
+		if !ok {
+			continue
+		}
+		normalized, err := applyTransforms(raw, rule.Transforms)
+		if err != nil {
+			continue
+		}
+		out = append(out, NormalizedInput{Phase: rule.Phase, Transforms: rule.Transforms, Raw: raw, Normalized: normalized})
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/klyr/klyr/internal/config"
+)
+
+func TestEngineTrace(t *testing.T) {
+	engine := exclusionEngine(t)
+	engine.Rules = append(engine.Rules, Rule{ID: "pl2", Phase: PhaseQuery, Score: 1, Matcher: engine.Rules[0].Matcher, ParanoiaLevel: 2})
+	engine = NewEngine(engine.Rules)
+
+	trace := &Trace{}
+	opts := EvalOptions{
+		Exclusions: NewExclusions([]config.Exclusion{{ID: "x", Tags: []string{"scanner"}}}),
+		Trace:      trace,
+	}
+	engine.EvaluateWith(exclusionContext(), opts)
+	want := map[string]string{"sqli-1": CheckMatched, "ua-1": CheckSuppressed, "pl2": CheckParanoia}
+	if len(trace.Checks) != len(want) {
+		t.Fatalf("expected %d checks, got %+v", len(want), trace.Checks)
+	}
+	for _, check := range trace.Checks {
+		if check.Outcome != want[check.RuleID] {
+			t.Fatalf("expected %s to be %s, got %+v", check.RuleID, want[check.RuleID], check)
+		}
+	}
+
+	ctx := exclusionContext()
+	ctx.Query = Field{Raw: "page=1"}
+	trace = &Trace{}
+	engine.EvaluateWith(ctx, EvalOptions{Budget: Budget{MaxBytes: 8}, Trace: trace})
+	if len(trace.Checks) != 3 || trace.Checks[0].Outcome != CheckNoMatch || trace.Checks[1].Outcome != CheckNotReached {
+		t.Fatalf("expected no match then not reached, got %+v", trace.Checks)
+	}
+}
+
+func TestEngineInputs(t *testing.T) {
+	inputs := exclusionEngine(t).Inputs(exclusionContext())
+	if len(inputs) != 2 {
+		t.Fatalf("expected query and headers inputs, got %+v", inputs)
+	}
+	if inputs[0].Phase != PhaseQuery || inputs[0].Normalized != "q=union select&page=1" {
+		t.Fatalf("expected lowercased, decoded query, got %+v", inputs[0])
+	}
+}
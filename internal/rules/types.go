@@ -1,5 +1,7 @@
 package rules
 
+import "time"
+
 type Phase string
 
 type MatchType string
@@ -11,19 +13,71 @@ const (
 	PhaseHeaders     Phase = "headers"
 	PhaseQuery       Phase = "query"
 	PhaseBody        Phase = "body"
+	// PhaseRequest is the default for condition rules, which pick their
+	// own targets from the whole request.
+	PhaseRequest Phase = "request"
 )
 
 const (
 	MatchRegex MatchType = "regex"
 	MatchAho   MatchType = "aho"
+	// MatchSignal matches the evasion flags raised by normalization.
+	MatchSignal MatchType = "signal"
+	// MatchSQLi matches values whose SQL token fingerprint is a known
+	// injection.
+	MatchSQLi MatchType = "sqli"
+	// MatchXSS matches values that would run script when reflected into HTML.
+	MatchXSS MatchType = "xss"
 )
 
 const (
 	TransformLowercase     Transform = "lowercase"
 	TransformHTMLEntity    Transform = "html_entity"
 	TransformPathNormalize Transform = "normalize_path"
+
+	TransformBase64Decode       Transform = "base64_decode"
+	TransformHexDecode          Transform = "hex_decode"
+	TransformURLDecodeUni       Transform = "url_decode_uni"
+	TransformUnicodeNFKC        Transform = "unicode_nfkc"
+	TransformFoldLookalikes     Transform = "fold_lookalikes"
+	TransformCompressWhitespace Transform = "compress_whitespace"
+	TransformRemoveNulls        Transform = "remove_nulls"
+	TransformReplaceComments    Transform = "replace_comments"
+	TransformCmdline            Transform = "cmdline"
+	TransformJSDecode           Transform = "js_decode"
+)
+
+type ActionType string
+
+const (
+	ActionScore    ActionType = ""
+	ActionAllow    ActionType = "allow"
+	ActionBlock    ActionType = "block"
+	ActionRedirect ActionType = "redirect"
+	ActionTarpit   ActionType = "tarpit"
+	ActionLog      ActionType = "log"
+	ActionTag      ActionType = "tag"
 )
 
+// Action is what a rule asks for when it matches, on top of its score.
+type Action struct {
+	Type       ActionType
+	StatusCode int
+	Location   string
+	Delay      time.Duration
+}
+
+// Scores reports whether matches of a rule with this action add to the
+// anomaly score.
+func (a Action) Scores() bool {
+	switch a.Type {
+	case ActionAllow, ActionLog, ActionTag:
+		return false
+	default:
+		return true
+	}
+}
+
 type Rule struct {
 	ID         string
 	Phase      Phase
@@ -31,6 +85,10 @@ type Rule struct {
 	Tags       []string
 	Transforms []Transform
 	Matcher    Matcher
+	Action     Action
+	// ParanoiaLevel is the lowest EvalOptions.ParanoiaLevel that runs the
+	// rule; 0 counts as 1.
+	ParanoiaLevel int
 }
 
 type Match struct {
@@ -39,11 +97,20 @@ type Match struct {
 	Score    int
 	Tags     []string
 	Evidence string
+	// Patterns lists the distinct patterns an aho rule matched, in order of
+	// first occurrence and bounded by maxMatchedPatterns.
+	Patterns []string
+	Action   Action
 }
 
+// Result totals the scores of the matches, overall and per rule tag.
+// BudgetExceeded is the reason evaluation stopped before every rule ran.
 type Result struct {
-	Score   int
-	Matches []Match
+	Score          int
+	Categories     map[string]int
+	Matches        []Match
+	Suppressed     []Suppression
+	BudgetExceeded string
 }
 
 // Matcher returns true if the input matches and an optional evidence snippet.
@@ -51,3 +118,9 @@ type Result struct {
 type Matcher interface {
 	Match(input string) (bool, string)
 }
+
+// ContextMatcher is implemented by matchers that read structured request data
+// instead of a single phase input. The engine prefers it over Match.
+type ContextMatcher interface {
+	MatchContext(ctx EvalContext, transforms []Transform) (bool, string)
+}
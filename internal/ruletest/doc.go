@@ -0,0 +1,2 @@
+// Package ruletest provides functionality for Klyr.
+package ruletest
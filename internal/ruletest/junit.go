@@ -0,0 +1,92 @@
+package ruletest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes results as JUnit XML with one test suite per corpus
+// file.
+func WriteJUnit(w io.Writer, results []Result) error {
+	doc := junitSuites{}
+	index := map[string]int{}
+	durations := map[string]time.Duration{}
+	for _, r := range results {
+		i, ok := index[r.Case.Source]
+		if !ok {
+			i = len(doc.Suites)
+			index[r.Case.Source] = i
+			doc.Suites = append(doc.Suites, junitSuite{Name: r.Case.Source})
+		}
+		suite := &doc.Suites[i]
+		tc := junitCase{Name: r.Case.Name, Classname: r.Case.Source, Time: seconds(r.Duration)}
+		switch {
+		case r.Err != nil:
+			tc.Error = &junitProblem{Message: r.Err.Error()}
+			suite.Errors++
+			doc.Errors++
+		case len(r.Failures) > 0:
+			tc.Failure = &junitProblem{
+				Message: r.Failures[0],
+				Text:    fmt.Sprintf("%s\nmatched: [%s]\naction: %s", strings.Join(r.Failures, "\n"), strings.Join(r.Matched, ", "), r.Action),
+			}
+			suite.Failures++
+			doc.Failures++
+		}
+		suite.Tests++
+		doc.Tests++
+		durations[r.Case.Source] += r.Duration
+		suite.Cases = append(suite.Cases, tc)
+	}
+	for i := range doc.Suites {
+		doc.Suites[i].Time = seconds(durations[doc.Suites[i].Name])
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("encode junit: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
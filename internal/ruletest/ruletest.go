@@ -0,0 +1,160 @@
+package ruletest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/klyr/klyr/internal/explain"
+	"github.com/klyr/klyr/internal/gateway"
+	"gopkg.in/yaml.v3"
+)
+
+// File is a YAML test corpus.
+type File struct {
+	Tests []Case `yaml:"tests"`
+}
+
+// Case is one request and the outcome the rules must produce for it.
+type Case struct {
+	Name    string  `yaml:"name"`
+	Request Request `yaml:"request"`
+	Expect  Expect  `yaml:"expect"`
+	// Source is the corpus file the case was loaded from.
+	Source string `yaml:"-"`
+}
+
+type Request struct {
+	Method     string            `yaml:"method"`
+	Host       string            `yaml:"host"`
+	Path       string            `yaml:"path"`
+	Query      string            `yaml:"query"`
+	Headers    map[string]string `yaml:"headers"`
+	Body       string            `yaml:"body"`
+	RemoteAddr string            `yaml:"remoteAddr"`
+}
+
+// Expect lists rule IDs that must and must not match and, optionally, the
+// action the gateway must take (allow, block, shadow or redirect).
+type Expect struct {
+	Matched    []string `yaml:"matched"`
+	NotMatched []string `yaml:"notMatched"`
+	Action     string   `yaml:"action"`
+}
+
+// Result is the outcome of one case. A case passed when Failures is empty
+// and Err is nil.
+type Result struct {
+	Case     Case
+	Matched  []string
+	Action   string
+	Failures []string
+	Err      error
+	Duration time.Duration
+}
+
+func (r Result) Passed() bool {
+	return r.Err == nil && len(r.Failures) == 0
+}
+
+// Load reads the cases of every corpus file in order.
+func Load(paths ...string) ([]Case, error) {
+	var cases []Case
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read rule tests: %w", err)
+		}
+		var file File
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("parse rule tests %s: %w", path, err)
+		}
+		for i, tc := range file.Tests {
+			if tc.Name == "" {
+				return nil, fmt.Errorf("%s: tests[%d].name is required", path, i)
+			}
+			tc.Source = path
+			cases = append(cases, tc)
+		}
+	}
+	return cases, nil
+}
+
+// Run evaluates every case against gw without contacting upstreams.
+func Run(gw *gateway.Gateway, cases []Case) []Result {
+	results := make([]Result, 0, len(cases))
+	for _, tc := range cases {
+		start := time.Now()
+		result := runCase(gw, tc)
+		result.Duration = time.Since(start)
+		results = append(results, result)
+	}
+	return results
+}
+
+func runCase(gw *gateway.Gateway, tc Case) Result {
+	result := Result{Case: tc}
+	req, err := tc.Request.build()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	ev, err := gw.Evaluate(req)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	matched := map[string]bool{}
+	for _, m := range ev.Result.Matches {
+		result.Matched = append(result.Matched, m.RuleID)
+		matched[m.RuleID] = true
+	}
+	result.Action = ev.Decision.Action
+
+	for _, id := range tc.Expect.Matched {
+		if !matched[id] {
+			result.Failures = append(result.Failures, fmt.Sprintf("expected rule %s to match", id))
+		}
+	}
+	for _, id := range tc.Expect.NotMatched {
+		if matched[id] {
+			result.Failures = append(result.Failures, fmt.Sprintf("expected rule %s not to match", id))
+		}
+	}
+	if tc.Expect.Action != "" && !strings.EqualFold(tc.Expect.Action, result.Action) {
+		result.Failures = append(result.Failures, fmt.Sprintf("expected action %s, got %s", tc.Expect.Action, result.Action))
+	}
+	return result
+}
+
+func (r Request) build() (*http.Request, error) {
+	host := r.Host
+	if host == "" {
+		host = "localhost"
+	}
+	path := r.Path
+	if path == "" {
+		path = "/"
+	}
+	// The query is kept as written so cases can carry raw attack payloads.
+	target := "http://" + host + (&url.URL{Path: path}).EscapedPath()
+	if r.Query != "" {
+		target += "?" + r.Query
+	}
+	header := make(http.Header, len(r.Headers))
+	for name, value := range r.Headers {
+		header.Set(name, value)
+	}
+	req, err := explain.NewRequest(r.Method, target, header, r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if r.RemoteAddr != "" {
+		req.RemoteAddr = r.RemoteAddr
+	}
+	return req, nil
+}
@@ -0,0 +1,101 @@
+package ruletest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klyr/klyr/internal/config"
+	"github.com/klyr/klyr/internal/gateway"
+)
+
+const corpus = `tests:
+  - name: quote in search
+    request:
+      path: /search
+      query: q=O'Brien
+    expect:
+      matched: [quote]
+      notMatched: [scanner]
+      action: shadow
+  - name: scanner agent
+    request:
+      method: post
+      path: /login
+      headers:
+        User-Agent: sqlmap/1.7
+      body: user=admin
+    expect:
+      matched: [scanner]
+      action: shadow
+  - name: wrong expectation
+    request:
+      path: /
+    expect:
+      matched: [quote]
+      action: block
+`
+
+func testGateway(t *testing.T) *gateway.Gateway {
+	t.Helper()
+	gw, err := gateway.NewOffline(&config.Config{
+		Upstreams: []config.Upstream{{Name: "backend", URL: "http://127.0.0.1:1"}},
+		Routes:    []config.Route{{Match: config.RouteMatch{PathPrefix: "/"}, Upstream: "backend", Policy: "default"}},
+		Policies: map[string]config.Policy{
+			"default": {Mode: config.ModeShadow, AnomalyThreshold: 5, Limits: config.Limits{MaxBodyBytes: 1024, MaxHeaderBytes: 1024, Timeout: time.Second}},
+		},
+		Rules: []config.Rule{
+			{ID: "quote", Score: 5, Condition: &config.Condition{Target: "ARGS", Op: "contains", Value: "'"}},
+			{ID: "scanner", Condition: &config.Condition{Target: "HEADERS:User-Agent", Op: "contains", Value: "sqlmap"}, Action: config.RuleAction{Type: config.RuleActionBlock}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewOffline error: %v", err)
+	}
+	return gw
+}
+
+func TestRunCases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cases.yaml")
+	if err := os.WriteFile(path, []byte(corpus), 0o644); err != nil {
+		t.Fatalf("write corpus: %v", err)
+	}
+	cases, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	results := Run(testGateway(t), cases)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if !results[0].Passed() || !results[1].Passed() {
+		t.Fatalf("expected first two cases to pass, got %+v %+v", results[0], results[1])
+	}
+	if got := results[2].Failures; len(got) != 2 || got[0] != "expected rule quote to match" || got[1] != "expected action block, got allow" {
+		t.Fatalf("unexpected failures: %q", got)
+	}
+
+	var out bytes.Buffer
+	if err := WriteJUnit(&out, results); err != nil {
+		t.Fatalf("WriteJUnit error: %v", err)
+	}
+	for _, want := range []string{`<testsuites tests="3" failures="1" errors="0">`, `<testcase name="scanner agent"`, `<failure message="expected rule quote to match">`} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in junit output:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestLoadRequiresName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cases.yaml")
+	if err := os.WriteFile(path, []byte("tests:\n  - request: {path: /}\n"), 0o644); err != nil {
+		t.Fatalf("write corpus: %v", err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "tests[0].name is required") {
+		t.Fatalf("expected missing name error, got %v", err)
+	}
+}
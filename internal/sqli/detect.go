@@ -0,0 +1,77 @@
+package sqli
+
+import "strings"
+
+const (
+	// fingerprintLen is the number of folded tokens a fingerprint keeps.
+	fingerprintLen = 5
+	// maxTokens bounds tokenizing before folding.
+	maxTokens = 16
+)
+
+// Detect reports whether input tokenizes to a known SQL injection
+// fingerprint, reading it as bare SQL and as the tail of a single- or
+// double-quoted string. It returns the matching fingerprint.
+func Detect(input string) (bool, string) {
+	for _, quote := range []byte{0, '\'', '"'} {
+		if quote != 0 && strings.IndexByte(input, quote) < 0 {
+			continue
+		}
+		fp := fingerprint(input, quote)
+		if attacks[fp] {
+			return true, fp
+		}
+	}
+	return false, ""
+}
+
+// Fingerprint returns the fingerprint of input read as bare SQL.
+func Fingerprint(input string) string {
+	return fingerprint(input, 0)
+}
+
+func fingerprint(input string, quote byte) string {
+	tokens := fold(tokenize(input, quote, maxTokens))
+	if len(tokens) > fingerprintLen {
+		tokens = tokens[:fingerprintLen]
+	}
+	b := make([]byte, len(tokens))
+	for i, t := range tokens {
+		b[i] = t.kind
+	}
+	return string(b)
+}
+
+// fold drops what does not change the structure of a statement: comments
+// except a trailing one, unary operators, the second word of UNION ALL and
+// GROUP/ORDER BY, and adjacent strings, which concatenate.
+func fold(tokens []token) []token {
+	out := make([]token, 0, len(tokens))
+	for _, t := range tokens {
+		var prev byte
+		if len(out) > 0 {
+			prev = out[len(out)-1].kind
+		}
+		switch {
+		case t.kind == tokenComment:
+			continue
+		case prev == tokenUnion && (strings.EqualFold(t.value, "ALL") || strings.EqualFold(t.value, "DISTINCT")):
+			continue
+		case prev == tokenGroup && strings.EqualFold(t.value, "BY"):
+			continue
+		case t.kind == tokenOperator && isUnary(t.value) && (prev == 0 || strings.IndexByte("o&(,;EkUB", prev) >= 0):
+			continue
+		case t.kind == tokenString && prev == tokenString:
+			continue
+		}
+		out = append(out, t)
+	}
+	if n := len(tokens); n > 0 && tokens[n-1].kind == tokenComment {
+		out = append(out, tokens[n-1])
+	}
+	return out
+}
+
+func isUnary(op string) bool {
+	return op == "+" || op == "-" || op == "!" || op == "~" || strings.EqualFold(op, "NOT")
+}
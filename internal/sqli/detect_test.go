@@ -0,0 +1,60 @@
+package sqli
+
+import "testing"
+
+func TestDetectAttacks(t *testing.T) {
+	cases := map[string]string{
+		"1' or '1'='1":                                    "s&sos",
+		"' or 1=1--":                                      "s&1o1",
+		"1 or 1=1":                                        "1&1o1",
+		"admin'--":                                        "sc",
+		"\" or \"a\"=\"a":                                 "s&sos",
+		"') or ('a'='a":                                   "s)&(s",
+		"-1 union all select 1,2,3":                       "1UE1,",
+		"1/**/union/**/select/**/1":                       "1UE1",
+		"/*!50000UnIoN*/ /*!50000SeLeCt*/ 1":              "UE1",
+		"' union select password from users--":            "sUEnk",
+		"1; drop table users":                             "1;Ekn",
+		"1' and sleep(5)#":                                "s&f(1",
+		"1 and benchmark(1000000,md5(1))":                 "1&f(1",
+		"1' waitfor delay '0:0:5'--":                      "skksc",
+		"' and 1 in (select min(name) from sysobjects)--": "s&1o(",
+		"1 order by 3--":                                  "1B1c",
+		"'||(select user from dual)||'":                   "s&(En",
+		"1' AND 1=CONVERT(int,(SELECT @@version))--":      "s&1of",
+	}
+	for input, want := range cases {
+		matched, fp := Detect(input)
+		if !matched || fp != want {
+			t.Errorf("Detect(%q) = %v, %q; want %q", input, matched, fp, want)
+		}
+	}
+}
+
+func TestDetectIgnoresText(t *testing.T) {
+	for _, input := range []string{
+		"", "hello world", "O'Brien", "john.doe@example.com", "Select your plan",
+		"2024-01-01", "rock and roll", "it's 5 o'clock", "search for union station",
+		"Tom & Jerry", "(555) 123-4567", "C# or Java", "she said \"hi\" to me",
+		"order by price", "1 or 2", "x=1 and y=2", "5' 10\"", "I can't (really) say",
+	} {
+		if matched, fp := Detect(input); matched {
+			t.Errorf("Detect(%q) matched %q", input, fp)
+		}
+	}
+}
+
+func TestFingerprintFolding(t *testing.T) {
+	cases := map[string]string{
+		"SELECT 'a' 'b' FROM t":  "Eskn",
+		"1 -- trailing":          "1c",
+		"1 /* inline */ + -2":    "1o1",
+		"1 GROUP BY x":           "1Bn",
+		"select @@version, 0x41": "Ev,1",
+	}
+	for input, want := range cases {
+		if got := Fingerprint(input); got != want {
+			t.Errorf("Fingerprint(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,2 @@
+// Package sqli provides functionality for Klyr.
+package sqli
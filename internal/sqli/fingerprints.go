@@ -0,0 +1,46 @@
+package sqli
+
+// Token types: s string, 1 number or literal, n name, v variable,
+// k keyword, f function, E statement, U union, B group/order by,
+// & and/or, o operator, c trailing comment, and ( ) , ; as themselves.
+var fingerprints = []string{
+	// Tautologies and boolean tests after a closed string or a number.
+	"s&sos", "s&so1", "s&1os", "s&1o1", "s&1o(", "s&1of", "s&1c", "s&sc",
+	"1&1o1", "1&1o(", "1&1of", "1&so1", "&1o1", "&sos",
+	"s)&(s", "s)&(1", "s)&1o", "s))&(", "1)&(1", "1)&(s", "1)&1o", "1))&(",
+	"so1c", "sc", "sk1o1",
+
+	// Function calls such as sleep, benchmark or extractvalue.
+	"s&f(1", "s&f(f", "s&f(s", "s&f()", "s&f(v", "sof(1", "sof(s",
+	"1&f(1", "1&f(f", "1&f(s", "1&f()", "1&f(v", "1of(1", "1of(s",
+
+	// Subqueries.
+	"s&(E1", "s&(En", "s&(Ef", "s&(Eo", "s&(Ev",
+	"1&(E1", "1&(En", "1&(Ef", "1&(Eo", "1&(Ev",
+
+	// UNION SELECT.
+	"UE1", "UE1,", "UE1c", "UEn,", "UEnk", "UEf(", "UEok", "UEv", "UEvc",
+	"1UE1", "1UE1,", "1UE1c", "1UEn,", "1UEnk", "1UEf(", "1UEok", "1UEv", "1UEvc",
+	"sUE1", "sUE1,", "sUE1c", "sUEn,", "sUEnk", "sUEf(", "sUEok", "sUEv", "sUEvc",
+	"nUE1,", "nUE1c", "nUEnk", "nUEf(", "nUEok",
+	"s)UE1", "1)UE1",
+
+	// Stacked statements.
+	"1;E1", "1;En", "1;Ec", "1;Ekn", "1;Enk", "1;Ens", "1;Eok", "1;Ef(", "1;kks",
+	"s;E1", "s;En", "s;Ec", "s;Ekn", "s;Enk", "s;Ens", "s;Eok", "s;Ef(", "s;kks",
+
+	// ORDER BY and GROUP BY probing, HAVING, WAITFOR and INTO OUTFILE.
+	"1B1", "1B1c", "sB1", "sB1c", "1Bnk1", "sBnk1",
+	"1kks", "skks", "skksc",
+
+	// Bare statements.
+	"Eokn", "Eoknk", "Eoknc",
+}
+
+var attacks = func() map[string]bool {
+	set := make(map[string]bool, len(fingerprints))
+	for _, fp := range fingerprints {
+		set[fp] = true
+	}
+	return set
+}()
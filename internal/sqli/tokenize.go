@@ -0,0 +1,241 @@
+package sqli
+
+import "strings"
+
+// Token types. Each token contributes one byte to a fingerprint; ( ) , and
+// ; stand for themselves.
+const (
+	tokenString    = 's'
+	tokenNumber    = '1'
+	tokenVariable  = 'v'
+	tokenBareword  = 'n'
+	tokenKeyword   = 'k'
+	tokenFunction  = 'f'
+	tokenStatement = 'E'
+	tokenUnion     = 'U'
+	tokenGroup     = 'B'
+	tokenLogic     = '&'
+	tokenOperator  = 'o'
+	tokenComment   = 'c'
+)
+
+type token struct {
+	kind  byte
+	value string
+}
+
+// words classifies SQL keywords. Barewords that are not listed are names,
+// or functions when an opening parenthesis follows.
+var words = map[string]byte{
+	"SELECT": tokenStatement, "INSERT": tokenStatement, "UPDATE": tokenStatement,
+	"DELETE": tokenStatement, "DROP": tokenStatement, "CREATE": tokenStatement,
+	"ALTER": tokenStatement, "EXEC": tokenStatement, "EXECUTE": tokenStatement,
+	"DECLARE": tokenStatement, "SHUTDOWN": tokenStatement, "TRUNCATE": tokenStatement,
+	"UNION": tokenUnion,
+	"GROUP": tokenGroup, "ORDER": tokenGroup,
+	"AND": tokenLogic, "OR": tokenLogic, "XOR": tokenLogic,
+	"NOT": tokenOperator, "LIKE": tokenOperator, "RLIKE": tokenOperator,
+	"REGEXP": tokenOperator, "SOUNDS": tokenOperator, "IS": tokenOperator,
+	"IN": tokenOperator, "BETWEEN": tokenOperator, "DIV": tokenOperator, "MOD": tokenOperator,
+	"NULL": tokenNumber, "TRUE": tokenNumber, "FALSE": tokenNumber,
+	"FROM": tokenKeyword, "WHERE": tokenKeyword, "HAVING": tokenKeyword,
+	"LIMIT": tokenKeyword, "OFFSET": tokenKeyword, "INTO": tokenKeyword,
+	"OUTFILE": tokenKeyword, "DUMPFILE": tokenKeyword, "TABLE": tokenKeyword,
+	"VALUES": tokenKeyword, "SET": tokenKeyword, "CASE": tokenKeyword,
+	"WHEN": tokenKeyword, "THEN": tokenKeyword, "ELSE": tokenKeyword,
+	"END": tokenKeyword, "AS": tokenKeyword, "DISTINCT": tokenKeyword,
+	"WAITFOR": tokenKeyword, "DELAY": tokenKeyword, "PROCEDURE": tokenKeyword,
+	"ASC": tokenKeyword, "DESC": tokenKeyword, "JOIN": tokenKeyword,
+	"ON": tokenKeyword, "USING": tokenKeyword, "TOP": tokenKeyword, "ALL": tokenKeyword, "BY": tokenKeyword,
+}
+
+// operators are matched longest first.
+var operators = []string{"<=>", "<>", "!=", "<=", ">=", "||", "&&", ":=", "<<", ">>",
+	"=", "<", ">", "!", "+", "-", "*", "/", "%", "|", "&", "^", "~"}
+
+// tokenize splits input into SQL tokens. A non-zero quote treats the input as
+// the continuation of a string literal opened with that quote, the way a
+// value is embedded in a quoted SQL string.
+func tokenize(input string, quote byte, limit int) []token {
+	var tokens []token
+	i := 0
+	if quote != 0 {
+		end := stringEnd(input, 0, quote)
+		tokens = append(tokens, token{kind: tokenString, value: input[:min(end, len(input))]})
+		i = end + 1
+	}
+
+	inCode := false // inside a MySQL /*! ... */ comment, which runs as code
+	for i < len(input) && len(tokens) < limit {
+		c := input[i]
+		switch {
+		case isSpace(c):
+			i++
+		case c == '\'' || c == '"':
+			end := stringEnd(input, i+1, c)
+			tokens = append(tokens, token{kind: tokenString, value: input[i+1 : min(end, len(input))]})
+			i = end + 1
+		case c == '`':
+			end := stringEnd(input, i+1, c)
+			tokens = append(tokens, token{kind: tokenBareword, value: input[i+1 : min(end, len(input))]})
+			i = end + 1
+		case strings.HasPrefix(input[i:], "/*!"):
+			i += 3
+			for i < len(input) && isDigit(input[i]) {
+				i++
+			}
+			inCode = true
+		case inCode && strings.HasPrefix(input[i:], "*/"):
+			i += 2
+			inCode = false
+		case strings.HasPrefix(input[i:], "/*"):
+			end := strings.Index(input[i+2:], "*/")
+			if end < 0 {
+				end = len(input)
+			} else {
+				end += i + 4
+			}
+			tokens = append(tokens, token{kind: tokenComment, value: input[i:end]})
+			i = end
+		case strings.HasPrefix(input[i:], "--") || c == '#':
+			end := strings.IndexByte(input[i:], '\n')
+			if end < 0 {
+				end = len(input)
+			} else {
+				end += i
+			}
+			tokens = append(tokens, token{kind: tokenComment, value: input[i:end]})
+			i = end
+		case isDigit(c) || c == '.' && i+1 < len(input) && isDigit(input[i+1]):
+			end := numberEnd(input, i)
+			tokens = append(tokens, token{kind: tokenNumber, value: input[i:end]})
+			i = end
+		case c == '@':
+			end := i + 1
+			if end < len(input) && input[end] == '@' {
+				end++
+			}
+			end = wordEnd(input, end)
+			tokens = append(tokens, token{kind: tokenVariable, value: input[i:end]})
+			i = end
+		case isWordStart(c):
+			end := wordEnd(input, i)
+			tokens = append(tokens, word(input[i:end], nextIsOpen(input, end)))
+			i = end
+		case c == '(' || c == ')' || c == ',' || c == ';':
+			tokens = append(tokens, token{kind: c, value: string(c)})
+			i++
+		default:
+			op := operator(input[i:])
+			if op == "" {
+				i++
+				continue
+			}
+			kind := byte(tokenOperator)
+			if op == "||" || op == "&&" {
+				kind = tokenLogic
+			}
+			tokens = append(tokens, token{kind: kind, value: op})
+			i += len(op)
+		}
+	}
+	return tokens
+}
+
+func word(value string, call bool) token {
+	kind, ok := words[strings.ToUpper(value)]
+	if !ok {
+		kind = tokenBareword
+	}
+	if call && (kind == tokenBareword || kind == tokenKeyword) {
+		kind = tokenFunction
+	}
+	return token{kind: kind, value: value}
+}
+
+// stringEnd returns the index of the quote closing a string that starts at
+// i, or len(input) if it is unterminated. Backslash escapes and doubled
+// quotes do not close it.
+func stringEnd(input string, i int, quote byte) int {
+	for i < len(input) {
+		switch input[i] {
+		case '\\':
+			i += 2
+			continue
+		case quote:
+			if i+1 < len(input) && input[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i
+		}
+		i++
+	}
+	return len(input)
+}
+
+func numberEnd(input string, i int) int {
+	if strings.HasPrefix(input[i:], "0x") || strings.HasPrefix(input[i:], "0X") ||
+		strings.HasPrefix(input[i:], "0b") || strings.HasPrefix(input[i:], "0B") {
+		i += 2
+		for i < len(input) && isHexDigit(input[i]) {
+			i++
+		}
+		return i
+	}
+	for i < len(input) && (isDigit(input[i]) || input[i] == '.') {
+		i++
+	}
+	if i < len(input) && (input[i] == 'e' || input[i] == 'E') {
+		j := i + 1
+		if j < len(input) && (input[j] == '+' || input[j] == '-') {
+			j++
+		}
+		if j < len(input) && isDigit(input[j]) {
+			for j < len(input) && isDigit(input[j]) {
+				j++
+			}
+			i = j
+		}
+	}
+	return i
+}
+
+func wordEnd(input string, i int) int {
+	for i < len(input) && (isWordStart(input[i]) || isDigit(input[i]) || input[i] == '.') {
+		i++
+	}
+	return i
+}
+
+func nextIsOpen(input string, i int) bool {
+	for i < len(input) && isSpace(input[i]) {
+		i++
+	}
+	return i < len(input) && input[i] == '('
+}
+
+func operator(s string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f' || c == 0xa0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func isWordStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '$'
+}
@@ -0,0 +1,45 @@
+package upstream
+
+import (
+	"hash/fnv"
+	"sync/atomic"
+)
+
+func pickRoundRobin(targets []*Target, counter uint64) *Target {
+	return targets[counter%uint64(len(targets))]
+}
+
+// pickLeastConn returns the target with the fewest in-flight requests. Ties
+// are broken by rotating the starting index so equal targets share load.
+func pickLeastConn(targets []*Target, counter uint64) *Target {
+	start := int(counter % uint64(len(targets)))
+	best := targets[start]
+	bestActive := atomic.LoadInt64(&best.active)
+	for i := 1; i < len(targets); i++ {
+		t := targets[(start+i)%len(targets)]
+		if active := atomic.LoadInt64(&t.active); active < bestActive {
+			best = t
+			bestActive = active
+		}
+	}
+	return best
+}
+
+// pickConsistentHash uses rendezvous hashing so a client keeps its target
+// and only clients of a removed target move when the healthy set changes.
+func pickConsistentHash(targets []*Target, key string) *Target {
+	var best *Target
+	var bestScore uint64
+	for _, t := range targets {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(key))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(t.String()))
+		score := h.Sum64()
+		if best == nil || score > bestScore {
+			best = t
+			bestScore = score
+		}
+	}
+	return best
+}
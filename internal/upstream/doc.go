@@ -0,0 +1,2 @@
+// Package upstream provides load-balanced upstream pools with health checks for Klyr.
+package upstream
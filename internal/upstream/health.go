@@ -0,0 +1,67 @@
+package upstream
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func (p *Pool) probeLoop(t *Target) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.health.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.probe(t)
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Pool) probe(t *Target) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.client.Timeout)
+	defer cancel()
+
+	ok := false
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL(t, p.health.Path), nil)
+	if err == nil {
+		resp, err := p.client.Do(req)
+		if err == nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			_ = resp.Body.Close()
+			ok = resp.StatusCode >= 200 && resp.StatusCode < 400
+		}
+	}
+	p.recordProbe(t, ok)
+}
+
+func (p *Pool) recordProbe(t *Target, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if ok {
+		t.probeFail = 0
+		t.probeOK++
+		if !t.healthy.Load() && t.probeOK >= p.health.HealthyThreshold {
+			t.healthy.Store(true)
+		}
+		return
+	}
+
+	t.probeOK = 0
+	t.probeFail++
+	if t.healthy.Load() && t.probeFail >= p.health.UnhealthyThreshold {
+		t.healthy.Store(false)
+	}
+}
+
+func probeURL(t *Target, path string) string {
+	base := strings.TrimSuffix(t.URL.String(), "/")
+	return base + path
+}
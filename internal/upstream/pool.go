@@ -0,0 +1,256 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klyr/klyr/internal/config"
+)
+
+var ErrNoHealthyTargets = errors.New("no healthy upstream targets")
+
+// ProxyFactory builds the reverse proxy used to forward requests to a target.
+type ProxyFactory func(target *url.URL) *httputil.ReverseProxy
+
+type Pool struct {
+	Name string
+
+	targets  []*Target
+	balancer string
+	rr       uint64
+
+	health  config.HealthCheckConfig
+	passive config.PassiveHealthConfig
+	client  *http.Client
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+type Target struct {
+	URL   *url.URL
+	proxy *httputil.ReverseProxy
+
+	active       int64
+	healthy      atomic.Bool
+	ejectedUntil atomic.Int64
+
+	mu          sync.Mutex
+	probeOK     int
+	probeFail   int
+	passiveFail int
+}
+
+// TargetState is a point-in-time view of a target's health.
+type TargetState struct {
+	Upstream string
+	Target   string
+	Healthy  bool
+	Ejected  bool
+	Active   int64
+}
+
+func NewPool(cfg config.Upstream, transport http.RoundTripper, newProxy ProxyFactory) (*Pool, error) {
+	raw := cfg.TargetURLs()
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("upstream %s has no targets", cfg.Name)
+	}
+
+	balancer := cfg.Balancer
+	if balancer == "" {
+		balancer = config.BalancerRoundRobin
+	}
+
+	p := &Pool{
+		Name:     cfg.Name,
+		balancer: balancer,
+		health:   cfg.HealthCheck,
+		passive:  cfg.PassiveHealth,
+		stop:     make(chan struct{}),
+	}
+
+	for _, item := range raw {
+		parsed, err := url.Parse(item)
+		if err != nil {
+			return nil, fmt.Errorf("parse upstream %s target %s: %w", cfg.Name, item, err)
+		}
+		t := &Target{URL: parsed}
+		t.healthy.Store(true)
+		t.proxy = p.instrument(t, newProxy(parsed))
+		p.targets = append(p.targets, t)
+	}
+
+	if p.health.Enabled {
+		timeout := p.health.Timeout
+		if timeout <= 0 {
+			timeout = p.health.Interval
+		}
+		p.client = &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+
+	return p, nil
+}
+
+// CarryOver copies the health and ejection state of prev's targets to the
+// targets of p with the same URL, so a reload does not send traffic back to
+// targets already found down. prev may be nil.
+func (p *Pool) CarryOver(prev *Pool) {
+	if p == nil || prev == nil {
+		return
+	}
+	old := make(map[string]*Target, len(prev.targets))
+	for _, t := range prev.targets {
+		old[t.String()] = t
+	}
+	for _, t := range p.targets {
+		o, ok := old[t.String()]
+		if !ok {
+			continue
+		}
+		o.mu.Lock()
+		t.healthy.Store(o.healthy.Load())
+		t.ejectedUntil.Store(o.ejectedUntil.Load())
+		t.probeOK, t.probeFail, t.passiveFail = o.probeOK, o.probeFail, o.passiveFail
+		o.mu.Unlock()
+	}
+}
+
+// Start launches active health probes if they are configured.
+func (p *Pool) Start() {
+	if p == nil || !p.health.Enabled {
+		return
+	}
+	for _, t := range p.targets {
+		p.wg.Add(1)
+		go p.probeLoop(t)
+	}
+}
+
+// Close stops health probes and waits for them to exit.
+func (p *Pool) Close() {
+	if p == nil {
+		return
+	}
+	p.stopOnce.Do(func() { close(p.stop) })
+	p.wg.Wait()
+}
+
+// Pick selects an available target for the request. It also returns the
+// targets that were skipped because they are unhealthy or ejected.
+func (p *Pool) Pick(clientIP string) (*Target, []string, error) {
+	now := time.Now()
+	available := make([]*Target, 0, len(p.targets))
+	var skipped []string
+	for _, t := range p.targets {
+		if t.available(now) {
+			available = append(available, t)
+		} else {
+			skipped = append(skipped, t.String())
+		}
+	}
+	if len(available) == 0 {
+		return nil, skipped, ErrNoHealthyTargets
+	}
+
+	var chosen *Target
+	switch p.balancer {
+	case config.BalancerLeastConn:
+		chosen = pickLeastConn(available, atomic.AddUint64(&p.rr, 1))
+	case config.BalancerConsistentHash:
+		chosen = pickConsistentHash(available, clientIP)
+	default:
+		chosen = pickRoundRobin(available, atomic.AddUint64(&p.rr, 1))
+	}
+	return chosen, skipped, nil
+}
+
+func (p *Pool) States() []TargetState {
+	if p == nil {
+		return nil
+	}
+	now := time.Now()
+	out := make([]TargetState, 0, len(p.targets))
+	for _, t := range p.targets {
+		out = append(out, TargetState{
+			Upstream: p.Name,
+			Target:   t.String(),
+			Healthy:  t.available(now),
+			Ejected:  now.UnixNano() < t.ejectedUntil.Load(),
+			Active:   atomic.LoadInt64(&t.active),
+		})
+	}
+	return out
+}
+
+func (t *Target) String() string {
+	return t.URL.String()
+}
+
+func (t *Target) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&t.active, 1)
+	defer atomic.AddInt64(&t.active, -1)
+	t.proxy.ServeHTTP(w, r)
+}
+
+func (t *Target) available(now time.Time) bool {
+	return t.healthy.Load() && now.UnixNano() >= t.ejectedUntil.Load()
+}
+
+// instrument wraps the proxy hooks so upstream errors and 5xx responses feed
+// passive health tracking.
+func (p *Pool) instrument(t *Target, proxy *httputil.ReverseProxy) *httputil.ReverseProxy {
+	if !p.passive.Enabled {
+		return proxy
+	}
+
+	errorHandler := proxy.ErrorHandler
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		if !errors.Is(err, context.Canceled) {
+			p.recordPassive(t, false)
+		}
+		if errorHandler != nil {
+			errorHandler(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}
+
+	modify := proxy.ModifyResponse
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		p.recordPassive(t, resp.StatusCode < http.StatusInternalServerError)
+		if modify != nil {
+			return modify(resp)
+		}
+		return nil
+	}
+	return proxy
+}
+
+func (p *Pool) recordPassive(t *Target, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if ok {
+		t.passiveFail = 0
+		return
+	}
+	t.passiveFail++
+	if t.passiveFail >= p.passive.MaxFailures {
+		t.passiveFail = 0
+		t.ejectedUntil.Store(time.Now().Add(p.passive.EjectDuration).UnixNano())
+	}
+}
@@ -0,0 +1,162 @@
+package upstream
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/klyr/klyr/internal/config"
+)
+
+func testProxy(target *url.URL) *httputil.ReverseProxy {
+	return httputil.NewSingleHostReverseProxy(target)
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	pool, err := NewPool(config.Upstream{
+		Name:    "app",
+		Targets: []string{"http://10.0.0.1", "http://10.0.0.2", "http://10.0.0.3"},
+	}, http.DefaultTransport, testProxy)
+	if err != nil {
+		t.Fatalf("NewPool error: %v", err)
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 30; i++ {
+		target, _, err := pool.Pick("203.0.113.1")
+		if err != nil {
+			t.Fatalf("Pick error: %v", err)
+		}
+		counts[target.String()]++
+	}
+	for _, raw := range []string{"http://10.0.0.1", "http://10.0.0.2", "http://10.0.0.3"} {
+		if counts[raw] != 10 {
+			t.Fatalf("expected 10 picks for %s, got %d", raw, counts[raw])
+		}
+	}
+}
+
+func TestPoolConsistentHashIsSticky(t *testing.T) {
+	pool, err := NewPool(config.Upstream{
+		Name:     "app",
+		Targets:  []string{"http://10.0.0.1", "http://10.0.0.2", "http://10.0.0.3"},
+		Balancer: config.BalancerConsistentHash,
+	}, http.DefaultTransport, testProxy)
+	if err != nil {
+		t.Fatalf("NewPool error: %v", err)
+	}
+
+	first, _, _ := pool.Pick("203.0.113.7")
+	for i := 0; i < 10; i++ {
+		next, _, _ := pool.Pick("203.0.113.7")
+		if next != first {
+			t.Fatalf("expected sticky target %s, got %s", first, next)
+		}
+	}
+}
+
+func TestPoolLeastConn(t *testing.T) {
+	pool, err := NewPool(config.Upstream{
+		Name:     "app",
+		Targets:  []string{"http://10.0.0.1", "http://10.0.0.2"},
+		Balancer: config.BalancerLeastConn,
+	}, http.DefaultTransport, testProxy)
+	if err != nil {
+		t.Fatalf("NewPool error: %v", err)
+	}
+
+	pool.targets[0].active = 5
+	for i := 0; i < 4; i++ {
+		target, _, _ := pool.Pick("")
+		if target != pool.targets[1] {
+			t.Fatalf("expected least loaded target, got %s", target)
+		}
+	}
+}
+
+func TestPoolPassiveEjection(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+
+	pool, err := NewPool(config.Upstream{
+		Name:          "app",
+		Targets:       []string{failing.URL, healthy.URL},
+		Balancer:      config.BalancerConsistentHash,
+		PassiveHealth: config.PassiveHealthConfig{Enabled: true, MaxFailures: 2, EjectDuration: time.Minute},
+	}, http.DefaultTransport, testProxy)
+	if err != nil {
+		t.Fatalf("NewPool error: %v", err)
+	}
+
+	bad := pool.targets[0]
+	for i := 0; i < 2; i++ {
+		bad.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	}
+
+	for i := 0; i < 5; i++ {
+		target, skipped, err := pool.Pick("client")
+		if err != nil {
+			t.Fatalf("Pick error: %v", err)
+		}
+		if target == bad {
+			t.Fatal("expected ejected target to be skipped")
+		}
+		if len(skipped) != 1 || skipped[0] != failing.URL {
+			t.Fatalf("expected skipped [%s], got %v", failing.URL, skipped)
+		}
+	}
+}
+
+func TestPoolActiveHealthCheck(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			t.Errorf("unexpected probe path %q", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer backend.Close()
+
+	pool, err := NewPool(config.Upstream{
+		Name:    "app",
+		Targets: []string{backend.URL},
+		HealthCheck: config.HealthCheckConfig{
+			Enabled:            true,
+			Path:               "/healthz",
+			Interval:           10 * time.Millisecond,
+			HealthyThreshold:   1,
+			UnhealthyThreshold: 2,
+		},
+	}, http.DefaultTransport, testProxy)
+	if err != nil {
+		t.Fatalf("NewPool error: %v", err)
+	}
+	pool.Start()
+	defer pool.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		_, _, err := pool.Pick("")
+		if errors.Is(err, ErrNoHealthyTargets) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected target to be marked unhealthy")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	states := pool.States()
+	if len(states) != 1 || states[0].Healthy {
+		t.Fatalf("expected unhealthy state, got %+v", states)
+	}
+}
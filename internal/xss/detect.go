@@ -0,0 +1,129 @@
+package xss
+
+import "strings"
+
+// Detect reports whether input would run script when reflected into an
+// HTML page: as markup, as an attribute value breaking out of its quotes,
+// as a URL, or as a JavaScript string breaking out of its quotes. It returns
+// the offending token, such as "<script", "onerror" or "javascript:".
+func Detect(input string) (bool, string) {
+	if ok, token := scanHTML(input); ok {
+		return true, token
+	}
+	if scheme := urlScheme(input); scheme != "" {
+		return true, scheme
+	}
+	if ok, token := attributeBreakout(input); ok {
+		return true, token
+	}
+	return scriptBreakout(input)
+}
+
+// attributeBreakout reads what follows a quote or > as the rest of a tag's
+// attribute list, as in x" onmouseover=alert(1). Without such a breakout an
+// event handler name is prose, like "Use the onclick= attribute".
+func attributeBreakout(input string) (bool, string) {
+	i := strings.IndexAny(input, "\"'>")
+	for i >= 0 {
+		rest := input[i+1:]
+		if ok, token := scanAttributes(rest, knownHandler); ok {
+			return true, token
+		}
+		next := strings.IndexByte(rest, '>')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return false, ""
+}
+
+// urlScheme reports a script URL making up the whole input. Unlike in an
+// attribute, prose like "javascript: the good parts" is not a URL.
+func urlScheme(input string) string {
+	scheme := scriptScheme(input)
+	if scheme == "" || strings.HasPrefix(scheme, "data:") {
+		return scheme
+	}
+	trimmed := strings.TrimLeft(decodeEntities(input), "\x00\x01\x02\x03\x04\x05\x06\x07\x08\t\n\v\f\r \x1f")
+	rest := trimmed[min(len(scheme), len(trimmed)):]
+	if strings.ContainsAny(rest, "(`=") || rest != "" && !isSpace(rest[0]) {
+		return scheme
+	}
+	return ""
+}
+
+// handlers are the event handler attributes recognized outside a tag, where
+// any on* word is too likely to be prose.
+var handlers = map[string]bool{}
+
+func init() {
+	for _, name := range strings.Fields(`
+		abort activate afterprint animationend animationiteration animationstart
+		auxclick beforecopy beforecut beforeinput beforepaste beforeprint
+		beforeunload begin blur canplay canplaythrough change click close
+		contextmenu copy cuechange cut dblclick drag dragend dragenter dragleave
+		dragover dragstart drop durationchange end ended error focus focusin
+		focusout formdata fullscreenchange hashchange input invalid keydown
+		keypress keyup load loadeddata loadedmetadata loadend loadstart message
+		mousedown mouseenter mouseleave mousemove mouseout mouseover mouseup
+		mousewheel offline online pagehide pageshow paste pause play playing
+		pointerdown pointerenter pointerleave pointermove pointerout pointerover
+		pointerrawupdate pointerup popstate progress ratechange repeat reset
+		resize scroll scrollend search seeked seeking select selectionchange
+		selectstart show stalled storage submit suspend timeupdate toggle
+		touchend touchmove touchstart transitioncancel transitionend
+		transitionrun transitionstart unload volumechange waiting wheel`) {
+		handlers["on"+name] = true
+	}
+}
+
+func knownHandler(name string) bool {
+	return handlers[name]
+}
+
+// sinks are the calls and objects a payload reaches for after breaking out
+// of a JavaScript string.
+var sinks = map[string]bool{
+	"alert": true, "confirm": true, "prompt": true, "eval": true, "print": true,
+	"fetch": true, "settimeout": true, "setinterval": true, "function": true,
+	"import": true, "atob": true, "document": true, "window": true, "top": true,
+	"self": true, "parent": true, "frames": true, "globalthis": true,
+	"location": true, "string": true, "constructor": true,
+}
+
+// scriptBreakout looks for a quote closing a JavaScript string followed by
+// an operator and a call to a known sink, as in '-alert(1)-' or
+// ";document.write(1)//.
+func scriptBreakout(input string) (bool, string) {
+	for i := 0; i < len(input); i++ {
+		if c := input[i]; c != '\'' && c != '"' && c != '`' {
+			continue
+		}
+		j := skipSpace(input, i+1)
+		ops := j
+		for j < len(input) && strings.IndexByte(";+-*/%|&,)}?:", input[j]) >= 0 {
+			j++
+		}
+		if j == ops {
+			continue
+		}
+		j = skipSpace(input, j)
+		start := j
+		for j < len(input) && (isLetter(input[j]) || isDigit(input[j]) || input[j] == '_' || input[j] == '$' || input[j] == '.' || input[j] == '[') {
+			j++
+		}
+		if j == start {
+			continue
+		}
+		ident := strings.ToLower(input[start:j])
+		root, _, _ := strings.Cut(strings.Split(ident, "[")[0], ".")
+		if !sinks[root] {
+			continue
+		}
+		if k := skipSpace(input, j); k < len(input) && (input[k] == '(' || input[k] == '`' || input[k] == '=') {
+			return true, "js:" + root
+		}
+	}
+	return false, ""
+}
@@ -0,0 +1,50 @@
+package xss
+
+import "testing"
+
+func TestDetectPayloads(t *testing.T) {
+	cases := map[string]string{
+		"<script>alert(1)</script>":                     "<script",
+		"<ScRiPt src=//evil.example>":                   "<script",
+		"<scr<script>ipt>alert(1)</scr</script>ipt>":    "<script",
+		"</script><img src=x onerror=alert(1)>":         "<script",
+		"<svg/onload=alert(1)>":                         "onload",
+		"<img src=x onerror=alert(1)>":                  "onerror",
+		"<body onpageshow = alert(1)>":                  "onpageshow",
+		"<details open ontoggle=alert(1)>":              "ontoggle",
+		`<a href="jav&#x09;ascript:alert(1)">x</a>`:     "href=javascript:",
+		"<a href=JaVaScRiPt&colon;alert(1)>x</a>":       "href=javascript:",
+		"<iframe srcdoc='&lt;script&gt;'>":              "<iframe",
+		`<form><button formaction=javascript:alert(1)>`: "formaction=javascript:",
+		`<div style="width:expression(alert(1))">`:      "style",
+		"javascript:alert(document.cookie)":             "javascript:",
+		"  javascript://%0aalert(1)":                    "javascript:",
+		"data:text/html;base64,PHNjcmlwdD4=":            "data:text/html",
+		`x" onmouseover="alert(1)`:                      "onmouseover",
+		"x' onfocus=alert(1) autofocus":                 "onfocus",
+		"a > b onclick=alert(1)":                        "onclick",
+		"';alert(String.fromCharCode(88))//":            "js:alert",
+		`"-confirm(1)-"`:                                "js:confirm",
+	}
+	for input, want := range cases {
+		if matched, token := Detect(input); !matched || token != want {
+			t.Errorf("Detect(%q) = %v, %q; want %q", input, matched, token, want)
+		}
+	}
+}
+
+func TestDetectIgnoresText(t *testing.T) {
+	for _, input := range []string{
+		"", "hello world", "a < b and c > d", "x<3", "<b>bold</b> and <i>italic</i>",
+		`<a href="https://example.com/page?id=1">link</a>`, "<img src=cat.png alt=\"a cat\">",
+		"The script was great", "description: a javascript tutorial",
+		"javascript: the good parts", "onboarding=true", "the onion's layers",
+		"she said \"on time\"", "O'Brien (CEO)", "it's 5 o'clock",
+		"price=10; discount=5", "<p class='note'>Hello</p>",
+		"Use the onclick= attribute",
+	} {
+		if matched, token := Detect(input); matched {
+			t.Errorf("Detect(%q) matched %q", input, token)
+		}
+	}
+}
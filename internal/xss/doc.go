@@ -0,0 +1,2 @@
+// Package xss provides functionality for Klyr.
+package xss
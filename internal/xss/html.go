@@ -0,0 +1,253 @@
+package xss
+
+import (
+	"strconv"
+	"strings"
+)
+
+// dangerousTags run script or load active content on their own.
+var dangerousTags = map[string]bool{
+	"script": true, "iframe": true, "frame": true, "frameset": true, "object": true,
+	"embed": true, "applet": true, "base": true, "meta": true, "link": true,
+	"style": true, "xml": true, "import": true, "isindex": true, "template": true,
+}
+
+// urlAttributes take a URL that is followed or loaded.
+var urlAttributes = map[string]bool{
+	"href": true, "src": true, "action": true, "formaction": true, "data": true,
+	"xlink:href": true, "background": true, "lowsrc": true, "dynsrc": true,
+	"poster": true, "codebase": true, "to": true, "from": true, "values": true,
+}
+
+var dangerousSchemes = []string{"javascript:", "vbscript:", "data:text/html", "data:image/svg+xml"}
+
+// scanHTML looks for dangerous tags, event handlers and script URLs in the
+// tags of input.
+func scanHTML(input string) (bool, string) {
+	for i := 0; i < len(input); i++ {
+		if input[i] != '<' {
+			continue
+		}
+		j := i + 1
+		if j < len(input) && input[j] == '/' {
+			j++
+		}
+		start := j
+		for j < len(input) && isTagChar(input[j]) {
+			j++
+		}
+		if j == start || !isLetter(input[start]) {
+			continue
+		}
+		name := strings.ToLower(input[start:j])
+		if dangerousTags[name] {
+			return true, "<" + name
+		}
+		if ok, token := scanAttributes(input[j:], isEventHandler); ok {
+			return true, token
+		}
+	}
+	return false, ""
+}
+
+// scanAttributes reads input as the attribute list of a tag, up to the
+// closing >, and reports the first attribute that runs script. handler
+// decides which attribute names are event handlers.
+func scanAttributes(input string, handler func(string) bool) (bool, string) {
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		if c == '>' {
+			return false, ""
+		}
+		if isSeparator(c) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(input) && !isSeparator(input[i]) && input[i] != '=' && input[i] != '>' {
+			i++
+		}
+		if i == start {
+			i++
+			continue
+		}
+		name := strings.ToLower(input[start:i])
+		k := skipSpace(input, i)
+		if k == len(input) || input[k] != '=' {
+			i = k
+			continue
+		}
+		value, next := attributeValue(input, skipSpace(input, k+1))
+		if token := checkAttribute(name, value, handler); token != "" {
+			return true, token
+		}
+		i = next
+	}
+	return false, ""
+}
+
+func attributeValue(input string, i int) (string, int) {
+	if i == len(input) {
+		return "", i
+	}
+	if q := input[i]; q == '"' || q == '\'' || q == '`' {
+		end := strings.IndexByte(input[i+1:], q)
+		if end < 0 {
+			return input[i+1:], len(input)
+		}
+		return input[i+1 : i+1+end], i + end + 2
+	}
+	end := i
+	for end < len(input) && !isSpace(input[end]) && input[end] != '>' {
+		end++
+	}
+	return input[i:end], end
+}
+
+func checkAttribute(name, value string, handler func(string) bool) string {
+	switch {
+	case handler(name):
+		return name
+	case urlAttributes[name]:
+		if scheme := scriptScheme(value); scheme != "" {
+			return name + "=" + scheme
+		}
+	case name == "style":
+		v := strings.ToLower(decodeEntities(value))
+		if strings.Contains(v, "expression(") || strings.Contains(v, "javascript:") || strings.Contains(v, "-moz-binding") {
+			return name
+		}
+	case name == "srcdoc":
+		return name
+	}
+	return ""
+}
+
+// scriptScheme returns the script scheme of a URL attribute value the way a
+// browser reads it: entities decoded, controls and whitespace dropped and
+// case folded.
+func scriptScheme(value string) string {
+	decoded := decodeEntities(value)
+	var b strings.Builder
+	for i := 0; i < len(decoded) && b.Len() < 32; i++ {
+		if c := decoded[i]; c > ' ' {
+			b.WriteByte(toLower(c))
+		}
+	}
+	url := b.String()
+	for _, scheme := range dangerousSchemes {
+		if strings.HasPrefix(url, scheme) {
+			return scheme
+		}
+	}
+	return ""
+}
+
+// isEventHandler accepts any on* attribute, as found inside a tag.
+func isEventHandler(name string) bool {
+	if len(name) < 4 || !strings.HasPrefix(name, "on") {
+		return false
+	}
+	for i := 2; i < len(name); i++ {
+		if !isLetter(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+var namedEntities = map[string]string{
+	"colon": ":", "tab": "\t", "newline": "\n", "lpar": "(", "rpar": ")",
+	"quot": `"`, "apos": "'", "lt": "<", "gt": ">", "amp": "&", "sol": "/",
+	"lowbar": "_", "period": ".", "grave": "`", "equals": "=",
+}
+
+// decodeEntities decodes numeric character references, with or without the
+// closing semicolon, and the named ones used to hide schemes and calls.
+func decodeEntities(s string) string {
+	if !strings.Contains(s, "&") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '&' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if r, n := entity(s[i+1:]); n > 0 {
+			b.WriteString(r)
+			i += n
+			continue
+		}
+		b.WriteByte('&')
+	}
+	return b.String()
+}
+
+func entity(s string) (string, int) {
+	if strings.HasPrefix(s, "#") {
+		base, start := 10, 1
+		if len(s) > 1 && (s[1] == 'x' || s[1] == 'X') {
+			base, start = 16, 2
+		}
+		end := start
+		for end < len(s) && (isDigit(s[end]) || base == 16 && isHexLetter(s[end])) {
+			end++
+		}
+		v, err := strconv.ParseUint(s[start:end], base, 32)
+		if end == start || err != nil || v > 0x10ffff {
+			return "", 0
+		}
+		if end < len(s) && s[end] == ';' {
+			end++
+		}
+		return string(rune(v)), end
+	}
+	end := strings.IndexByte(s, ';')
+	if end < 1 || end > 8 {
+		return "", 0
+	}
+	if r, ok := namedEntities[strings.ToLower(s[:end])]; ok {
+		return r, end + 1
+	}
+	return "", 0
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && isSpace(s[i]) {
+		i++
+	}
+	return i
+}
+
+func isSeparator(c byte) bool {
+	return isSpace(c) || c == '/' || c == '"' || c == '\''
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isTagChar(c byte) bool {
+	return isLetter(c) || isDigit(c) || c == '-' || c == ':'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexLetter(c byte) bool {
+	return c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func toLower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}